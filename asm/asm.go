@@ -0,0 +1,374 @@
+// Package asm assembles Chip-8 programs written with the mnemonics of the
+// disassembler, so that its listings assemble back into the same bytes.
+//
+// A line holds an optional label followed by a colon, then an instruction
+// or directive, then an optional comment after a semicolon:
+//
+//	loop:	MVI V0, 05		; count down from five
+//		ADD V0, -1
+//		SKIP.EQ V0, 00
+//		JUMP loop
+//
+// Operands are separated by commas or spaces. Values are hex, like the
+// disassembler writes immediates, unless prefixed with 0x, 0b or 0o, or
+// with # for decimal. They may add and subtract labels and constants
+// without spaces in between, as in sprite+8. The directives are:
+//
+//	ORG addr		; place what follows at addr, 0x200 by default
+//	NAME EQU value		; define the constant NAME
+//	DB 0x3C, "text"		; bytes and strings
+//	DW 0x1234, label	; big endian words
+//	INCLUDE "file.asm"	; assemble a file, relative to this one
+//
+// Mnemonics and directives are not case sensitive; labels and constants
+// are.
+package asm
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/albertseo/chip8"
+)
+
+// How deep includes may nest, to catch files including themselves
+const maxIncludeDepth = 16
+
+// Program is the output of the assembler
+type Program struct {
+	// Address of the first byte, the first ORG
+	Origin uint16
+	Bytes  []byte
+	// Address of each label
+	Symbols map[string]uint16
+}
+
+// Error is a problem with a line of the source
+type Error struct {
+	File string
+	Line int
+	Err  error
+	// Order of the line in the source with includes expanded
+	seq int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorList is every problem found in the source, in the order of the lines
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Assembles the file name. Included files are found relative to the file
+// including them. Errors are returned as an ErrorList.
+func AssembleFile(name string) (*Program, error) {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return Assemble(name, src)
+}
+
+// Assembles src, which is named name in errors and includes files relative
+// to the directory of name. Errors are returned as an ErrorList.
+func Assemble(name string, src []byte) (*Program, error) {
+	a := &assembler{
+		symbols: make(map[string]int),
+		labels:  make(map[string]bool),
+		pc:      chip8.DefaultLoadAddress,
+		origin:  -1,
+	}
+	a.load(name, src, 0)
+	if a.origin < 0 {
+		a.origin, a.end = a.pc, a.pc
+	}
+	bytes := make([]byte, a.end-a.origin)
+	for _, s := range a.statements {
+		data, err := a.assemble(s)
+		if err != nil {
+			a.errorf(s.file, s.line, s.seq, "%v", err)
+			continue
+		}
+		copy(bytes[s.addr-a.origin:], data)
+	}
+	if len(a.errors) > 0 {
+		sort.SliceStable(a.errors, func(i, j int) bool { return a.errors[i].seq < a.errors[j].seq })
+		return nil, a.errors
+	}
+
+	p := &Program{Origin: uint16(a.origin), Bytes: bytes, Symbols: make(map[string]uint16)}
+	for l := range a.labels {
+		p.Symbols[l] = uint16(a.symbols[l])
+	}
+	return p, nil
+}
+
+// Writes the labels of the program, one "0x0200 name" per line in order
+// of address
+func (p *Program) WriteSymbols(w io.Writer) error {
+	names := make([]string, 0, len(p.Symbols))
+	for name := range p.Symbols {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := p.Symbols[names[i]], p.Symbols[names[j]]
+		return a < b || a == b && names[i] < names[j]
+	})
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "0x%04X %s\n", p.Symbols[name], name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// An instruction or data directive, placed at addr by the first pass and
+// encoded by the second once all labels are known
+type statement struct {
+	file     string
+	line     int
+	seq      int
+	addr     int
+	mnemonic string
+	operands []string
+}
+
+type assembler struct {
+	// Labels and constants
+	symbols map[string]int
+	labels  map[string]bool
+
+	statements []statement
+	// Lines read so far
+	lines int
+	pc    int
+	// First and past the last address of the program, origin is -1 until
+	// something is placed
+	origin int
+	end    int
+
+	errors ErrorList
+}
+
+func (a *assembler) errorf(file string, line, seq int, format string, args ...interface{}) {
+	a.errors = append(a.errors, &Error{File: file, Line: line, Err: fmt.Errorf(format, args...), seq: seq})
+}
+
+// Runs the first pass over the lines of src, defining symbols and placing
+// statements
+func (a *assembler) load(name string, src []byte, depth int) {
+	for i, text := range strings.Split(string(src), "\n") {
+		a.lines++
+		seq := a.lines
+		if err := a.parseLine(name, i+1, seq, text, depth); err != nil {
+			a.errorf(name, i+1, seq, "%v", err)
+		}
+	}
+}
+
+func (a *assembler) parseLine(file string, line, seq int, text string, depth int) error {
+	text = strings.TrimSpace(stripComment(text))
+	if colon := strings.Index(text, ":"); colon > 0 && isSymbol(text[:colon]) {
+		if err := a.define(text[:colon], a.pc); err != nil {
+			return err
+		}
+		a.labels[text[:colon]] = true
+		text = strings.TrimSpace(text[colon+1:])
+	}
+	if text == "" {
+		return nil
+	}
+
+	fields := strings.Fields(text)
+	rest := strings.TrimSpace(text[len(fields[0]):])
+	if len(fields) > 1 && strings.EqualFold(fields[1], "EQU") {
+		if !isSymbol(fields[0]) {
+			return fmt.Errorf("invalid constant name %s", fields[0])
+		}
+		v, err := a.eval(strings.Join(fields[2:], ""))
+		if err != nil {
+			return err
+		}
+		return a.define(fields[0], v)
+	}
+
+	mnemonic := strings.ToUpper(fields[0])
+	var operands []string
+	size := 0
+	switch mnemonic {
+	case "ORG":
+		addr, err := a.eval(strings.Join(fields[1:], ""))
+		if err != nil {
+			return err
+		}
+		if addr < 0 || addr > 0xFFFF {
+			return fmt.Errorf("ORG 0x%X is outside memory", addr)
+		}
+		if a.origin >= 0 && addr < a.pc {
+			return fmt.Errorf("ORG 0x%04X moves back from 0x%04X", addr, a.pc)
+		}
+		a.pc = addr
+		return nil
+	case "INCLUDE":
+		path, err := strconv.Unquote(rest)
+		if err != nil {
+			return fmt.Errorf("INCLUDE needs a quoted file name")
+		}
+		if depth >= maxIncludeDepth {
+			return fmt.Errorf("includes nested more than %d deep", maxIncludeDepth)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		a.load(path, src, depth+1)
+		return nil
+	case "DB", "DW":
+		var err error
+		operands, err = splitData(rest)
+		if err != nil {
+			return err
+		}
+		for _, o := range operands {
+			switch {
+			case mnemonic == "DW":
+				size += 2
+			case strings.HasPrefix(o, `"`):
+				s, err := strconv.Unquote(o)
+				if err != nil {
+					return fmt.Errorf("invalid string %s", o)
+				}
+				size += len(s)
+			default:
+				size++
+			}
+		}
+	default:
+		size = instructionSize(mnemonic)
+		if size == 0 {
+			return fmt.Errorf("unknown instruction %s", fields[0])
+		}
+		operands = splitOperands(rest)
+	}
+
+	if a.pc+size > 0x10000 {
+		return fmt.Errorf("program runs past the end of memory")
+	}
+	if a.origin < 0 {
+		a.origin = a.pc
+	}
+	a.statements = append(a.statements, statement{file: file, line: line, seq: seq, addr: a.pc, mnemonic: mnemonic, operands: operands})
+	a.pc += size
+	a.end = a.pc
+	return nil
+}
+
+// Defines a label or constant
+func (a *assembler) define(name string, v int) error {
+	if _, ok := a.symbols[name]; ok {
+		return fmt.Errorf("%s is already defined", name)
+	}
+	if isRegister(name) {
+		return fmt.Errorf("%s names a register", name)
+	}
+	a.symbols[name] = v
+	return nil
+}
+
+// Encodes a statement once all labels are known
+func (a *assembler) assemble(s statement) ([]byte, error) {
+	switch s.mnemonic {
+	case "DB":
+		var data []byte
+		for _, o := range s.operands {
+			if strings.HasPrefix(o, `"`) {
+				str, _ := strconv.Unquote(o)
+				data = append(data, str...)
+				continue
+			}
+			v, err := a.eval(o)
+			if err != nil {
+				return nil, err
+			}
+			if v < -0x80 || v > 0xFF {
+				return nil, fmt.Errorf("%s is out of range for a byte", o)
+			}
+			data = append(data, byte(v))
+		}
+		return data, nil
+	case "DW":
+		var data []byte
+		for _, o := range s.operands {
+			v, err := a.eval(o)
+			if err != nil {
+				return nil, err
+			}
+			if v < -0x8000 || v > 0xFFFF {
+				return nil, fmt.Errorf("%s is out of range for a word", o)
+			}
+			data = append(data, byte(v>>8), byte(v))
+		}
+		return data, nil
+	}
+	return a.encode(s.mnemonic, s.operands)
+}
+
+// Removes the comment from a line, leaving semicolons in strings
+func stripComment(text string) string {
+	quoted := false
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quoted:
+			i++
+		case text[i] == '"':
+			quoted = !quoted
+		case text[i] == ';' && !quoted:
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// Splits the comma separated operands of DB and DW, leaving commas in
+// strings
+func splitData(text string) ([]string, error) {
+	var items []string
+	quoted := false
+	start := 0
+	for i := 0; i <= len(text); i++ {
+		switch {
+		case i == len(text) || text[i] == ',' && !quoted:
+			item := strings.TrimSpace(text[start:i])
+			if item == "" {
+				return nil, fmt.Errorf("missing value")
+			}
+			items = append(items, item)
+			start = i + 1
+		case text[i] == '\\' && quoted:
+			i++
+		case text[i] == '"':
+			quoted = !quoted
+		}
+	}
+	return items, nil
+}
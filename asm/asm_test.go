@@ -0,0 +1,151 @@
+package asm
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/albertseo/chip8"
+)
+
+func TestAssembleFishie(t *testing.T) {
+	rom, err := ioutil.ReadFile("../Fishie.ch8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listing := chip8.Disassemble(rom, chip8.DefaultLoadAddress, chip8.VariantCHIP8).String()
+	p, err := Assemble("fishie.asm", []byte(listing))
+	if err != nil {
+		t.Fatalf("Expected the listing to assemble, got %v instead", err)
+	}
+	if p.Origin != 0x200 {
+		t.Errorf("Expected origin 0x200, got 0x%X instead", p.Origin)
+	}
+	if !bytes.Equal(p.Bytes, rom) {
+		t.Errorf("Expected the bytes of Fishie.ch8, got\n% X\ninstead", p.Bytes)
+	}
+	if addr, ok := p.Symbols["loc_0208"]; !ok || addr != 0x208 {
+		t.Errorf("Expected loc_0208 at 0x208, got 0x%X instead", addr)
+	}
+}
+
+func TestAssembleEveryInstruction(t *testing.T) {
+	for op := 0; op <= 0xFFFF; op++ {
+		text := chip8.Mnemonic(uint16(op))
+		if strings.HasPrefix(text, "Instruction not") || op == 0xF000 {
+			continue
+		}
+		// Drop the opcode in front of the mnemonic
+		text = text[5:]
+		p, err := Assemble("op.asm", []byte(text))
+		if err != nil {
+			t.Errorf("Expected %q to assemble, got %v instead", text, err)
+			continue
+		}
+		if got := int(p.Bytes[0])<<8 | int(p.Bytes[1]); len(p.Bytes) != 2 || got != op {
+			t.Errorf("Expected %q to assemble to %04X, got % X instead", text, op, p.Bytes)
+		}
+	}
+}
+
+func TestAssembleDirectives(t *testing.T) {
+	src := `
+; A program using the directives
+COUNT EQU #10
+	ORG 0x300
+start:	MVI V0, COUNT		; ten
+	MVI I sprite
+	mvi.l i sprite+1
+	jump start(v0)
+	ORG 0x310
+sprite:	DB 0b00111100, 0x42, FF
+	DB "A;b", -1
+	DW start, 0x1234
+`
+	p, err := Assemble("test.asm", []byte(src))
+	if err != nil {
+		t.Fatalf("Expected the source to assemble, got %v instead", err)
+	}
+	expected := []byte{
+		0x60, 0x0A, 0xA3, 0x10, 0xF0, 0x00, 0x03, 0x11, 0xB3, 0x00,
+		0, 0, 0, 0, 0, 0,
+		0x3C, 0x42, 0xFF, 'A', ';', 'b', 0xFF, 0x03, 0x00, 0x12, 0x34,
+	}
+	if p.Origin != 0x300 {
+		t.Errorf("Expected origin 0x300, got 0x%X instead", p.Origin)
+	}
+	if !bytes.Equal(p.Bytes, expected) {
+		t.Errorf("Expected % X, got % X instead", expected, p.Bytes)
+	}
+
+	var sym bytes.Buffer
+	p.WriteSymbols(&sym)
+	if sym.String() != "0x0300 start\n0x0310 sprite\n" {
+		t.Errorf("Expected the symbols of start and sprite, got %q instead", sym.String())
+	}
+}
+
+func TestAssembleInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "asm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	main := filepath.Join(dir, "main.asm")
+	ioutil.WriteFile(main, []byte("\tCALL draw\n\tJUMP 0x202\n\tINCLUDE \"lib/draw.asm\"\n"), 0644)
+	os.Mkdir(filepath.Join(dir, "lib"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "lib", "draw.asm"), []byte("draw:\n\tCLS\n\tRTS\n"), 0644)
+
+	p, err := AssembleFile(main)
+	if err != nil {
+		t.Fatalf("Expected the source to assemble, got %v instead", err)
+	}
+	expected := []byte{0x22, 0x04, 0x12, 0x02, 0x00, 0xE0, 0x00, 0xEE}
+	if !bytes.Equal(p.Bytes, expected) {
+		t.Errorf("Expected % X, got % X instead", expected, p.Bytes)
+	}
+
+	ioutil.WriteFile(main, []byte("\tCLS\n\tINCLUDE \"main.asm\"\n"), 0644)
+	if _, err := AssembleFile(main); err == nil || !strings.Contains(err.Error(), "nested") {
+		t.Errorf("Expected an error for a file including itself, got %v instead", err)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	src := `	CLS
+	JUMP nowhere
+	MVI V0, 100
+	FROB V1
+	SPRITE V0, V1
+loop:	RTS
+loop:	RTS
+	DB
+	ORG 0x100
+`
+	_, err := Assemble("bad.asm", []byte(src))
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("Expected an ErrorList, got %v instead", err)
+	}
+	expected := []string{
+		"bad.asm:2: undefined symbol nowhere",
+		"bad.asm:3: 100 is out of range for NN",
+		"bad.asm:4: unknown instruction FROB",
+		"bad.asm:5: SPRITE takes 3 operands, got 2",
+		"bad.asm:7: loop is already defined",
+		"bad.asm:8: missing value",
+		"bad.asm:9: ORG 0x0100 moves back from 0x020A",
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d instead:\n%v", len(expected), len(list), err)
+	}
+	for i, e := range list {
+		if e.Error() != expected[i] {
+			t.Errorf("Expected %q, got %q instead", expected[i], e.Error())
+		}
+	}
+}
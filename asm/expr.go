@@ -0,0 +1,115 @@
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Evaluates an expression: numbers and symbols added and subtracted, with
+// no spaces in between. Numbers are hex like the disassembler writes them,
+// with a 0x, 0b or 0o prefix, or decimal after #. Symbols take precedence
+// over bare hex numbers, so a label named BEEF hides the number.
+func (a *assembler) eval(expr string) (int, error) {
+	if expr == "" {
+		return 0, fmt.Errorf("missing value")
+	}
+	total := 0
+	sign := 1
+	start := 0
+	if expr[0] == '-' || expr[0] == '+' {
+		start = 1
+	}
+	for i := start; i <= len(expr); i++ {
+		if i < len(expr) && expr[i] != '+' && expr[i] != '-' {
+			continue
+		}
+		v, err := a.term(expr[start:i])
+		if err != nil {
+			return 0, err
+		}
+		if start > 0 && expr[start-1] == '-' {
+			sign = -1
+		} else {
+			sign = 1
+		}
+		total += sign * v
+		start = i + 1
+	}
+	return total, nil
+}
+
+// Evaluates a single number or symbol
+func (a *assembler) term(s string) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("missing value")
+	}
+	if v, ok := a.symbols[s]; ok {
+		return v, nil
+	}
+	if isRegister(s) {
+		return 0, fmt.Errorf("expected a value, got register %s", s)
+	}
+	var v uint64
+	var err error
+	switch {
+	case strings.HasPrefix(s, "#"):
+		v, err = strconv.ParseUint(s[1:], 10, 16)
+	case len(s) > 2 && s[0] == '0' && strings.ContainsRune("xXbBoO", rune(s[1])):
+		v, err = strconv.ParseUint(s, 0, 16)
+	default:
+		v, err = strconv.ParseUint(s, 16, 16)
+	}
+	if err != nil {
+		if isSymbol(s) {
+			return 0, fmt.Errorf("undefined symbol %s", s)
+		}
+		return 0, fmt.Errorf("invalid number %s", s)
+	}
+	return int(v), nil
+}
+
+// Reports whether s can name a label or constant
+func isSymbol(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Reports whether s names one of the registers V0 to VF
+func isRegister(s string) bool {
+	_, ok := register(s)
+	return ok
+}
+
+// Returns the number of the register named s
+func register(s string) (int, bool) {
+	if len(s) != 2 || s[0] != 'V' && s[0] != 'v' {
+		return 0, false
+	}
+	r, err := strconv.ParseUint(s[1:], 16, 4)
+	if err != nil {
+		return 0, false
+	}
+	return int(r), true
+}
+
+// Returns the registers of a range like V0-V5
+func registerRange(s string) (int, int, bool) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	x, okX := register(parts[0])
+	y, okY := register(parts[1])
+	return x, y, okX && okY
+}
@@ -0,0 +1,207 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of operands in an instruction form. Anything else is a keyword that
+// must be written as is.
+const (
+	opX     = "VX"
+	opY     = "VY"
+	opN     = "N"
+	opNN    = "NN"
+	opNNN   = "NNN"
+	opNNNV0 = "NNN(V0)"
+	opNNNN  = "NNNN"
+	// Register ranges, V0-VX and VX-VY
+	opToX  = "V0-VX"
+	opXToY = "VX-VY"
+	// A nibble written in the X position, the planes of PLANE
+	opXN = "XN"
+)
+
+// The operands an instruction is written with and its opcode with the
+// operands zeroed
+type form struct {
+	mnemonic string
+	operands []string
+	opcode   uint16
+}
+
+// The instructions in the syntax of instruction.String. Forms of the same
+// mnemonic are tried in order.
+var forms = []form{
+	{"CLS", nil, 0x00E0},
+	{"RTS", nil, 0x00EE},
+	{"SCROLL.DOWN", []string{opN}, 0x00C0},
+	{"SCROLL.UP", []string{opN}, 0x00D0},
+	{"SCROLL.RIGHT", nil, 0x00FB},
+	{"SCROLL.LEFT", nil, 0x00FC},
+	{"EXIT", nil, 0x00FD},
+	{"LORES", nil, 0x00FE},
+	{"HIRES", nil, 0x00FF},
+	{"JUMP", []string{opNNNV0}, 0xB000},
+	{"JUMP", []string{opNNN}, 0x1000},
+	{"CALL", []string{opNNN}, 0x2000},
+	{"SKIP.EQ", []string{opX, opY}, 0x5000},
+	{"SKIP.EQ", []string{opX, opNN}, 0x3000},
+	{"SKIP.NEQ", []string{opX, opY}, 0x9000},
+	{"SKIP.NEQ", []string{opX, opNN}, 0x4000},
+	{"STORE.R", []string{"(I)", opXToY}, 0x5002},
+	{"LOAD.R", []string{opXToY, "(I)"}, 0x5003},
+	{"MVI", []string{opX, opNN}, 0x6000},
+	{"MVI", []string{"I", opNNN}, 0xA000},
+	{"MVI.L", []string{"I", opNNNN}, 0xF000},
+	{"ADD", []string{opX, opNN}, 0x7000},
+	{"ADD", []string{"I", opX}, 0xF01E},
+	{"MOV", []string{opX, opY}, 0x8000},
+	{"MOV", []string{opX, "DELAY"}, 0xF007},
+	{"MOV", []string{"DELAY", opX}, 0xF015},
+	{"MOV", []string{"SOUND", opX}, 0xF018},
+	{"OR", []string{opX, opY}, 0x8001},
+	{"AND", []string{opX, opY}, 0x8002},
+	{"XOR", []string{opX, opY}, 0x8003},
+	{"ADD.", []string{opX, opY}, 0x8004},
+	{"SUB.", []string{opX, opY}, 0x8005},
+	{"SHR.", []string{opX, opY}, 0x8006},
+	{"SUBB.", []string{opX, opY}, 0x8007},
+	{"SHL.", []string{opX, opY}, 0x800E},
+	{"RAND", []string{opX, opNN}, 0xC000},
+	{"SPRITE", []string{opX, opY, opN}, 0xD000},
+	{"SKIP.KEY", []string{opX}, 0xE09E},
+	{"SKIP.NKEY", []string{opX}, 0xE0A1},
+	{"PLANE", []string{opXN}, 0xF001},
+	{"AUDIO", []string{"(I)"}, 0xF002},
+	{"WAITKEY", []string{opX}, 0xF00A},
+	{"SPRITECHAR", []string{"I", opX}, 0xF029},
+	{"BIGSPRITECHAR", []string{"I", opX}, 0xF030},
+	{"MOVBCD", []string{opX}, 0xF033},
+	{"PITCH", []string{opX}, 0xF03A},
+	{"STORE", []string{"(I)", opToX}, 0xF055},
+	{"LOAD", []string{opToX, "(I)"}, 0xF065},
+	{"STORE", []string{"FLAGS", opToX}, 0xF075},
+	{"LOAD", []string{opToX, "FLAGS"}, 0xF085},
+}
+
+// Forms by mnemonic
+var formsByMnemonic = func() map[string][]form {
+	m := make(map[string][]form)
+	for _, f := range forms {
+		m[f.mnemonic] = append(m[f.mnemonic], f)
+	}
+	return m
+}()
+
+// Returns the size in bytes of the instruction mnemonic, 0 if there is no
+// such instruction
+func instructionSize(mnemonic string) int {
+	switch {
+	case mnemonic == "MVI.L":
+		return 4
+	case formsByMnemonic[mnemonic] != nil:
+		return 2
+	}
+	return 0
+}
+
+// Splits the operands of an instruction, which are separated by commas or
+// spaces
+func splitOperands(s string) []string {
+	return strings.Fields(strings.Replace(s, ",", " ", -1))
+}
+
+// Encodes the instruction mnemonic with the given operands. When no form
+// matches, the error of the form that got furthest is returned.
+func (a *assembler) encode(mnemonic string, operands []string) ([]byte, error) {
+	var best error
+	bestMatched := -1
+	for _, f := range formsByMnemonic[mnemonic] {
+		op, matched, err := a.match(f, operands)
+		if err == nil {
+			if f.opcode == 0xF000 {
+				return []byte{0xF0, 0x00, byte(op >> 8), byte(op)}, nil
+			}
+			return []byte{byte(op >> 8), byte(op)}, nil
+		}
+		if matched > bestMatched {
+			best, bestMatched = err, matched
+		}
+	}
+	return nil, best
+}
+
+// Matches operands against the form f and returns the opcode, or how far
+// the operands matched and why they did not match further. For
+// MVI.L the returned opcode is the address following F000.
+func (a *assembler) match(f form, operands []string) (uint16, int, error) {
+	if len(operands) != len(f.operands) {
+		return 0, 0, fmt.Errorf("%s takes %d operands, got %d", f.mnemonic, len(f.operands), len(operands))
+	}
+	op := f.opcode
+	for n, kind := range f.operands {
+		s := operands[n]
+		switch kind {
+		case opX, opY:
+			r, ok := register(s)
+			if !ok {
+				return 0, n, fmt.Errorf("expected a register, got %s", s)
+			}
+			if kind == opX {
+				op |= uint16(r) << 8
+			} else {
+				op |= uint16(r) << 4
+			}
+		case opToX:
+			x, y, ok := registerRange(s)
+			if !ok || x != 0 {
+				return 0, n, fmt.Errorf("expected registers V0-VX, got %s", s)
+			}
+			op |= uint16(y) << 8
+		case opXToY:
+			x, y, ok := registerRange(s)
+			if !ok {
+				return 0, n, fmt.Errorf("expected registers VX-VY, got %s", s)
+			}
+			op |= uint16(x)<<8 | uint16(y)<<4
+		case opN, opXN, opNN, opNNN, opNNNV0, opNNNN:
+			if isRegister(s) {
+				return 0, n, fmt.Errorf("expected a value, got register %s", s)
+			}
+			if kind == opNNNV0 {
+				if !strings.HasSuffix(strings.ToUpper(s), "(V0)") {
+					return 0, n, fmt.Errorf("expected an address followed by (V0), got %s", s)
+				}
+				s = s[:len(s)-len("(V0)")]
+			}
+			// The operand has the right shape from here on, so its errors
+			// count as this form's
+			v, err := a.eval(s)
+			if err != nil {
+				return 0, n + 1, err
+			}
+			max := map[string]int{opN: 0xF, opXN: 0xF, opNN: 0xFF, opNNN: 0xFFF, opNNNV0: 0xFFF, opNNNN: 0xFFFF}[kind]
+			// Negative bytes are allowed for adding and comparing
+			if kind == opNN && v < 0 && v >= -0x80 {
+				v &= 0xFF
+			}
+			if v < 0 || v > max {
+				return 0, n + 1, fmt.Errorf("%s is out of range for %s", s, kind)
+			}
+			switch kind {
+			case opXN:
+				op |= uint16(v) << 8
+			case opNNNN:
+				op = uint16(v)
+			default:
+				op |= uint16(v)
+			}
+		default:
+			if !strings.EqualFold(s, kind) {
+				return 0, n, fmt.Errorf("expected %s, got %s", kind, s)
+			}
+		}
+	}
+	return op, len(operands), nil
+}
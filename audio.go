@@ -0,0 +1,188 @@
+package chip8
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// AudioSink plays the sound of the machine. The machine hands it the
+// samples of every frame it runs, silent while the sound timer is zero.
+type AudioSink interface {
+	// Samples per second the sink plays at
+	SampleRate() int
+	// Plays the samples of a frame, signed 16 bit mono. Samples are never
+	// zero while the machine sounds, so a frame of zeros is silence.
+	Play(samples []int16)
+}
+
+// Frequency of the square wave the sound timer plays when none is
+// configured, in Hz
+const DefaultTone = 440
+
+// Amplitude of the samples, a quarter of the full scale
+const audioAmplitude = 8192
+
+// Plays the sound on sink: a square wave while the sound timer is not
+// zero, or on XO-CHIP the audio pattern once one has been loaded. Frames run
+// again while rewinding are not played.
+func WithAudio(sink AudioSink) Option {
+	return func(c8 *Machine) {
+		c8.audio = sink
+	}
+}
+
+// Plays the sound timer as a square wave of frequency Hz instead of
+// DefaultTone
+func WithTone(frequency float64) Option {
+	return func(c8 *Machine) {
+		c8.tone = frequency
+	}
+}
+
+// Sets the audio sink, nil to stop playing
+func (c8 *Machine) SetAudio(sink AudioSink) {
+	c8.audio = sink
+}
+
+// Reports whether the machine is sounding, which it does while the sound
+// timer is not zero
+func (c8 *Machine) Sounding() bool {
+	return c8.soundDelay > 0
+}
+
+// Hands the samples of the frame being run to the audio sink
+func (c8 *Machine) playAudio() {
+	rate := uint64(c8.audio.SampleRate())
+	// Samples played so far at the start and end of this frame, so that
+	// rates which are not a multiple of 60 don't drift
+	n := int((c8.frames+1)*rate/FrameRate - c8.frames*rate/FrameRate)
+	if cap(c8.audioBuf) < n {
+		c8.audioBuf = make([]int16, n)
+	}
+	samples := c8.audioBuf[:n]
+	if !c8.Sounding() {
+		for i := range samples {
+			samples[i] = 0
+		}
+		c8.audio.Play(samples)
+		return
+	}
+
+	// Bits of the pattern or periods of the square wave per sample. The
+	// phase carries on across frames so that the wave has no clicks.
+	pattern := c8.variant == VariantXOCHIP && c8.pattern != [16]uint8{}
+	step := c8.tone / float64(rate)
+	if pattern {
+		step = 4000 * math.Pow(2, (float64(c8.pitch)-64)/48) / float64(rate)
+	}
+	for i := range samples {
+		var high bool
+		if pattern {
+			bit := int(c8.audioPhase) % 128
+			high = c8.pattern[bit/8]&(0x80>>uint(bit%8)) != 0
+		} else {
+			high = c8.audioPhase-math.Floor(c8.audioPhase) < 0.5
+		}
+		samples[i] = -audioAmplitude
+		if high {
+			samples[i] = audioAmplitude
+		}
+		c8.audioPhase += step
+		if pattern && c8.audioPhase >= 128 {
+			c8.audioPhase -= 128
+		} else if !pattern && c8.audioPhase >= 1 {
+			c8.audioPhase -= math.Floor(c8.audioPhase)
+		}
+	}
+	c8.audio.Play(samples)
+}
+
+// NullAudioSink is an AudioSink discarding every sample, for running
+// without sound such as in tests
+type NullAudioSink struct {
+	Rate int
+}
+
+// Returns Rate
+func (s NullAudioSink) SampleRate() int {
+	return s.Rate
+}
+
+// Does nothing
+func (NullAudioSink) Play(samples []int16) {}
+
+// WAVSink is an AudioSink writing the samples to a WAV file. Call Close
+// once done to fill in the sizes in the header.
+type WAVSink struct {
+	w    io.WriteSeeker
+	rate int
+	// Bytes of samples written
+	size uint32
+	err  error
+}
+
+// Length of the header of a WAV file
+const wavHeaderSize = 44
+
+// Returns a WAVSink writing to w at rate samples per second
+func NewWAVSink(w io.WriteSeeker, rate int) *WAVSink {
+	s := &WAVSink{w: w, rate: rate}
+	s.err = s.writeHeader()
+	return s
+}
+
+// Returns the sample rate of the file
+func (s *WAVSink) SampleRate() int {
+	return s.rate
+}
+
+// Writes samples to the file. Errors are kept and returned by Close.
+func (s *WAVSink) Play(samples []int16) {
+	if s.err != nil {
+		return
+	}
+	buf := make([]byte, 2*len(samples))
+	for i, v := range samples {
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(v))
+	}
+	_, s.err = s.w.Write(buf)
+	s.size += uint32(len(buf))
+}
+
+// Fills in the sizes in the header and returns the first error met. w is
+// left open.
+func (s *WAVSink) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	if _, err := s.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := s.writeHeader(); err != nil {
+		return err
+	}
+	_, err := s.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// Writes the header of a 16 bit mono PCM file holding the samples written
+// so far
+func (s *WAVSink) writeHeader() error {
+	var h [wavHeaderSize]byte
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], wavHeaderSize-8+s.size)
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	// PCM, one channel
+	binary.LittleEndian.PutUint16(h[20:], 1)
+	binary.LittleEndian.PutUint16(h[22:], 1)
+	binary.LittleEndian.PutUint32(h[24:], uint32(s.rate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(s.rate)*2)
+	binary.LittleEndian.PutUint16(h[32:], 2)
+	binary.LittleEndian.PutUint16(h[34:], 16)
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], s.size)
+	_, err := s.w.Write(h[:])
+	return err
+}
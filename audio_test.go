@@ -0,0 +1,124 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Keeps the samples of every frame played
+type audioLog struct {
+	rate   int
+	frames [][]int16
+}
+
+func (a *audioLog) SampleRate() int {
+	return a.rate
+}
+
+func (a *audioLog) Play(samples []int16) {
+	a.frames = append(a.frames, append([]int16(nil), samples...))
+}
+
+func TestAudioSquareWave(t *testing.T) {
+	log := &audioLog{rate: 4000}
+	// Sets the sound timer to 3 and loops
+	rom := []byte{0x60, 0x03, 0xF0, 0x18, 0x12, 0x04}
+	c8, err := NewFromROM(bytes.NewReader(rom), WithAudio(log), WithTone(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		c8.RunFrame()
+	}
+	if len(log.frames) != 4 {
+		t.Fatalf("Expected 4 frames of samples, got %d instead", len(log.frames))
+	}
+	// 4000 samples per second spread over 60 frames
+	for n, expected := range []int{66, 67, 67, 66} {
+		if len(log.frames[n]) != expected {
+			t.Errorf("Expected %d samples in frame %d, got %d instead", expected, n, len(log.frames[n]))
+		}
+	}
+	a := int16(audioAmplitude)
+	if f := log.frames[0]; f[0] != a || f[1] != a || f[2] != -a || f[3] != -a || f[4] != a {
+		t.Errorf("Expected a square wave of 4 samples a period, got %v instead", f[:5])
+	}
+	// The first frame ends half way through a period
+	if f := log.frames[1]; f[0] != -a || f[1] != -a || f[2] != a {
+		t.Errorf("Expected the wave to carry on, got %v instead", f[:3])
+	}
+	for _, v := range log.frames[3] {
+		if v != 0 {
+			t.Fatalf("Expected silence once the sound timer ran out, got %v instead", log.frames[3])
+		}
+	}
+	if c8.Sounding() {
+		t.Errorf("Expected the machine to be silent")
+	}
+}
+
+func TestAudioPattern(t *testing.T) {
+	log := &audioLog{rate: 4000}
+	rom := []byte{
+		0xA2, 0x0A, // MVI I 0x020A
+		0xF0, 0x02, // Load the pattern at I
+		0x60, 0x02, // MVI V0, 02
+		0xF0, 0x18, // Sound timer = V0
+		0x12, 0x08, // JUMP 0x0208
+	}
+	for i := 0; i < 8; i++ {
+		rom = append(rom, 0xFF, 0x00)
+	}
+	c8, err := NewFromROM(bytes.NewReader(rom), WithVariant(VariantXOCHIP), WithAudio(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c8.RunFrame()
+	// At the default pitch a bit of the pattern lasts one sample at 4000 Hz
+	f, a := log.frames[0], int16(audioAmplitude)
+	for i := 0; i < 32; i++ {
+		expected := a
+		if i/8%2 == 1 {
+			expected = -a
+		}
+		if f[i] != expected {
+			t.Fatalf("Expected eight high then eight low samples, got %v instead", f[:32])
+		}
+	}
+}
+
+func TestWAVSink(t *testing.T) {
+	f, err := ioutil.TempFile("", "chip8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	s := NewWAVSink(f, 8000)
+	c8, err := NewFromROM(bytes.NewReader([]byte{0x12, 0x00}), WithAudio(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		c8.RunFrame()
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(f.Name())
+	// 8000 samples per second spread over 60 frames, two bytes each
+	size := uint32(133+133+134) * 2
+	if len(data) != wavHeaderSize+int(size) {
+		t.Fatalf("Expected %d bytes, got %d instead", wavHeaderSize+size, len(data))
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:16]) != "WAVEfmt " || string(data[36:40]) != "data" {
+		t.Errorf("Expected a WAV header, got %q instead", data[:wavHeaderSize])
+	}
+	if binary.LittleEndian.Uint32(data[4:]) != 36+size || binary.LittleEndian.Uint32(data[24:]) != 8000 || binary.LittleEndian.Uint32(data[40:]) != size {
+		t.Errorf("Expected the sizes and rate in the header, got % X instead", data[:wavHeaderSize])
+	}
+}
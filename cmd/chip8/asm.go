@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/albertseo/chip8"
+	"github.com/albertseo/chip8/asm"
+	"github.com/albertseo/chip8/octo"
+)
+
+// Assembles a source file, or compiles Octo source, into a program and
+// returns the exit code
+func runAsm(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("chip8 asm", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	out := fs.String("o", "", "program to write (default the source with a .ch8 extension)")
+	symbols := fs.String("symbols", "", "file to write the address of each label to")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: chip8 asm [flags] source.asm|source.8o\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	src := fs.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(src, filepath.Ext(src)) + ".ch8"
+	}
+
+	p, err := assemble(src)
+	if err != nil {
+		var octoErr *octo.Error
+		if _, ok := err.(asm.ErrorList); ok || errors.As(err, &octoErr) {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitROM
+	}
+	if err := ioutil.WriteFile(*out, p.Bytes, 0644); err != nil {
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitError
+	}
+	if *symbols != "" {
+		f, err := os.Create(*symbols)
+		if err == nil {
+			err = p.WriteSymbols(f)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "chip8: %v\n", err)
+			return exitError
+		}
+	}
+	return exitOK
+}
+
+// Assembles src, compiling it as Octo if it ends in .8o
+func assemble(src string) (*asm.Program, error) {
+	if !isOcto(src) {
+		return asm.AssembleFile(src)
+	}
+	p, err := octo.CompileFile(src)
+	if err != nil {
+		return nil, err
+	}
+	return &asm.Program{Origin: chip8.DefaultLoadAddress, Bytes: p.Bytes, Symbols: p.Labels}, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+
+	"github.com/albertseo/chip8/conformance"
+)
+
+// Runs a conformance suite and returns the exit code, exitError if any
+// test fails
+func runConformance(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("chip8 conformance", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	update := fs.Bool("update", false, "write the screens of the tests as their golden images")
+	pattern := fs.String("run", "", "run only the tests whose name matches this regular expression")
+	shots := fs.String("screenshots", "", "directory to write the screen of each test to as a PNG image")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: chip8 conformance [flags] [suite directory]\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	dir := "conformance/suite"
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	match, err := regexp.Compile(*pattern)
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8: -run: %v\n", err)
+		return exitUsage
+	}
+	suite, err := conformance.LoadSuite(dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitError
+	}
+
+	failed := 0
+	for i := range suite.Tests {
+		t := &suite.Tests[i]
+		if !match.MatchString(t.Name) {
+			continue
+		}
+		if *update {
+			if err := suite.UpdateGolden(t); err != nil {
+				fmt.Fprintf(stdout, "FAIL %s: %v\n", t.Name, err)
+				failed++
+				continue
+			}
+			fmt.Fprintf(stdout, "wrote %s\n", t.Golden)
+			continue
+		}
+		r := suite.RunTest(t)
+		fmt.Fprintln(stdout, r)
+		if !r.Passed() {
+			failed++
+		}
+		if *shots != "" && r.Screen != nil {
+			if err := conformance.WriteImage(filepath.Join(*shots, t.Name+".png"), r.Screen); err != nil {
+				fmt.Fprintf(stderr, "chip8: %v\n", err)
+				return exitError
+			}
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(stdout, "%d failed\n", failed)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/albertseo/chip8"
+)
+
+// Writes the listing of a program to stdout and returns the exit code
+func runDisasm(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("chip8 disasm", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	variantName := fs.String("variant", "chip8", "instruction set: chip8, schip or xochip")
+	origin := fs.Uint("origin", chip8.DefaultLoadAddress, "address the program is loaded at")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: chip8 disasm [flags] rom.ch8\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *origin > 0xFFFF {
+		fmt.Fprintf(stderr, "chip8: -origin 0x%X is past the end of memory\n", *origin)
+		return exitUsage
+	}
+	variant, err := chip8.ParseVariant(*variantName)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	rom, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitROM
+	}
+	if _, err := chip8.Disassemble(rom, uint16(*origin), variant).WriteTo(stdout); err != nil {
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,484 @@
+// Command chip8 runs a Chip-8 program, or disassembles or assembles one.
+//
+//	chip8 [flags] rom.ch8
+//	chip8 disasm [flags] rom.ch8
+//	chip8 asm [flags] source.asm
+//	chip8 tracediff [flags] rom.ch8
+//	chip8 conformance [flags] [suite directory]
+//
+// Octo source files ending in .8o are compiled before running, and asm
+// compiles them into a program too. The terminal frontend is quit with Esc
+// or Ctrl-C. The headless frontend runs until -frames have passed or the
+// process is interrupted. -debug starts the terminal frontend paused in the
+// debugger, with the :breakpoint and :monitor directives of Octo source.
+// -trace writes every instruction executed and what it changed to a file,
+// -screenshot writes the screen to an image when the run ends, and -record
+// records every frame to an animated GIF or PNG. The sound timer rings the
+// terminal bell, and -audio writes the tone to a WAV file instead. -movie
+// records the keypad input to a movie file, and -play plays one back
+// headless, reproducing the session.
+// tracediff runs a program under two configurations, or against the trace
+// of another emulator, and reports the first instruction they disagree on.
+// conformance runs the test programs of a suite headless and compares their
+// screens with golden images.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/albertseo/chip8"
+	"github.com/albertseo/chip8/debugger"
+	"github.com/albertseo/chip8/frontend/terminal"
+	"github.com/albertseo/chip8/octo"
+)
+
+// Exit codes
+const (
+	exitOK    = 0
+	exitROM   = 1
+	exitUsage = 2
+	exitError = 3
+	// tracediff found the traces disagree
+	exitDiverged = 4
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Options from the command line
+type config struct {
+	rom      string
+	ips      int
+	variant  string
+	quirks   string
+	rpl      string
+	scale    int
+	colors   string
+	keyMap   string
+	frontend string
+	frames   uint64
+	debug    bool
+	trace    string
+	traceFmt string
+	shot     string
+	shotSize int
+	record   string
+	recSize  int
+	audio    string
+	rate     int
+	tone     float64
+	movie    string
+	play     string
+	seed     int64
+	random   string
+}
+
+func parseFlags(args []string, stderr io.Writer) (*config, error) {
+	cfg := new(config)
+	fs := flag.NewFlagSet("chip8", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.IntVar(&cfg.ips, "ips", chip8.DefaultInstructionsPerSecond, "instructions executed per second")
+	fs.StringVar(&cfg.variant, "variant", "chip8", "instruction set: chip8, schip or xochip")
+	fs.StringVar(&cfg.quirks, "quirks", "", "quirk profile: "+strings.Join(chip8.QuirkProfileNames(), ", ")+" (default the usual profile of the variant)")
+	fs.StringVar(&cfg.rpl, "rpl", "", "file to keep the SUPER-CHIP user flags in between runs")
+	fs.IntVar(&cfg.scale, "scale", 1, "terminal rows per pixel")
+	fs.StringVar(&cfg.colors, "colors", "default", "color scheme: "+colorNames())
+	fs.StringVar(&cfg.keyMap, "keys", "qwerty", "key map: "+keyMapNames()+", or 16 characters for 123C456D789EA0BF")
+	fs.StringVar(&cfg.frontend, "frontend", "terminal", "frontend: terminal or headless")
+	fs.Uint64Var(&cfg.frames, "frames", 0, "stop after this many frames, 0 runs until quit")
+	fs.BoolVar(&cfg.debug, "debug", false, "start paused in the debugger, terminal frontend only")
+	fs.StringVar(&cfg.trace, "trace", "", "file to write a trace of every instruction executed to")
+	fs.StringVar(&cfg.traceFmt, "trace-format", "jsonl", "trace format: jsonl or binary")
+	fs.StringVar(&cfg.shot, "screenshot", "", "file to write the screen to when the run ends, .png, .pbm or .pgm")
+	fs.IntVar(&cfg.shotSize, "screenshot-scale", 4, "image pixels per screen pixel in the screenshot")
+	fs.StringVar(&cfg.record, "record", "", "file to record the screen to as an animation, .gif or .png (APNG)")
+	fs.IntVar(&cfg.recSize, "record-scale", 2, "animation pixels per high resolution screen pixel")
+	fs.StringVar(&cfg.audio, "audio", "", "sound output: bell, none or a .wav file (default bell for the terminal frontend, none headless)")
+	fs.IntVar(&cfg.rate, "sample-rate", 44100, "samples per second of the .wav file")
+	fs.Float64Var(&cfg.tone, "tone", chip8.DefaultTone, "frequency of the sound timer tone in Hz")
+	fs.StringVar(&cfg.movie, "movie", "", "file to record the keypad input to as a movie")
+	fs.StringVar(&cfg.play, "play", "", "movie file to play back headless instead of reading the keypad")
+	fs.Int64Var(&cfg.seed, "seed", 0, "seed of the random numbers, 0 picks one from the time")
+	fs.StringVar(&cfg.random, "random", "seeded", "source of the random numbers: "+randomSourceNames())
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: chip8 [flags] rom.ch8\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, fmt.Errorf("expected one rom, got %d arguments", fs.NArg())
+	}
+	cfg.rom = fs.Arg(0)
+	if cfg.ips <= 0 {
+		return nil, fmt.Errorf("-ips must be positive, got %d", cfg.ips)
+	}
+	if _, ok := terminal.ColorSchemes[cfg.colors]; !ok {
+		return nil, fmt.Errorf("unknown color scheme %q", cfg.colors)
+	}
+	if cfg.frontend != "terminal" && cfg.frontend != "headless" {
+		return nil, fmt.Errorf("unknown frontend %q", cfg.frontend)
+	}
+	if ext := strings.ToLower(filepath.Ext(cfg.shot)); cfg.shot != "" && ext != ".png" && ext != ".pbm" && ext != ".pgm" {
+		return nil, fmt.Errorf("-screenshot must be a .png, .pbm or .pgm file, got %q", cfg.shot)
+	}
+	if cfg.record != "" {
+		if _, err := recordFormat(cfg.record); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.audio == "" {
+		cfg.audio = "none"
+		if cfg.frontend == "terminal" {
+			cfg.audio = "bell"
+		}
+	}
+	if cfg.audio != "bell" && cfg.audio != "none" && !strings.EqualFold(filepath.Ext(cfg.audio), ".wav") {
+		return nil, fmt.Errorf("-audio must be bell, none or a .wav file, got %q", cfg.audio)
+	}
+	if cfg.rate <= 0 || cfg.tone <= 0 {
+		return nil, fmt.Errorf("-sample-rate and -tone must be positive")
+	}
+	if cfg.play != "" {
+		if cfg.movie != "" || cfg.debug {
+			return nil, fmt.Errorf("-play can't be used with -movie or -debug")
+		}
+		cfg.frontend = "headless"
+	}
+	if (cfg.movie != "" || cfg.play != "") && cfg.rpl != "" {
+		return nil, fmt.Errorf("-rpl can't be used with -movie or -play, the user flags would differ between runs")
+	}
+	if cfg.debug && cfg.frontend != "terminal" {
+		return nil, fmt.Errorf("-debug needs the terminal frontend")
+	}
+	return cfg, nil
+}
+
+// Runs the emulator, or the subcommand named by the first argument, and
+// returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "disasm":
+			return runDisasm(args[1:], stdout, stderr)
+		case "asm":
+			return runAsm(args[1:], stdout, stderr)
+		case "tracediff":
+			return runTraceDiff(args[1:], stdout, stderr)
+		case "conformance":
+			return runConformance(args[1:], stdout, stderr)
+		}
+	}
+	cfg, err := parseFlags(args, stderr)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitUsage
+	}
+	keyMap, err := chip8.ParseKeyMap(cfg.keyMap)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitUsage
+	}
+	variant, err := chip8.ParseVariant(cfg.variant)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitUsage
+	}
+	quirks := variant.Quirks()
+	if cfg.quirks != "" {
+		quirks, err = chip8.ParseQuirks(cfg.quirks)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return exitUsage
+		}
+	}
+	random, err := chip8.ParseRandomSource(cfg.random)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	opts := []chip8.Option{
+		chip8.WithInstructionsPerSecond(cfg.ips),
+		chip8.WithVariant(variant),
+		chip8.WithQuirks(quirks),
+		chip8.WithTone(cfg.tone),
+		chip8.WithRandomSource(random),
+	}
+	if cfg.seed != 0 {
+		opts = append(opts, chip8.WithSeed(cfg.seed))
+	}
+	if cfg.rpl != "" {
+		opts = append(opts, chip8.WithFlagStore(chip8.FileFlagStore(cfg.rpl)))
+	}
+	if cfg.trace != "" {
+		format, err := chip8.ParseTraceFormat(cfg.traceFmt)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return exitUsage
+		}
+		f, err := os.Create(cfg.trace)
+		if err != nil {
+			fmt.Fprintf(stderr, "chip8: %v\n", err)
+			return exitError
+		}
+		tw := chip8.NewTraceWriter(f, format)
+		defer func() {
+			err := tw.Flush()
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "chip8: writing the trace: %v\n", err)
+			}
+		}()
+		opts = append(opts, chip8.WithTracer(tw))
+	}
+	if cfg.record != "" {
+		format, _ := recordFormat(cfg.record)
+		f, err := os.Create(cfg.record)
+		if err != nil {
+			fmt.Fprintf(stderr, "chip8: %v\n", err)
+			return exitError
+		}
+		rec := chip8.NewRecorder(f, format, cfg.recSize, nil)
+		defer func() {
+			err := rec.Close()
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "chip8: writing the recording: %v\n", err)
+			}
+		}()
+		opts = append(opts, chip8.WithRecorder(rec))
+	}
+	switch cfg.audio {
+	case "bell":
+		opts = append(opts, chip8.WithAudio(terminal.NewBell(stdout)))
+	case "none":
+	default:
+		f, err := os.Create(cfg.audio)
+		if err != nil {
+			fmt.Fprintf(stderr, "chip8: %v\n", err)
+			return exitError
+		}
+		wav := chip8.NewWAVSink(f, cfg.rate)
+		defer func() {
+			err := wav.Close()
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				fmt.Fprintf(stderr, "chip8: writing the audio: %v\n", err)
+			}
+		}()
+		opts = append(opts, chip8.WithAudio(wav))
+	}
+	// Closed when the user quits
+	var quit <-chan struct{}
+	// Headless runs with a frame limit don't need to wait for real time
+	var clock chip8.Clock = chip8.NewWallClock()
+	// Restores the terminal, must run before anything is printed
+	closeFrontend := func() {}
+	// Terminal frontend, for the debugger
+	var disp *terminal.Display
+	var kp *terminal.Keypad
+
+	switch cfg.frontend {
+	case "terminal":
+		disp, err = terminal.Open(cfg.scale, terminal.ColorSchemes[cfg.colors])
+		if err != nil {
+			fmt.Fprintf(stderr, "chip8: %v\n", err)
+			return exitError
+		}
+		kp = terminal.NewKeypad(keyMap, terminal.DefaultHoldTime)
+		closeFrontend = func() {
+			kp.Close()
+			disp.Close()
+		}
+		opts = append(opts, chip8.WithRenderer(disp), chip8.WithKeypad(kp))
+		quit = kp.Quit()
+	case "headless":
+		interrupted := make(chan struct{})
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		go func() {
+			<-interrupt
+			close(interrupted)
+		}()
+		quit = interrupted
+		if cfg.frames > 0 {
+			clock = new(chip8.VirtualClock)
+		}
+	}
+
+	m := chip8.New(opts...)
+	// Octo source is compiled first
+	var program *octo.Program
+	if isOcto(cfg.rom) {
+		program, err = octo.CompileFile(cfg.rom)
+		if err == nil {
+			_, err = m.LoadROMBytes(program.Bytes)
+		}
+	} else {
+		_, err = m.LoadROMFile(cfg.rom)
+	}
+	if err != nil {
+		closeFrontend()
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitROM
+	}
+
+	if cfg.play != "" {
+		movie, err := readMovie(cfg.play)
+		if err == nil {
+			err = m.PlayMovie(movie)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "chip8: %v\n", err)
+			return exitError
+		}
+		// The run ends with the movie, or earlier with a smaller -frames
+		if cfg.frames == 0 || cfg.frames > movie.Frames {
+			cfg.frames = movie.Frames
+		}
+		if cfg.frames == 0 {
+			return screenshot(cfg, m, stderr)
+		}
+		clock = new(chip8.VirtualClock)
+	}
+	if cfg.movie != "" {
+		movie, err := m.RecordMovie()
+		if err != nil {
+			closeFrontend()
+			fmt.Fprintf(stderr, "%v\n", err)
+			return exitUsage
+		}
+		defer func() {
+			if err := writeMovie(cfg.movie, movie); err != nil {
+				fmt.Fprintf(stderr, "chip8: writing the movie: %v\n", err)
+			}
+		}()
+	}
+
+	if cfg.debug {
+		dbg := debugger.New(m)
+		if program != nil {
+			program.Install(dbg)
+		}
+		terminal.NewDebugger(disp, kp, m, dbg).Run(quit)
+		closeFrontend()
+		return screenshot(cfg, m, stderr)
+	}
+
+	s := chip8.NewScheduler(m, clock)
+	if cfg.frames > 0 {
+		err = s.RunFrames(cfg.frames, quit)
+	} else {
+		err = s.Run(quit)
+	}
+	closeFrontend()
+	if err != nil && !errors.Is(err, chip8.ErrExit) {
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		screenshot(cfg, m, stderr)
+		return exitError
+	}
+	return screenshot(cfg, m, stderr)
+}
+
+// Writes the screen of m to the -screenshot file, if any, and returns the
+// exit code
+func screenshot(cfg *config, m *chip8.Machine, stderr io.Writer) int {
+	if cfg.shot == "" {
+		return exitOK
+	}
+	if err := chip8.WriteScreenshot(cfg.shot, m.Framebuffer().Image(cfg.shotSize, nil)); err != nil {
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// Reads the movie file name
+func readMovie(name string) (*chip8.Movie, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return chip8.ReadMovie(f)
+}
+
+// Writes movie to the file name
+func writeMovie(name string, movie *chip8.Movie) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = movie.Write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Returns the animation format of the file name from its extension
+func recordFormat(name string) (chip8.RecordFormat, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gif":
+		return chip8.RecordGIF, nil
+	case ".png", ".apng":
+		return chip8.RecordAPNG, nil
+	}
+	return 0, fmt.Errorf("-record must be a .gif, .png or .apng file, got %q", name)
+}
+
+// Reports whether the file name holds Octo source rather than a program
+func isOcto(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".8o")
+}
+
+// Lists the names of the color schemes
+func colorNames() string {
+	var list []string
+	for name := range terminal.ColorSchemes {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
+
+// Lists the names of the key maps
+func keyMapNames() string {
+	var list []string
+	for name := range chip8.KeyMaps {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
+
+// Lists the names of the random sources
+func randomSourceNames() string {
+	var list []string
+	for name := range chip8.RandomSources {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunHeadless(t *testing.T) {
+	var stderr bytes.Buffer
+	code := run([]string{"-frontend", "headless", "-frames", "30", "-quirks", "cosmac", "../../Fishie.ch8"}, ioutil.Discard, &stderr)
+	if code != exitOK {
+		t.Errorf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+}
+
+func TestRunMissingROM(t *testing.T) {
+	var stderr bytes.Buffer
+	code := run([]string{"-frontend", "headless", "-frames", "1", "missing.ch8"}, ioutil.Discard, &stderr)
+	if code != exitROM {
+		t.Errorf("Expected exit code %d, got %d instead", exitROM, code)
+	}
+	if !strings.Contains(stderr.String(), "missing.ch8") {
+		t.Errorf("Expected the error to name the rom, got %q instead", stderr.String())
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"-frontend", "nope", "rom.ch8"},
+		{"-colors", "nope", "rom.ch8"},
+		{"-keys", "nope", "rom.ch8"},
+		{"-quirks", "nope", "rom.ch8"},
+		{"-variant", "nope", "rom.ch8"},
+		{"-ips", "0", "rom.ch8"},
+		{"-debug", "-frontend", "headless", "rom.ch8"},
+		{"-trace", "trace.jsonl", "-trace-format", "nope", "rom.ch8"},
+		{"-audio", "beep.mp3", "rom.ch8"},
+		{"-audio", "beep.wav", "-sample-rate", "0", "rom.ch8"},
+		{"-play", "a.movie", "-movie", "b.movie", "rom.ch8"},
+		{"-movie", "a.movie", "-rpl", "flags.rpl", "rom.ch8"},
+		{"-random", "dice", "rom.ch8"},
+	}
+	for _, args := range tests {
+		var stderr bytes.Buffer
+		if code := run(args, ioutil.Discard, &stderr); code != exitUsage {
+			t.Errorf("Expected exit code %d for %q, got %d instead", exitUsage, args, code)
+		}
+	}
+}
+
+func TestRunDisasm(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"disasm", "../../Fishie.ch8"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "\tORG 0x0200\n\tCLS") {
+		t.Errorf("Expected a listing starting with CLS, got %q instead", stdout.String())
+	}
+	if code := run([]string{"disasm", "-origin", "0x10000", "../../Fishie.ch8"}, &stdout, &stderr); code != exitUsage {
+		t.Errorf("Expected exit code %d for an origin past memory, got %d instead", exitUsage, code)
+	}
+}
+
+func TestRunAsm(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chip8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "loop.asm")
+	ioutil.WriteFile(src, []byte("loop:\tCLS\n\tJUMP loop\n"), 0644)
+
+	var stderr bytes.Buffer
+	code := run([]string{"asm", "-symbols", filepath.Join(dir, "loop.sym"), src}, ioutil.Discard, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	rom, _ := ioutil.ReadFile(filepath.Join(dir, "loop.ch8"))
+	if !bytes.Equal(rom, []byte{0x00, 0xE0, 0x12, 0x00}) {
+		t.Errorf("Expected the assembled loop, got % X instead", rom)
+	}
+	sym, _ := ioutil.ReadFile(filepath.Join(dir, "loop.sym"))
+	if string(sym) != "0x0200 loop\n" {
+		t.Errorf("Expected the symbol of loop, got %q instead", sym)
+	}
+
+	ioutil.WriteFile(src, []byte("\tCLS\n\tJUMP nowhere\n"), 0644)
+	stderr.Reset()
+	if code := run([]string{"asm", src}, ioutil.Discard, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d, got %d instead", exitError, code)
+	}
+	if !strings.Contains(stderr.String(), "loop.asm:2: undefined symbol nowhere") {
+		t.Errorf("Expected a diagnostic for line 2, got %q instead", stderr.String())
+	}
+}
+
+func TestRunOcto(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chip8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "loop.8o")
+	ioutil.WriteFile(src, []byte(": main\n\tclear\n\tloop again\n"), 0644)
+
+	var stderr bytes.Buffer
+	if code := run([]string{"-frontend", "headless", "-frames", "5", src}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	if code := run([]string{"asm", src}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	rom, _ := ioutil.ReadFile(filepath.Join(dir, "loop.ch8"))
+	if !bytes.Equal(rom, []byte{0x00, 0xE0, 0x12, 0x02}) {
+		t.Errorf("Expected the compiled loop, got % X instead", rom)
+	}
+
+	ioutil.WriteFile(src, []byte(": main\n\tjump nowhere\n"), 0644)
+	stderr.Reset()
+	if code := run([]string{"asm", src}, ioutil.Discard, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d, got %d instead", exitError, code)
+	}
+	if !strings.Contains(stderr.String(), "loop.8o:2: undefined label nowhere") {
+		t.Errorf("Expected a diagnostic for line 2, got %q instead", stderr.String())
+	}
+}
+
+func TestRunTrace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chip8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	trace := filepath.Join(dir, "fishie.trace")
+
+	var stderr bytes.Buffer
+	code := run([]string{"-frontend", "headless", "-frames", "2", "-trace", trace, "-trace-format", "binary", "../../Fishie.ch8"}, ioutil.Discard, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	data, _ := ioutil.ReadFile(trace)
+	if !bytes.HasPrefix(data, []byte("C8TR")) {
+		t.Errorf("Expected a binary trace, got %q instead", data)
+	}
+}
+
+func TestRunTraceDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chip8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// Shifts V2 into V1, unless the shift quirk shifts V1 in place
+	rom := filepath.Join(dir, "shift.ch8")
+	ioutil.WriteFile(rom, []byte{0x61, 0x03, 0x62, 0x04, 0x81, 0x26, 0x12, 0x06}, 0644)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"tracediff", "-frames", "2", "-a", "quirks=cosmac", "-b", "quirks=schip1.1", rom}, &stdout, &stderr)
+	if code != exitDiverged {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitDiverged, code, stderr.String())
+	}
+	for _, s := range []string{"first divergence at instruction 2: V1, VF", "8126 SHR. V1, V2", "> 0x0204", "V2=04"} {
+		if !strings.Contains(stdout.String(), s) {
+			t.Errorf("Expected the report to contain %q, got\n%s\ninstead", s, stdout.String())
+		}
+	}
+
+	trace := filepath.Join(dir, "shift.jsonl")
+	if code := run([]string{"-frontend", "headless", "-frames", "2", "-quirks", "cosmac", "-trace", trace, rom}, ioutil.Discard, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	stdout.Reset()
+	code = run([]string{"tracediff", "-frames", "2", "-a", "quirks=cosmac", "-ref", trace, rom}, &stdout, &stderr)
+	if code != exitOK || stdout.String() != "no divergence in 20 instructions\n" {
+		t.Errorf("Expected the run to match its trace, got %d and %q instead", code, stdout.String())
+	}
+
+	// Both runs draw the same random numbers, unless their seeds differ
+	random := filepath.Join(dir, "random.ch8")
+	ioutil.WriteFile(random, []byte{0xC0, 0xFF, 0x12, 0x00}, 0644)
+	if code := run([]string{"tracediff", "-frames", "2", random}, ioutil.Discard, &stderr); code != exitOK {
+		t.Errorf("Expected exit code %d for the same seed, got %d instead", exitOK, code)
+	}
+	stdout.Reset()
+	code = run([]string{"tracediff", "-frames", "2", "-a", "seed=1", "-b", "seed=2", random}, &stdout, &stderr)
+	if code != exitDiverged || !strings.Contains(stdout.String(), "first divergence at instruction 0: V0") {
+		t.Errorf("Expected the seeds to give other numbers, got %d and %q instead", code, stdout.String())
+	}
+
+	for _, args := range [][]string{
+		{"tracediff", "-b", "quirks=cosmac", "-ref", trace, rom},
+		{"tracediff", "-a", "speed=2", rom},
+		{"tracediff", "-a", "ips=0", rom},
+		{"tracediff", "-a", "random=dice", rom},
+	} {
+		if code := run(args, ioutil.Discard, &stderr); code != exitUsage {
+			t.Errorf("Expected exit code %d for %q, got %d instead", exitUsage, args, code)
+		}
+	}
+}
+
+func TestRunConformance(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"conformance", "-run", "^quirks", "../../conformance/suite"}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d instead: %s%s", exitOK, code, stdout.String(), stderr.String())
+	}
+	if stdout.String() != "ok   quirks-cosmac\nok   quirks-schip\nok   quirks-xochip\n" {
+		t.Errorf("Expected the quirks tests to pass, got %q instead", stdout.String())
+	}
+	if code := run([]string{"conformance", "missing"}, ioutil.Discard, &stderr); code != exitError {
+		t.Errorf("Expected exit code %d for a missing suite, got %d instead", exitError, code)
+	}
+}
+
+func TestRunScreenshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chip8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	shot := filepath.Join(dir, "fishie.pgm")
+
+	var stderr bytes.Buffer
+	code := run([]string{"-frontend", "headless", "-frames", "30", "-screenshot", shot, "-screenshot-scale", "2", "../../Fishie.ch8"}, ioutil.Discard, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	data, _ := ioutil.ReadFile(shot)
+	if !bytes.HasPrefix(data, []byte("P5\n128 64\n255\n")) || len(data) != len("P5\n128 64\n255\n")+128*64 {
+		t.Errorf("Expected a 128x64 PGM image, got %d bytes instead", len(data))
+	}
+	if code := run([]string{"-screenshot", "shot.gif", "rom.ch8"}, ioutil.Discard, &stderr); code != exitUsage {
+		t.Errorf("Expected exit code %d for an unknown image format, got %d instead", exitUsage, code)
+	}
+}
+
+func TestRunRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chip8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rec := filepath.Join(dir, "fishie.gif")
+
+	var stderr bytes.Buffer
+	code := run([]string{"-frontend", "headless", "-frames", "30", "-record", rec, "../../Fishie.ch8"}, ioutil.Discard, &stderr)
+	if code != exitOK || stderr.Len() != 0 {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	data, _ := ioutil.ReadFile(rec)
+	if !bytes.HasPrefix(data, []byte("GIF89a")) {
+		t.Errorf("Expected an animated GIF, got %q instead", data)
+	}
+	if code := run([]string{"-record", "fishie.mp4", "rom.ch8"}, ioutil.Discard, &stderr); code != exitUsage {
+		t.Errorf("Expected exit code %d for an unknown animation format, got %d instead", exitUsage, code)
+	}
+}
+
+func TestRunAudio(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chip8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wav := filepath.Join(dir, "fishie.wav")
+
+	var stderr bytes.Buffer
+	code := run([]string{"-frontend", "headless", "-frames", "30", "-audio", wav, "-sample-rate", "6000", "../../Fishie.ch8"}, ioutil.Discard, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	// Half a second at 6000 samples per second, two bytes each
+	data, _ := ioutil.ReadFile(wav)
+	if !bytes.HasPrefix(data, []byte("RIFF")) || len(data) != 44+3000*2 {
+		t.Errorf("Expected half a second of WAV audio, got %d bytes instead", len(data))
+	}
+}
+
+func TestRunMovie(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chip8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	movie := filepath.Join(dir, "fishie.movie")
+	recorded, played := filepath.Join(dir, "recorded.pgm"), filepath.Join(dir, "played.pgm")
+
+	var stderr bytes.Buffer
+	code := run([]string{"-frontend", "headless", "-frames", "45", "-seed", "3", "-movie", movie, "-screenshot", recorded, "../../Fishie.ch8"}, ioutil.Discard, &stderr)
+	if code != exitOK || stderr.Len() != 0 {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	// The movie sets the speed and the number of frames
+	code = run([]string{"-play", movie, "-ips", "60", "-screenshot", played, "../../Fishie.ch8"}, ioutil.Discard, &stderr)
+	if code != exitOK || stderr.Len() != 0 {
+		t.Fatalf("Expected exit code %d, got %d instead: %s", exitOK, code, stderr.String())
+	}
+	a, _ := ioutil.ReadFile(recorded)
+	b, _ := ioutil.ReadFile(played)
+	if len(a) == 0 || !bytes.Equal(a, b) {
+		t.Errorf("Expected the movie to end on the recorded screen")
+	}
+
+	// The movie only plays with the program it was recorded with
+	code = run([]string{"-play", movie, "../../conformance/suite/flags.8o"}, ioutil.Discard, &stderr)
+	if code != exitError {
+		t.Errorf("Expected exit code %d for another program, got %d instead", exitError, code)
+	}
+	// Numbers from crypto/rand can't be played back
+	code = run([]string{"-frontend", "headless", "-frames", "1", "-random", "crypto", "-movie", movie, "../../Fishie.ch8"}, ioutil.Discard, &stderr)
+	if code != exitUsage {
+		t.Errorf("Expected exit code %d for crypto random numbers, got %d instead", exitUsage, code)
+	}
+}
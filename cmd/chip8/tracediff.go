@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/albertseo/chip8"
+	"github.com/albertseo/chip8/octo"
+)
+
+// Instructions shown before and after the PC of a divergence
+const disasmContext = 4
+
+// Runs a program under two configurations, or one against a trace from
+// another emulator, and reports the first instruction they disagree on.
+// Returns the exit code, exitDiverged if they disagree.
+func runTraceDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("chip8 tracediff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	confA := fs.String("a", "", "configuration of the first run, such as variant=schip,quirks=cosmac,ips=700")
+	confB := fs.String("b", "", "configuration of the second run")
+	ref := fs.String("ref", "", "trace, in jsonl or binary, to compare the first run against instead of a second run")
+	frames := fs.Uint64("frames", 600, "frames to run each configuration for")
+	context := fs.Int("context", 8, "instructions to show before the divergence")
+	seed := fs.Int64("seed", 0, "seed of the random numbers of both runs, 0 picks one from the time")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: chip8 tracediff [flags] rom.ch8\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return exitOK
+	} else if err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *confB != "" && *ref != "" {
+		fmt.Fprintf(stderr, "chip8: -b and -ref both give the second trace\n")
+		return exitUsage
+	}
+	for _, conf := range []string{*confA, *confB} {
+		if _, err := parseRunConfig(conf); err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return exitUsage
+		}
+	}
+	// Both runs draw the same random numbers unless configured otherwise
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rom, err := readProgram(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitROM
+	}
+
+	a, err := newRunTrace(rom, "a", *confA, *frames, *seed)
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitROM
+	}
+	// Both traces start from the program as loaded by the first run
+	memory := a.m.Snapshot().Memory
+	var b chip8.TraceSource
+	var runB *runTrace
+	nameB := ""
+	if *ref != "" {
+		f, err := os.Open(*ref)
+		if err != nil {
+			fmt.Fprintf(stderr, "chip8: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+		tr, err := chip8.NewTraceReader(f)
+		if err != nil {
+			fmt.Fprintf(stderr, "chip8: %v\n", err)
+			return exitError
+		}
+		b, nameB = tr, "b ("+*ref+")"
+	} else {
+		runB, err = newRunTrace(rom, "b", *confB, *frames, *seed)
+		if err != nil {
+			fmt.Fprintf(stderr, "chip8: %v\n", err)
+			return exitROM
+		}
+		b, nameB = runB, runB.name
+	}
+
+	div, n, err := chip8.CompareTraces(a, b, memory, *context)
+	if err != nil {
+		fmt.Fprintf(stderr, "chip8: %v\n", err)
+		return exitError
+	}
+	for _, r := range []*runTrace{a, runB} {
+		if r != nil && r.err != nil && !errors.Is(r.err, chip8.ErrExit) {
+			fmt.Fprintf(stdout, "%s stopped: %v\n", r.name, r.err)
+		}
+	}
+	if div == nil {
+		fmt.Fprintf(stdout, "no divergence in %d instructions\n", n)
+		return exitOK
+	}
+	writeDivergence(stdout, div, a.name, nameB)
+	return exitDiverged
+}
+
+// Returns the options of a configuration such as "variant=schip,ips=700".
+// seed and random set the random numbers.
+func parseRunConfig(conf string) ([]chip8.Option, error) {
+	variant := chip8.VariantCHIP8
+	var quirks *chip8.Quirks
+	var opts []chip8.Option
+	for _, field := range strings.Split(conf, ",") {
+		if field == "" {
+			continue
+		}
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("chip8: expected key=value in the configuration, got %q", field)
+		}
+		var err error
+		switch kv[0] {
+		case "variant":
+			variant, err = chip8.ParseVariant(kv[1])
+		case "quirks":
+			var q chip8.Quirks
+			q, err = chip8.ParseQuirks(kv[1])
+			quirks = &q
+		case "ips":
+			var ips int
+			ips, err = strconv.Atoi(kv[1])
+			if err == nil && ips <= 0 {
+				err = fmt.Errorf("chip8: ips must be positive, got %d", ips)
+			}
+			opts = append(opts, chip8.WithInstructionsPerSecond(ips))
+		case "seed":
+			var seed int64
+			seed, err = strconv.ParseInt(kv[1], 0, 64)
+			opts = append(opts, chip8.WithSeed(seed))
+		case "random":
+			var src chip8.RandomSource
+			src, err = chip8.ParseRandomSource(kv[1])
+			opts = append(opts, chip8.WithRandomSource(src))
+		default:
+			err = fmt.Errorf("chip8: unknown configuration key %q, expected variant, quirks, ips, seed or random", kv[0])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if quirks == nil {
+		q := variant.Quirks()
+		quirks = &q
+	}
+	return append(opts, chip8.WithVariant(variant), chip8.WithQuirks(*quirks)), nil
+}
+
+// Reads a program, compiling it first if it is Octo source
+func readProgram(name string) ([]byte, error) {
+	if !isOcto(name) {
+		return ioutil.ReadFile(name)
+	}
+	p, err := octo.CompileFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return p.Bytes, nil
+}
+
+// A machine running a program, handing out the instructions it executes
+// as a chip8.TraceSource
+type runTrace struct {
+	// a or b and the configuration, for the report
+	name   string
+	m      *chip8.Machine
+	frames uint64
+	queue  []*chip8.TraceEntry
+	// Why the machine stopped before running all frames
+	err error
+}
+
+// Returns a run of rom for frames frames under the configuration conf,
+// seeded with seed unless conf sets the seed
+func newRunTrace(rom []byte, name, conf string, frames uint64, seed int64) (*runTrace, error) {
+	opts, err := parseRunConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	if conf == "" {
+		conf = "default"
+	}
+	r := &runTrace{name: name + " (" + conf + ")", frames: frames}
+	opts = append([]chip8.Option{chip8.WithSeed(seed)}, opts...)
+	r.m = chip8.New(append(opts, chip8.WithTracer(r))...)
+	if _, err := r.m.LoadROMBytes(rom); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *runTrace) Trace(e *chip8.TraceEntry) {
+	r.queue = append(r.queue, e)
+}
+
+// Runs frames until an instruction has executed, io.EOF once the frames
+// have run or the machine has stopped
+func (r *runTrace) Next() (*chip8.TraceEntry, error) {
+	for len(r.queue) == 0 {
+		if r.err != nil || r.m.Frames() >= r.frames {
+			return nil, io.EOF
+		}
+		r.err = r.m.RunFrame()
+	}
+	e := r.queue[0]
+	r.queue = r.queue[1:]
+	return e, nil
+}
+
+// Writes the report of a divergence: both instructions, the state before
+// them, memory at I, the code around the PC and the instructions leading up
+// to it
+func writeDivergence(w io.Writer, div *chip8.TraceDivergence, nameA, nameB string) {
+	fmt.Fprintf(w, "first divergence at instruction %d: %s\n\n", div.Index, strings.Join(div.Fields, ", "))
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "\t%s\t%s\n", nameA, nameB)
+	fmt.Fprintf(tw, "cycle\t%d\t%d\n", div.A.Cycle, div.B.Cycle)
+	fmt.Fprintf(tw, "pc\t0x%04X\t0x%04X\n", div.A.PC, div.B.PC)
+	fmt.Fprintf(tw, "instruction\t%s\t%s\n", chip8.Mnemonic(div.A.Opcode), chip8.Mnemonic(div.B.Opcode))
+	fmt.Fprintf(tw, "changes\t%s\t%s\n", traceChanges(div.A), traceChanges(div.B))
+	fmt.Fprintf(tw, "\t\t\n")
+	sa, sb := div.StateA, div.StateB
+	for r := range sa.V {
+		fmt.Fprintf(tw, "V%X\t%02X\t%02X\n", r, sa.V[r], sb.V[r])
+	}
+	fmt.Fprintf(tw, "I\t0x%04X\t0x%04X\n", sa.I, sb.I)
+	fmt.Fprintf(tw, "SP\t%d\t%d\n", sa.SP, sb.SP)
+	fmt.Fprintf(tw, "(I)\t% X\t% X\n", sa.Memory[sa.I:min(int(sa.I)+8, len(sa.Memory))], sb.Memory[sb.I:min(int(sb.I)+8, len(sb.Memory))])
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%s around 0x%04X:\n", nameA, div.A.PC)
+	writeCode(w, sa, div.A.PC)
+	if div.B.PC != div.A.PC {
+		fmt.Fprintf(w, "\n%s around 0x%04X:\n", nameB, div.B.PC)
+		writeCode(w, sb, div.B.PC)
+	}
+	if len(div.History) > 0 {
+		fmt.Fprintf(w, "\nbefore:\n")
+		for _, e := range div.History {
+			fmt.Fprintf(w, "  %8d  0x%04X  %-24s %s\n", e.Cycle, e.PC, chip8.Mnemonic(e.Opcode), traceChanges(e))
+		}
+	}
+}
+
+// Writes the instructions around pc in the memory of s, marking pc
+func writeCode(w io.Writer, s *chip8.TraceState, pc uint16) {
+	for n := -disasmContext; n <= disasmContext; n++ {
+		addr := int(pc) + 2*n
+		if addr < 0 || addr+1 >= len(s.Memory) {
+			continue
+		}
+		mark := " "
+		if n == 0 {
+			mark = ">"
+		}
+		inst := uint16(s.Memory[addr])<<8 | uint16(s.Memory[addr+1])
+		fmt.Fprintf(w, "%s 0x%04X  %s\n", mark, addr, chip8.Mnemonic(inst))
+	}
+}
+
+// Lists what an instruction changed, such as "V3=05 I=0x0300 (0x0300)=7B"
+func traceChanges(e *chip8.TraceEntry) string {
+	var names []string
+	for name := range e.Registers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var changes []string
+	for _, name := range names {
+		changes = append(changes, fmt.Sprintf("%s=%02X", name, e.Registers[name]))
+	}
+	if e.I != nil {
+		changes = append(changes, fmt.Sprintf("I=0x%04X", *e.I))
+	}
+	if e.SP != nil {
+		changes = append(changes, fmt.Sprintf("SP=%d", *e.SP))
+	}
+	for _, mw := range e.Writes {
+		changes = append(changes, fmt.Sprintf("(0x%04X)=%02X", mw.Addr, mw.Value))
+	}
+	if len(changes) == 0 {
+		return "-"
+	}
+	return strings.Join(changes, " ")
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,207 @@
+// Package conformance runs programs headless for a number of frames, with
+// scripted key presses, and compares the screen they leave behind with a
+// golden image.
+//
+// A suite is a directory holding suite.json, which lists the tests, and the
+// programs and golden images the tests name. The suite in the suite
+// directory of this package checks the instructions, the flags they set,
+// the quirk profiles and the keypad with programs written in Octo.
+package conformance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/albertseo/chip8"
+	"github.com/albertseo/chip8/octo"
+)
+
+// Name of the file listing the tests of a suite
+const SuiteFile = "suite.json"
+
+// Test is a program run for a number of frames whose screen must then
+// match a golden image
+type Test struct {
+	Name string `json:"name"`
+	// Program to run, relative to the suite. Octo source is compiled first.
+	ROM string `json:"rom"`
+	// Variant and quirk profile, the default of the variant if not set
+	Variant string `json:"variant,omitempty"`
+	Quirks  string `json:"quirks,omitempty"`
+	// Instructions per second, chip8.DefaultInstructionsPerSecond if not set
+	IPS    int    `json:"ips,omitempty"`
+	Frames uint64 `json:"frames"`
+	// Seed of the random numbers, so that programs using CXNN draw the
+	// same screen on every run
+	Seed int64 `json:"seed,omitempty"`
+	// Keys pressed and released during the run
+	Input []KeyPress `json:"input,omitempty"`
+	// Image the screen must match, relative to the suite, see ReadImage
+	Golden string `json:"golden"`
+}
+
+// KeyPress is a key pressed or released before the frame Frame runs
+type KeyPress struct {
+	Frame   uint64 `json:"frame"`
+	Key     uint8  `json:"key"`
+	Pressed bool   `json:"pressed"`
+}
+
+// Suite is a set of tests sharing a directory
+type Suite struct {
+	// Directory the programs and golden images are in
+	Dir   string `json:"-"`
+	Tests []Test `json:"tests"`
+}
+
+// Loads the suite in directory dir
+func LoadSuite(dir string) (*Suite, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, SuiteFile))
+	if err != nil {
+		return nil, err
+	}
+	s := &Suite{Dir: dir}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("conformance: %s: %v", filepath.Join(dir, SuiteFile), err)
+	}
+	for _, t := range s.Tests {
+		if t.Name == "" || t.ROM == "" || t.Golden == "" {
+			return nil, fmt.Errorf("conformance: %s: every test needs a name, rom and golden", filepath.Join(dir, SuiteFile))
+		}
+	}
+	return s, nil
+}
+
+// Result is the outcome of a test
+type Result struct {
+	Test *Test
+	// Screen at the end of the run, nil if the program could not run
+	Screen *Image
+	// Pixels that differ from the golden image
+	Diff int
+	// Why the test could not be run or checked, such as a fault
+	Err error
+}
+
+// Reports whether the test ran and the screen matched the golden image
+func (r *Result) Passed() bool {
+	return r.Err == nil && r.Diff == 0
+}
+
+func (r *Result) String() string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("FAIL %s: %v", r.Test.Name, r.Err)
+	case r.Diff != 0:
+		return fmt.Sprintf("FAIL %s: %d pixels differ from %s", r.Test.Name, r.Diff, r.Test.Golden)
+	}
+	return "ok   " + r.Test.Name
+}
+
+// Runs every test of the suite
+func (s *Suite) Run() []*Result {
+	var results []*Result
+	for i := range s.Tests {
+		results = append(results, s.RunTest(&s.Tests[i]))
+	}
+	return results
+}
+
+// Runs a test and compares its screen with the golden image
+func (s *Suite) RunTest(t *Test) *Result {
+	r := &Result{Test: t}
+	r.Screen, r.Err = s.runProgram(t)
+	if r.Err != nil {
+		return r
+	}
+	golden, err := ReadImage(filepath.Join(s.Dir, t.Golden))
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Diff = r.Screen.Diff(golden)
+	return r
+}
+
+// Runs a test and writes its screen as the golden image
+func (s *Suite) UpdateGolden(t *Test) error {
+	screen, err := s.runProgram(t)
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(s.Dir, t.Golden)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return WriteImage(name, screen)
+}
+
+// Runs the program of a test and returns the screen it leaves behind
+func (s *Suite) runProgram(t *Test) (*Image, error) {
+	m, err := s.newMachine(t)
+	if err != nil {
+		return nil, err
+	}
+	input := t.Input
+	for m.Frames() < t.Frames {
+		for len(input) > 0 && input[0].Frame <= m.Frames() {
+			if input[0].Pressed {
+				m.PressKey(input[0].Key)
+			} else {
+				m.ReleaseKey(input[0].Key)
+			}
+			input = input[1:]
+		}
+		if err := m.RunFrame(); errors.Is(err, chip8.ErrExit) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("frame %d: %v", m.Frames(), err)
+		}
+	}
+	return ScreenImage(m.Framebuffer()), nil
+}
+
+// Returns a machine configured for a test with its program loaded
+func (s *Suite) newMachine(t *Test) (*chip8.Machine, error) {
+	variant := chip8.VariantCHIP8
+	var err error
+	if t.Variant != "" {
+		if variant, err = chip8.ParseVariant(t.Variant); err != nil {
+			return nil, err
+		}
+	}
+	quirks := variant.Quirks()
+	if t.Quirks != "" {
+		if quirks, err = chip8.ParseQuirks(t.Quirks); err != nil {
+			return nil, err
+		}
+	}
+	opts := []chip8.Option{chip8.WithVariant(variant), chip8.WithQuirks(quirks), chip8.WithSeed(t.Seed)}
+	if t.IPS != 0 {
+		opts = append(opts, chip8.WithInstructionsPerSecond(t.IPS))
+	}
+
+	name := filepath.Join(s.Dir, t.ROM)
+	var rom []byte
+	if strings.EqualFold(filepath.Ext(name), ".8o") {
+		var p *octo.Program
+		if p, err = octo.CompileFile(name); err == nil {
+			rom = p.Bytes
+		}
+	} else {
+		rom, err = ioutil.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := chip8.New(opts...)
+	if _, err := m.LoadROMBytes(rom); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
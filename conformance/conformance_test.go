@@ -0,0 +1,70 @@
+package conformance
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSuite(t *testing.T) {
+	s, err := LoadSuite("suite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range s.Run() {
+		if !r.Passed() {
+			t.Errorf("%v\n%s", r, r.Screen)
+		}
+	}
+}
+
+func TestMismatch(t *testing.T) {
+	s := &Suite{Dir: "suite", Tests: []Test{
+		{Name: "no-input", ROM: "keypad.8o", Frames: 10, Golden: "golden/keypad.txt"},
+		{Name: "missing", ROM: "missing.ch8", Frames: 10, Golden: "golden/keypad.txt"},
+	}}
+	results := s.Run()
+	// Without key presses the program never draws
+	if r := results[0]; r.Passed() || r.Diff != 33 {
+		t.Errorf("Expected 33 pixels to differ without input, got %v instead", r)
+	}
+	if r := results[1]; r.Passed() || r.Err == nil {
+		t.Errorf("Expected an error for a missing program, got %v instead", r)
+	}
+}
+
+func TestImageFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conformance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	img := &Image{Width: 4, Height: 2, Pix: []uint8{0, 1, 2, 3, 1, 0, 0, 1}}
+	if s := img.String(); s != ".#23\n#..#\n" {
+		t.Errorf("Expected a character per pixel, got %q instead", s)
+	}
+	for _, name := range []string{"screen.txt", "screen.png"} {
+		name = filepath.Join(dir, name)
+		if err := WriteImage(name, img); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadImage(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(read, img) {
+			t.Errorf("%s: expected to read back the image written, got %v instead", name, read)
+		}
+	}
+
+	if n := img.Diff(&Image{Width: 2, Height: 2, Pix: make([]uint8, 4)}); n != 8 {
+		t.Errorf("Expected every pixel to differ for another size, got %d instead", n)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "bad.txt"), []byte("..\n...\n"), 0644)
+	if _, err := ReadImage(filepath.Join(dir, "bad.txt")); err == nil {
+		t.Errorf("Expected an error for rows of different lengths")
+	}
+}
@@ -0,0 +1,140 @@
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/albertseo/chip8"
+)
+
+// Image is the screen of a machine: the color of each pixel, 0 when off
+// and 1 to 3 when lit, see chip8.Framebuffer.Color
+type Image struct {
+	Width, Height int
+	// Colors row by row
+	Pix []uint8
+}
+
+// Characters of the text golden images, by color
+const textColors = ".#23"
+
+// Returns the image of a screen
+func ScreenImage(fb *chip8.Framebuffer) *Image {
+	// At scale 1 the palette index of each pixel is its color
+	return &Image{Width: fb.Width(), Height: fb.Height(), Pix: fb.Image(1, nil).Pix}
+}
+
+// Returns the color of the pixel at (x, y)
+func (img *Image) At(x, y int) uint8 {
+	return img.Pix[y*img.Width+x]
+}
+
+// Returns the number of pixels that differ from other, every pixel of the
+// larger image if the sizes differ
+func (img *Image) Diff(other *Image) int {
+	if img.Width != other.Width || img.Height != other.Height {
+		if len(img.Pix) > len(other.Pix) {
+			return len(img.Pix)
+		}
+		return len(other.Pix)
+	}
+	n := 0
+	for i := range img.Pix {
+		if img.Pix[i] != other.Pix[i] {
+			n++
+		}
+	}
+	return n
+}
+
+// Returns the image as text, a line per row with a character per pixel: .
+// when off, # when lit and 2 or 3 for the other XO-CHIP colors
+func (img *Image) String() string {
+	var b strings.Builder
+	for y := 0; y < img.Height; y++ {
+		for x := 0; x < img.Width; x++ {
+			b.WriteByte(textColors[img.At(x, y)&3])
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Reads an image from a .png file or a text file, see Image.String. A PNG
+// pixel is the index in its palette if it has one, and lit if it is
+// brighter than half otherwise.
+func ReadImage(name string) (*Image, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(name), ".png") {
+		return decodePNG(name, data)
+	}
+	return parseText(name, data)
+}
+
+// Writes an image to a .png file or, for any other extension, a text file
+func WriteImage(name string, img *Image) error {
+	if !strings.EqualFold(filepath.Ext(name), ".png") {
+		return ioutil.WriteFile(name, []byte(img.String()), 0644)
+	}
+	p := image.NewPaletted(image.Rect(0, 0, img.Width, img.Height), chip8.DefaultPalette)
+	copy(p.Pix, img.Pix)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, p); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, buf.Bytes(), 0644)
+}
+
+func parseText(name string, data []byte) (*Image, error) {
+	img := new(Image)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if img.Height == 0 {
+			img.Width = len(line)
+		}
+		img.Height++
+		if len(line) != img.Width {
+			return nil, fmt.Errorf("conformance: %s:%d: expected %d pixels, got %d", name, img.Height, img.Width, len(line))
+		}
+		for i := 0; i < len(line); i++ {
+			c := strings.IndexByte(textColors, line[i])
+			if c < 0 {
+				return nil, fmt.Errorf("conformance: %s:%d: unknown pixel %q", name, img.Height, line[i])
+			}
+			img.Pix = append(img.Pix, uint8(c))
+		}
+	}
+	return img, sc.Err()
+}
+
+func decodePNG(name string, data []byte) (*Image, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("conformance: %s: %v", name, err)
+	}
+	bounds := src.Bounds()
+	img := &Image{Width: bounds.Dx(), Height: bounds.Dy()}
+	paletted, _ := src.(*image.Paletted)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			var c uint8
+			if paletted != nil {
+				c = paletted.ColorIndexAt(x, y) & 3
+			} else if r, g, b, _ := src.At(x, y).RGBA(); (r+g+b)/3 >= 0x8000 {
+				c = 1
+			}
+			img.Pix = append(img.Pix, c)
+		}
+	}
+	return img, nil
+}
@@ -1,307 +1,508 @@
 package chip8
 
-import (
-	"fmt"
-  "io/ioutil"
-  "math/rand"
-
-  "github.com/nsf/termbox-go"
-)
-
-type cpu struct {
-	// Memory is 4096 bytes
-	memory [4096]uint8
-  // Graphics is 2048 bits
-  graphics Display
-	// There are 16 registers, each with 8 bits of memory
-	reg [16]uint8
-	// Memory address register I of 16 bits
-	i uint16
-	// Program Counter register PC of 16 bits
-	pc uint16
-	// Timer delay and sound delay
-	timerDelay uint8
-	soundDelay uint8
-	// Stack limited to 16 subcalls
-	stack [16]uint16
-	// Stack pointer
-	sp uint16
-  // Array to record which key is held
-  key [16]int
-  // Flag to see if need to flush graphics to screen
-  drawFlag bool
-}
-
 // Preloaded fonts for the memory starting at 0x000 in the memory
-var fontSprite = []uint8{ 
-  0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
-  0x20, 0x60, 0x20, 0x20, 0x70, // 1
-  0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
-  0xF0, 0x10, 0xF0, 0x10, 0xF0, // 3
-  0x90, 0x90, 0xF0, 0x10, 0x10, // 4
-  0xF0, 0x80, 0xF0, 0x10, 0xF0, // 5
-  0xF0, 0x80, 0xF0, 0x90, 0xF0, // 6
-  0xF0, 0x10, 0x20, 0x40, 0x40, // 7
-  0xF0, 0x90, 0xF0, 0x90, 0xF0, // 8
-  0xF0, 0x90, 0xF0, 0x10, 0xF0, // 9
-  0xF0, 0x90, 0xF0, 0x90, 0x90, // A
-  0xE0, 0x90, 0xE0, 0x90, 0xE0, // B
-  0xF0, 0x80, 0x80, 0x80, 0xF0, // C
-  0xE0, 0x90, 0x90, 0x90, 0xE0, // D
-  0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
-  0xF0, 0x80, 0xF0, 0x80, 0x80} // F
+var fontSprite = []uint8{
+	0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
+	0x20, 0x60, 0x20, 0x20, 0x70, // 1
+	0xF0, 0x10, 0xF0, 0x80, 0xF0, // 2
+	0xF0, 0x10, 0xF0, 0x10, 0xF0, // 3
+	0x90, 0x90, 0xF0, 0x10, 0x10, // 4
+	0xF0, 0x80, 0xF0, 0x10, 0xF0, // 5
+	0xF0, 0x80, 0xF0, 0x90, 0xF0, // 6
+	0xF0, 0x10, 0x20, 0x40, 0x40, // 7
+	0xF0, 0x90, 0xF0, 0x90, 0xF0, // 8
+	0xF0, 0x90, 0xF0, 0x10, 0xF0, // 9
+	0xF0, 0x90, 0xF0, 0x90, 0x90, // A
+	0xE0, 0x90, 0xE0, 0x90, 0xE0, // B
+	0xF0, 0x80, 0x80, 0x80, 0xF0, // C
+	0xE0, 0x90, 0x90, 0x90, 0xE0, // D
+	0xF0, 0x80, 0xF0, 0x80, 0xF0, // E
+	0xF0, 0x80, 0xF0, 0x80, 0x80} // F
 
-// Returns a new instance of a Chip-8 CPU
-func newCpu() *cpu {
-  // New returns a pointer to a new zero'ed out cpu struct
-	cp8 := new(cpu)
-	// Set stack pointer and program counter
-	cp8.pc = 0x200
-	return cp8
-}
+// Address of the SUPER-CHIP big font, right after the small font
+const bigFontAddress = 0x50
 
-// Emulate one cycle of Chip-8
-func (c8 *cpu) emulateOneCycle() {
-  // Fetch instruction
-  inst := c8.fetchInstruction()
-  // Execute instruction
-  c8.executeInstruction(inst)
-  //update timers
+// Preloaded 8x10 fonts for SUPER-CHIP, starting at bigFontAddress
+var bigFontSprite = []uint8{
+	0xFF, 0xFF, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, // 0
+	0x18, 0x78, 0x78, 0x18, 0x18, 0x18, 0x18, 0x18, 0xFF, 0xFF, // 1
+	0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // 2
+	0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 3
+	0xC3, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0x03, 0x03, 0x03, 0x03, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 5
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, // 6
+	0xFF, 0xFF, 0x03, 0x03, 0x06, 0x0C, 0x18, 0x18, 0x18, 0x18, // 7
+	0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, // 8
+	0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 9
+	0x7E, 0xFF, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3, // A
+	0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, // B
+	0x3C, 0xFF, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0xFF, 0x3C, // C
+	0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC, // D
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // E
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xC0} // F
+
+// Emulate one cycle of Chip-8. Returns a *Fault if the instruction could not
+// be executed, after applying the fault policy.
+func (c8 *Machine) emulateOneCycle() error {
+	pc := c8.pc
+	if int(pc)+1 >= len(c8.memory) {
+		return c8.fault(ErrMemoryOutOfBounds, pc, 0)
+	}
+	// Fetch instruction
+	inst := c8.fetchInstruction()
+	tracing := c8.tracer != nil && !c8.replaying()
+	var before traceBefore
+	if tracing {
+		before = c8.traceBefore(inst)
+	}
+	// Execute instruction
+	err := c8.executeInstruction(inst)
+	if tracing && (err == nil || err == ErrExit) {
+		c8.trace(pc, inst, &before)
+	}
+	switch err {
+	case nil:
+		return nil
+	case ErrStackOverflow, ErrStackUnderflow, ErrInvalidOpcode, ErrMemoryOutOfBounds:
+		return c8.fault(err, pc, inst)
+	case ErrExit:
+		c8.exited = true
+		return err
+	default:
+		// Failed to load or save the user flags, the instruction is
+		// retried on the next cycle
+		c8.pc = pc
+		return err
+	}
 }
 
 // Retrives the current Instruction from memory
-func (c8 *cpu) fetchInstruction() uint16 {
-  return uint16(c8.memory[c8.pc]) << 8 | uint16(c8.memory[c8.pc + 1])
+func (c8 *Machine) fetchInstruction() uint16 {
+	return uint16(c8.memory[c8.pc])<<8 | uint16(c8.memory[c8.pc+1])
 }
 
-// Decodes and executes instruction
-func (c8 *cpu) executeInstruction(inst uint16) {
-  // Increment pc
-  c8.pc += 2
+// Decodes and executes instruction. Returns one of the fault errors without
+// changing anything but the pc if the instruction can't be executed.
+func (c8 *Machine) executeInstruction(inst uint16) error {
+	// Increment pc
+	c8.pc += 2
 
-  // Decode instruction  type
-  switch inst & 0xF000 {
-  case 0x0000:
-    switch inst & 0x0FFF {
-    case 0x00E0:
-      // Clear screen
-      c8.graphics.clear()
-    case 0x00EE:
-      // Return from subroutine
-      c8.sp -= 1
-      c8.pc = c8.stack[c8.sp]
-    }
-  case 0x1000:
-    // JUMP to instruction at addresss 0x0NNN
-    imm := inst & 0x0FFF
-    c8.pc = imm
-  case 0x2000:
-    // CALL subroutine at address 0xNNN
-    imm := inst & 0x0FFF
-    c8.stack[c8.sp] = c8.pc - 2
-    c8.sp += 1
-    c8.pc = imm
-  case 0x3000:
-    // SKIP next instruction if VX == imm
-    regX := int(inst >> 8 & 0x0F)
-    imm := inst & 0x00FF
-    if uint16(c8.reg[regX]) == imm {
-      c8.pc += 2
-    }
-  case 0x4000:
-    // SKIP next instruction if VX != imm
-    regX := int(inst >> 8 & 0x0F)
-    imm := inst & 0x00FF
-    if uint16(c8.reg[regX]) != imm {
-      c8.pc += 2
-    }
-  case 0x5000:
-    if inst & 0x000F == 0x0 {
-      // SKIP next instruction if VX == VY
-      regX := int(inst >> 8 & 0x0F)
-      regY := int(inst >> 4 & 0x00F)
-      if c8.reg[regX] == c8.reg[regY] {
-        c8.pc += 2
-      }
-    }
-  case 0x6000:
-    // MOVE immediate into register VX
-    regX := int(inst >> 8 & 0x0F)
-    imm := inst & 0x00FF
-    c8.reg[regX] = uint8(imm)
-    // return preamble + fmt.Sprintf("MVI V%X, %02X", uint16(reg), uint16(imm))
-  case 0x7000:
-    // ADD imm to value at VX
-    regX := int(inst >> 8 & 0x0F)
-    imm := inst & 0x00FF
-    c8.reg[regX] += uint8(imm)
-  case 0x8000:
-    // Operates with two registers
-    regX := int(inst >> 8 & 0x0F)
-    regY := int(inst >> 4 & 0x00F)
-    switch inst & 0x000F {
-    case 0x0:
-      // Set VX to the value of VY
-      c8.reg[regX] = c8.reg[regY]
-    case 0x1:
-      // Set VX to the value of VY | VX
-      c8.reg[regX] = c8.reg[regY] | c8.reg[regX]
-    case 0x2:
-      // Set VX to the value of VY & VX
-      c8.reg[regX] = c8.reg[regY] & c8.reg[regX]
-    case 0x3:
-      // Set VX to the value of VY ^ VX
-      c8.reg[regX] = c8.reg[regY] ^ c8.reg[regX]
-    case 0x4:
-      // Set VX to the value of VY + VX, VF set to 1 if there is a carry over
-      temp := uint16(c8.reg[regY]) + uint16(c8.reg[regX])
-      if temp > 0xFF {
-        c8.reg[15] = 1
-      } else {
-        c8.reg[15] = 0
-      }
-      c8.reg[regX] = uint8(temp)
-    case 0x5:
-      // Set VX to the value of VX - VY, VF set to 0 if need to borrow
-      if c8.reg[regX] >= c8.reg[regY] {
-        c8.reg[15] = 1
-      } else {
-        c8.reg[15] = 0
-      }
-      c8.reg[regX] = c8.reg[regX] - c8.reg[regY]
-    case 0x6:
-      // Set VX to the value of VY >> 1, VF set to least significant digit of VY
-      c8.reg[15] = c8.reg[regY] & 1
-      c8.reg[regX] = c8.reg[regY] >> 1
-    case 0x7:
-      // Set VX to the value of VY - VX, VH set to 0 if need to borrow
-      if c8.reg[regY] >= c8.reg[regX] {
-        c8.reg[15] = 1
-      } else {
-        c8.reg[15] = 0
-      }
-      c8.reg[regX] = c8.reg[regY] - c8.reg[regX]
-    case 0xE:
-      // Set VX and VY to VY << 1, VF set to most significant digit of VY before shift.
-      c8.reg[15] = c8.reg[regY] >> 7
-      c8.reg[regX] = c8.reg[regY] << 1
-    }
-  case 0x9000:
-    if inst & 0x000F == 0x0 {
-      // SKIP next instruction if VX != VY
-      regX := (inst >> 8 & 0x0F)
-      regY := (inst >> 4 & 0x00F)
-      if c8.reg[regX] != c8.reg[regY] {
-        c8.pc += 2
-      }
-    }
-  case 0xA000:
-    // Set register I to imm
-    imm := inst & 0x0FFF
-    c8.i = uint16(imm)
-  case 0xB000:
-    // JUMP to address at imm + value at V0
-    imm := inst & 0x0FFF
-    c8.pc = uint16(c8.reg[0]) + imm
-  case 0xC000:
-    // Set register VX to Imm & rand(0,255)
-    regX := inst >> 8 & 0x0F
-    imm := inst & 0x00ff
-    c8.reg[regX] = uint8(imm) & uint8(rand.Int())
-  case 0xD000:
-    // Draw stuff to the screen
-    xCord := c8.reg[inst >> 8 & 0x0F]
-    yCord := c8.reg[inst >> 4 & 0x00F]
-    height := inst & 0x000F
-    c8.reg[15] = c8.graphics.drawSprite(xCord, yCord, height, c8.memory[c8.i:c8.i+height])
-    // Maybe Flush to screen here?
-    c8.drawFlag = true
-  case 0xE000:
-    switch inst & 0x00FF {
-    case 0x9E:
-      // SKIP next instruction if key stored in VX is held
-      regX := inst >> 8 & 0x0F
-      if c8.key[c8.reg[regX]] == 1 {
-        c8.pc += 2
-      }
-    case 0xA1:
-      // SKIP next instruction if key stored in VX isn't held
-      regX := inst >> 8 & 0x0F
-      if c8.key[c8.reg[regX]] != 1 {
-        c8.pc += 2
-      }
-    }
-  case 0xF000:
-    regX := inst >> 8 & 0x0F
-    switch inst & 0x00FF {
-    case 0x07:
-      // Set VX to value of delay timer
-      c8.reg[regX] = c8.timerDelay
-    case 0x0A:
-      // Wait for keypress, then store in VX
-      c8.reg[regX] = c8.getKey()
-    case 0x15:
-      // Set delay timer to value in VX
-      c8.timerDelay = c8.reg[regX]
-    case 0x18:
-      // Set sound timer to value in VX
-      c8.soundDelay = c8.reg[regX]
-    case 0x1E:
-      // ADDS VX to I
-      c8.i += uint16(c8.reg[regX])
-    case 0x29:
-      // Sets I to the location of sprite of character in VX
-      c8.i = uint16(c8.reg[regX] * 5)
-    case 0x33:
-      // Stores BCD of VX at I, I+1, I+2
-      value := c8.reg[regX]
-      c8.memory[c8.i + 2] = uint8(value % 10)
-      value = value / 10
-      c8.memory[c8.i + 1] = uint8(value % 10)
-      value = value / 10
-      c8.memory[c8.i] = uint8(value % 10)
-    case 0x55:
-      // Stores V0 to VX in memory starting at I
-      for j := 0; j <= int(regX); j++ {
-        c8.memory[c8.i] = c8.reg[j]
-        c8.i++
-      }
-    case 0x65:
-      // Load values at V0 to VX starting at memory address I
-      for j := 0; j <= int(regX); j++ {
-        c8.reg[j] = c8.memory[c8.i]
-        c8.i++
-      }
-    }
-  }
+	// Decode instruction  type
+	switch inst & 0xF000 {
+	case 0x0000:
+		switch inst & 0x0FFF {
+		case 0x00E0:
+			// Clear screen
+			c8.graphics.clear()
+			c8.drawFlag = true
+		case 0x00EE:
+			// Return from subroutine. The stack holds the address of the
+			// CALL, so return to the instruction after it.
+			if c8.sp == 0 {
+				return ErrStackUnderflow
+			}
+			c8.sp -= 1
+			c8.pc = c8.stack[c8.sp] + 2
+		case 0x00FB:
+			// SUPER-CHIP: Scroll the screen right by 4 pixels
+			if c8.variant < VariantSCHIP {
+				return ErrInvalidOpcode
+			}
+			c8.graphics.scrollRight(4)
+			c8.drawFlag = true
+		case 0x00FC:
+			// SUPER-CHIP: Scroll the screen left by 4 pixels
+			if c8.variant < VariantSCHIP {
+				return ErrInvalidOpcode
+			}
+			c8.graphics.scrollLeft(4)
+			c8.drawFlag = true
+		case 0x00FD:
+			// SUPER-CHIP: Exit the interpreter
+			if c8.variant < VariantSCHIP {
+				return ErrInvalidOpcode
+			}
+			return ErrExit
+		case 0x00FE, 0x00FF:
+			// SUPER-CHIP: Switch to low (00FE) or high (00FF) resolution
+			if c8.variant < VariantSCHIP {
+				return ErrInvalidOpcode
+			}
+			c8.graphics.setHiRes(inst == 0x00FF)
+			c8.drawFlag = true
+		default:
+			switch {
+			case inst&0x0FF0 == 0x00C0 && c8.variant >= VariantSCHIP:
+				// SUPER-CHIP: Scroll the screen down by N pixels
+				c8.graphics.scrollDown(int(inst & 0x000F))
+			case inst&0x0FF0 == 0x00D0 && c8.variant >= VariantXOCHIP:
+				// XO-CHIP: Scroll the screen up by N pixels
+				c8.graphics.scrollUp(int(inst & 0x000F))
+			default:
+				return ErrInvalidOpcode
+			}
+			c8.drawFlag = true
+		}
+	case 0x1000:
+		// JUMP to instruction at addresss 0x0NNN
+		imm := inst & 0x0FFF
+		c8.pc = imm
+	case 0x2000:
+		// CALL subroutine at address 0xNNN
+		imm := inst & 0x0FFF
+		if int(c8.sp) >= len(c8.stack) {
+			return ErrStackOverflow
+		}
+		c8.stack[c8.sp] = c8.pc - 2
+		c8.sp += 1
+		c8.pc = imm
+	case 0x3000:
+		// SKIP next instruction if VX == imm
+		regX := int(inst >> 8 & 0x0F)
+		imm := inst & 0x00FF
+		if uint16(c8.reg[regX]) == imm {
+			c8.skip()
+		}
+	case 0x4000:
+		// SKIP next instruction if VX != imm
+		regX := int(inst >> 8 & 0x0F)
+		imm := inst & 0x00FF
+		if uint16(c8.reg[regX]) != imm {
+			c8.skip()
+		}
+	case 0x5000:
+		if inst&0x000F == 0x0 {
+			// SKIP next instruction if VX == VY
+			regX := int(inst >> 8 & 0x0F)
+			regY := int(inst >> 4 & 0x00F)
+			if c8.reg[regX] == c8.reg[regY] {
+				c8.skip()
+			}
+		} else if inst&0x000F == 0x2 || inst&0x000F == 0x3 {
+			// XO-CHIP: Store (5XY2) or load (5XY3) VX to VY at I, in
+			// reverse order when X > Y. I is left unchanged.
+			if c8.variant < VariantXOCHIP {
+				return ErrInvalidOpcode
+			}
+			regX := int(inst >> 8 & 0x0F)
+			regY := int(inst >> 4 & 0x00F)
+			step := 1
+			if regX > regY {
+				step = -1
+			}
+			count := (regY-regX)*step + 1
+			if int(c8.i)+count > len(c8.memory) {
+				return ErrMemoryOutOfBounds
+			}
+			for j := 0; j < count; j++ {
+				if inst&0x000F == 0x2 {
+					c8.memory[int(c8.i)+j] = c8.reg[regX+j*step]
+				} else {
+					c8.reg[regX+j*step] = c8.memory[int(c8.i)+j]
+				}
+			}
+		} else {
+			return ErrInvalidOpcode
+		}
+	case 0x6000:
+		// MOVE immediate into register VX
+		regX := int(inst >> 8 & 0x0F)
+		imm := inst & 0x00FF
+		c8.reg[regX] = uint8(imm)
+		// return preamble + fmt.Sprintf("MVI V%X, %02X", uint16(reg), uint16(imm))
+	case 0x7000:
+		// ADD imm to value at VX
+		regX := int(inst >> 8 & 0x0F)
+		imm := inst & 0x00FF
+		c8.reg[regX] += uint8(imm)
+	case 0x8000:
+		// Operates with two registers
+		regX := int(inst >> 8 & 0x0F)
+		regY := int(inst >> 4 & 0x00F)
+		switch inst & 0x000F {
+		case 0x0:
+			// Set VX to the value of VY
+			c8.reg[regX] = c8.reg[regY]
+		// The flag is written to VF after the result, so it wins when X is F
+		case 0x1:
+			// Set VX to the value of VY | VX
+			c8.reg[regX] = c8.reg[regY] | c8.reg[regX]
+			if c8.quirks.VFReset {
+				c8.reg[15] = 0
+			}
+		case 0x2:
+			// Set VX to the value of VY & VX
+			c8.reg[regX] = c8.reg[regY] & c8.reg[regX]
+			if c8.quirks.VFReset {
+				c8.reg[15] = 0
+			}
+		case 0x3:
+			// Set VX to the value of VY ^ VX
+			c8.reg[regX] = c8.reg[regY] ^ c8.reg[regX]
+			if c8.quirks.VFReset {
+				c8.reg[15] = 0
+			}
+		case 0x4:
+			// Set VX to the value of VY + VX, VF set to 1 if there is a carry over
+			temp := uint16(c8.reg[regY]) + uint16(c8.reg[regX])
+			c8.reg[regX] = uint8(temp)
+			c8.reg[15] = uint8(temp >> 8)
+		case 0x5:
+			// Set VX to the value of VX - VY, VF set to 0 if need to borrow
+			flag := boolToFlag(c8.reg[regX] >= c8.reg[regY])
+			c8.reg[regX] = c8.reg[regX] - c8.reg[regY]
+			c8.reg[15] = flag
+		case 0x6:
+			// Set VX to the value of VY >> 1, VF set to least significant digit of VY
+			src := c8.reg[regY]
+			if c8.quirks.ShiftVX {
+				src = c8.reg[regX]
+			}
+			c8.reg[regX] = src >> 1
+			c8.reg[15] = src & 1
+		case 0x7:
+			// Set VX to the value of VY - VX, VH set to 0 if need to borrow
+			flag := boolToFlag(c8.reg[regY] >= c8.reg[regX])
+			c8.reg[regX] = c8.reg[regY] - c8.reg[regX]
+			c8.reg[15] = flag
+		case 0xE:
+			// Set VX to VY << 1, VF set to most significant digit of VY before shift.
+			src := c8.reg[regY]
+			if c8.quirks.ShiftVX {
+				src = c8.reg[regX]
+			}
+			c8.reg[regX] = src << 1
+			c8.reg[15] = src >> 7
+		default:
+			return ErrInvalidOpcode
+		}
+	case 0x9000:
+		if inst&0x000F == 0x0 {
+			// SKIP next instruction if VX != VY
+			regX := (inst >> 8 & 0x0F)
+			regY := (inst >> 4 & 0x00F)
+			if c8.reg[regX] != c8.reg[regY] {
+				c8.skip()
+			}
+		} else {
+			return ErrInvalidOpcode
+		}
+	case 0xA000:
+		// Set register I to imm
+		imm := inst & 0x0FFF
+		c8.i = uint16(imm)
+	case 0xB000:
+		// JUMP to address at imm + value at V0, or at VX where X is the
+		// highest digit of imm
+		imm := inst & 0x0FFF
+		if c8.quirks.JumpVX {
+			c8.pc = uint16(c8.reg[inst>>8&0x0F]) + imm
+		} else {
+			c8.pc = uint16(c8.reg[0]) + imm
+		}
+	case 0xC000:
+		// Set register VX to Imm & rand(0,255)
+		regX := inst >> 8 & 0x0F
+		imm := inst & 0x00ff
+		c8.reg[regX] = uint8(imm) & c8.randomByte()
+	case 0xD000:
+		// Draw stuff to the screen
+		xCord := c8.reg[inst>>8&0x0F]
+		yCord := c8.reg[inst>>4&0x00F]
+		height := inst & 0x000F
+		// XO-CHIP: One sprite for each selected plane
+		planes := c8.graphics.selectedPlanes()
+		// SUPER-CHIP 1.1 counts rows in VF in high resolution mode only
+		mode := spriteMode{
+			clip:      c8.quirks.Clip,
+			countRows: c8.quirks.CollisionRows && c8.graphics.hires,
+		}
+		if height == 0 && c8.variant >= VariantSCHIP {
+			// SUPER-CHIP: 16x16 sprite of 32 bytes
+			if int(c8.i)+32*planes > len(c8.memory) {
+				return ErrMemoryOutOfBounds
+			}
+			c8.reg[15] = c8.graphics.drawWideSprite(xCord, yCord, c8.memory[c8.i:int(c8.i)+32*planes], mode)
+			c8.drawFlag = true
+			c8.vblankWait = c8.quirks.DisplayWait
+			break
+		}
+		if int(c8.i)+int(height)*planes > len(c8.memory) {
+			return ErrMemoryOutOfBounds
+		}
+		c8.reg[15] = c8.graphics.drawSprite(xCord, yCord, height, c8.memory[c8.i:int(c8.i)+int(height)*planes], mode)
+		c8.drawFlag = true
+		c8.vblankWait = c8.quirks.DisplayWait
+	case 0xE000:
+		switch inst & 0x00FF {
+		case 0x9E:
+			// SKIP next instruction if key stored in VX is held
+			regX := inst >> 8 & 0x0F
+			if c8.KeyPressed(c8.reg[regX]) {
+				c8.skip()
+			}
+		case 0xA1:
+			// SKIP next instruction if key stored in VX isn't held
+			regX := inst >> 8 & 0x0F
+			if !c8.KeyPressed(c8.reg[regX]) {
+				c8.skip()
+			}
+		default:
+			return ErrInvalidOpcode
+		}
+	case 0xF000:
+		regX := inst >> 8 & 0x0F
+		switch inst & 0x00FF {
+		case 0x00:
+			// XO-CHIP: F000 NNNN sets I to the 16-bit address NNNN
+			if inst != 0xF000 || c8.variant < VariantXOCHIP {
+				return ErrInvalidOpcode
+			}
+			if int(c8.pc)+1 >= len(c8.memory) {
+				return ErrMemoryOutOfBounds
+			}
+			c8.i = c8.fetchInstruction()
+			c8.pc += 2
+		case 0x01:
+			// XO-CHIP: Select the planes drawn to, cleared and scrolled
+			if c8.variant < VariantXOCHIP {
+				return ErrInvalidOpcode
+			}
+			c8.graphics.selectPlanes(uint8(regX))
+		case 0x02:
+			// XO-CHIP: Load the 16 bytes at I into the audio pattern
+			if regX != 0 || c8.variant < VariantXOCHIP {
+				return ErrInvalidOpcode
+			}
+			if int(c8.i)+len(c8.pattern) > len(c8.memory) {
+				return ErrMemoryOutOfBounds
+			}
+			copy(c8.pattern[:], c8.memory[c8.i:])
+		case 0x07:
+			// Set VX to value of delay timer
+			c8.reg[regX] = c8.timerDelay
+		case 0x0A:
+			// Wait for keypress, then store in VX. The machine is suspended
+			// until the key is released, see waitForKey.
+			c8.waitingKey = true
+			c8.waitReg = uint8(regX)
+			c8.waitKey = -1
+		case 0x15:
+			// Set delay timer to value in VX
+			c8.timerDelay = c8.reg[regX]
+		case 0x18:
+			// Set sound timer to value in VX
+			c8.soundDelay = c8.reg[regX]
+		case 0x1E:
+			// ADDS VX to I
+			c8.i += uint16(c8.reg[regX])
+		case 0x29:
+			// Sets I to the location of sprite of the character in the low
+			// nibble of VX
+			c8.i = uint16(c8.reg[regX]&0x0F) * 5
+		case 0x30:
+			// SUPER-CHIP: Sets I to the location of the big sprite of
+			// character in VX
+			if c8.variant < VariantSCHIP {
+				return ErrInvalidOpcode
+			}
+			c8.i = bigFontAddress + uint16(c8.reg[regX]&0x0F)*10
+		case 0x3A:
+			// XO-CHIP: Sets the pitch of the audio pattern to VX
+			if c8.variant < VariantXOCHIP {
+				return ErrInvalidOpcode
+			}
+			c8.pitch = c8.reg[regX]
+		case 0x33:
+			// Stores BCD of VX at I, I+1, I+2
+			if int(c8.i)+2 >= len(c8.memory) {
+				return ErrMemoryOutOfBounds
+			}
+			value := c8.reg[regX]
+			c8.memory[c8.i+2] = uint8(value % 10)
+			value = value / 10
+			c8.memory[c8.i+1] = uint8(value % 10)
+			value = value / 10
+			c8.memory[c8.i] = uint8(value % 10)
+		case 0x55:
+			// Stores V0 to VX in memory starting at I
+			if int(c8.i)+int(regX) >= len(c8.memory) {
+				return ErrMemoryOutOfBounds
+			}
+			for j := 0; j <= int(regX); j++ {
+				c8.memory[int(c8.i)+j] = c8.reg[j]
+			}
+			c8.incrementI(regX)
+		case 0x65:
+			// Load values at V0 to VX starting at memory address I
+			if int(c8.i)+int(regX) >= len(c8.memory) {
+				return ErrMemoryOutOfBounds
+			}
+			for j := 0; j <= int(regX); j++ {
+				c8.reg[j] = c8.memory[int(c8.i)+j]
+			}
+			c8.incrementI(regX)
+		case 0x75:
+			// SUPER-CHIP: Stores V0 to VX in the user flags
+			if c8.variant < VariantSCHIP {
+				return ErrInvalidOpcode
+			}
+			if err := c8.loadFlags(); err != nil {
+				return err
+			}
+			copy(c8.rpl[:regX+1], c8.reg[:regX+1])
+			if c8.flagStore != nil {
+				if err := c8.flagStore.SaveFlags(c8.rpl[:]); err != nil {
+					return err
+				}
+			}
+		case 0x85:
+			// SUPER-CHIP: Loads V0 to VX from the user flags
+			if c8.variant < VariantSCHIP {
+				return ErrInvalidOpcode
+			}
+			if err := c8.loadFlags(); err != nil {
+				return err
+			}
+			copy(c8.reg[:regX+1], c8.rpl[:regX+1])
+		default:
+			return ErrInvalidOpcode
+		}
+	}
+	return nil
 }
 
-func (c8 *cpu) loadFile(fileName string) {
-  buffer, err := ioutil.ReadFile(fileName)
-
-  if err != nil {
-    fmt.Printf("Welp")
-  }
-
-  for i := 0; i < len(buffer); i++ {
-    c8.memory[512 + i] = buffer[i]
-  }
+// Skips the next instruction, which is four bytes long when it is the
+// XO-CHIP F000 NNNN
+func (c8 *Machine) skip() {
+	if c8.variant >= VariantXOCHIP && int(c8.pc)+1 < len(c8.memory) && c8.fetchInstruction() == 0xF000 {
+		c8.pc += 4
+		return
+	}
+	c8.pc += 2
 }
 
-var keyMap = map[rune]byte{
-  '1': 0x01, '2': 0x02, '3': 0x03, '4': 0x0C,
-  'q': 0x04, 'w': 0x05, 'e': 0x06, 'r': 0x0D,
-  'a': 0x07, 's': 0x08, 'd': 0x09, 'f': 0x0E,
-  'z': 0x0A, 'x': 0x00, 'c': 0x0B, 'v': 0x0F,
+// Moves I past the registers stored or loaded by FX55 and FX65 up to VX
+func (c8 *Machine) incrementI(regX uint16) {
+	switch c8.quirks.LoadStore {
+	case LoadStoreIncrement:
+		c8.i += regX + 1
+	case LoadStoreIncrementX:
+		c8.i += regX
+	}
 }
 
-func (c8 *cpu) getKey() (uint8) {
-  key := termbox.PollEvent()
-  return keyMap[key.Ch]
+// Returns 1 for true and 0 for false
+func boolToFlag(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
 }
 
-func (c8 *cpu) loadSprites() {
-  for i := 0; i < 80; i++ {
-    c8.memory[i] = fontSprite[i]
-  }
-}
\ No newline at end of file
+func (c8 *Machine) loadSprites() {
+	for i := 0; i < 80; i++ {
+		c8.memory[i] = fontSprite[i]
+	}
+	copy(c8.memory[bigFontAddress:], bigFontSprite)
+}
@@ -1,26 +1,35 @@
 package chip8
 
 import (
+	"os"
 	"testing"
 )
 
 func TestDissassemble(t *testing.T) {
-	c8 := newCpu()
-	c8.loadFile("Fishie.ch8")
-	for i := 0; i < 10; i++ {
-		c8.emulateOneCycle()
+	rom, err := os.Open("Fishie.ch8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rom.Close()
+	c8, err := NewFromROM(rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c8.RunFor(10)
+	if c8.Cycles() != 10 {
+		t.Errorf("Expected 10 cycles, got %d instead", c8.Cycles())
 	}
 }
 
 func TestInit(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	if c8.pc != 0x200 {
 		t.Errorf("Expected pc of 0x200, got %X instead", c8.pc)
 	}
 }
 
 func TestInstruction1(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0x1FFF)
 	c8.executeInstruction(inst)
 	if c8.pc != 0x0FFF {
@@ -29,7 +38,7 @@ func TestInstruction1(t *testing.T) {
 }
 
 func TestInstruction2(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0x20AA)
 	c8.executeInstruction(inst)
 
@@ -45,7 +54,7 @@ func TestInstruction2(t *testing.T) {
 }
 
 func TestInstruction3(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0x30AA)
 	c8.reg[0] = 0xAA
 	c8.executeInstruction(inst)
@@ -53,7 +62,7 @@ func TestInstruction3(t *testing.T) {
 		t.Errorf("Expected pc of 0x204 , got 0x%04X instead", c8.pc)
 	}
 
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x30AA)
 	c8.reg[0] = 0xAB
 	c8.executeInstruction(inst)
@@ -63,7 +72,7 @@ func TestInstruction3(t *testing.T) {
 }
 
 func TestInstruction4(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0x40AA)
 	c8.reg[0] = 0xAB
 	c8.executeInstruction(inst)
@@ -72,7 +81,7 @@ func TestInstruction4(t *testing.T) {
 		t.Errorf("Expected pc of 0x204 , got 0x%04X instead", c8.pc)
 	}
 
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x40AA)
 	c8.reg[0] = 0xAA
 	c8.executeInstruction(inst)
@@ -83,7 +92,7 @@ func TestInstruction4(t *testing.T) {
 }
 
 func TestInstruction5(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0x50A0)
 	c8.reg[0] = 0xAA
 	c8.reg[10] = 0xAA
@@ -93,7 +102,7 @@ func TestInstruction5(t *testing.T) {
 		t.Errorf("Expected pc of 0x204 , got 0x%04X instead", c8.pc)
 	}
 
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x50A0)
 	c8.reg[0] = 0xAA
 	c8.reg[10] = 0xAB
@@ -105,7 +114,7 @@ func TestInstruction5(t *testing.T) {
 }
 
 func TestInstruction6(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0x6DAA)
 	c8.executeInstruction(inst)
 
@@ -115,7 +124,7 @@ func TestInstruction6(t *testing.T) {
 }
 
 func TestInstruction7(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0x7401)
 	c8.executeInstruction(inst)
 	if c8.reg[4] != 0x01 {
@@ -129,7 +138,7 @@ func TestInstruction7(t *testing.T) {
 
 func TestInstruction8(t *testing.T) {
 	// 8XY0
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0x80A0)
 	c8.reg[10] = 0xAD
 	c8.executeInstruction(inst)
@@ -138,7 +147,7 @@ func TestInstruction8(t *testing.T) {
 	}
 
 	// 8XY1
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x80A1)
 	c8.reg[0] = 0xAA
 	c8.reg[0xA] = 0x55
@@ -148,7 +157,7 @@ func TestInstruction8(t *testing.T) {
 	}
 
 	// 8XY2
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x80A2)
 	c8.reg[0] = 0xAA
 	c8.reg[0xA] = 0x55
@@ -158,7 +167,7 @@ func TestInstruction8(t *testing.T) {
 	}
 
 	// 8XY3
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x80A3)
 	c8.reg[0] = 0xAA
 	c8.reg[0xA] = 0x55
@@ -168,7 +177,7 @@ func TestInstruction8(t *testing.T) {
 	}
 
 	// 8XY4
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x80A4)
 	c8.reg[0] = 0xAA
 	c8.reg[0xA] = 0x55
@@ -191,7 +200,7 @@ func TestInstruction8(t *testing.T) {
 	}
 
 	// 8XY5
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x80A5)
 	c8.reg[0] = 0xFF
 	c8.reg[0xA] = 0xAA
@@ -214,7 +223,7 @@ func TestInstruction8(t *testing.T) {
 	}
 
 	// 8XY6
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x80A6)
 	c8.reg[0xA] = 0xFF
 	c8.executeInstruction(inst)
@@ -235,7 +244,7 @@ func TestInstruction8(t *testing.T) {
 	}
 
 	// 8XY7
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x80A7)
 	c8.reg[0x0] = 0xFF
 	c8.reg[0xA] = 0xFF
@@ -258,7 +267,7 @@ func TestInstruction8(t *testing.T) {
 	}
 
 	// 8XYE
-	c8 = newCpu()
+	c8 = New()
 	inst = uint16(0x80AE)
 	c8.reg[0xA] = 0xFF
 	c8.executeInstruction(inst)
@@ -272,7 +281,7 @@ func TestInstruction8(t *testing.T) {
 
 // 9XY0
 func TestInstruction9(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0x90A0)
 	c8.reg[0] = 0x0A
 	c8.reg[0xA] = 0x0A
@@ -292,7 +301,7 @@ func TestInstruction9(t *testing.T) {
 
 // ANNN
 func TestInstructionA(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0xABAA)
 	c8.executeInstruction(inst)
 
@@ -303,7 +312,7 @@ func TestInstructionA(t *testing.T) {
 
 // BNNN
 func TestInstructionB(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0xBAAA)
 	c8.executeInstruction(inst)
 	if c8.pc != 0xAAA {
@@ -318,7 +327,7 @@ func TestInstructionB(t *testing.T) {
 
 // CXNN
 func TestInstructionC(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0xC000)
 	c8.executeInstruction(inst)
 
@@ -329,7 +338,7 @@ func TestInstructionC(t *testing.T) {
 
 func TestInstructionE(t *testing.T) {
 	// EX9E
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0xED9E)
 	c8.executeInstruction(inst)
 
@@ -340,7 +349,7 @@ func TestInstructionE(t *testing.T) {
 
 func TestInstructionF(t *testing.T) {
 	// FX07
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0xF207)
 	c8.timerDelay = 0x0D
 	c8.executeInstruction(inst)
@@ -388,6 +397,13 @@ func TestInstructionF(t *testing.T) {
 		t.Errorf("Expected value of 0x0000 , got 0x%04X instead", c8.i)
 	}
 
+	// Only the low nibble picks the character
+	c8.reg[3] = 0x3F
+	c8.executeInstruction(0xF329)
+	if c8.i != 0x004B {
+		t.Errorf("Expected value of 0x004B, got 0x%04X instead", c8.i)
+	}
+
 	// FX33
 	c8.reg[4] = 0x7B
 	inst = uint16(0xF433)
@@ -404,7 +420,7 @@ func TestInstructionF(t *testing.T) {
 	}
 
 	// FX55
-	c8 = newCpu()
+	c8 = New()
 	c8.reg[0] = 0x00
 	c8.reg[1] = 0x01
 	c8.reg[2] = 0x02
@@ -447,7 +463,7 @@ func TestInstructionF(t *testing.T) {
 }
 
 func TestInstructionX(t *testing.T) {
-	c8 := newCpu()
+	c8 := New()
 	inst := uint16(0x20AA)
 	c8.executeInstruction(inst)
 
@@ -0,0 +1,181 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/albertseo/chip8"
+)
+
+type breakpointKind int
+
+const (
+	kindPC breakpointKind = iota
+	kindOpcode
+	kindWatch
+)
+
+// Breakpoint stops the machine before an instruction. It is created with
+// AtPC, OnOpcode, Watch or Parse.
+type Breakpoint struct {
+	// Set by Debugger.Add
+	ID int
+	// Description in the syntax of Parse
+	Spec string
+	// Optional name shown instead of Spec when the breakpoint is hit, such
+	// as the name of an Octo :breakpoint
+	Name string
+
+	kind breakpointKind
+	pc   uint16
+	// Opcode bits that must match value
+	mask  uint16
+	value uint16
+	// Value watched, the value to stop at or -1 to stop at any change, and
+	// the value seen before the last instruction
+	watch func(m *chip8.Machine) int
+	want  int
+	last  int
+}
+
+// Returns a breakpoint stopping before the instruction at addr
+func AtPC(addr uint16) *Breakpoint {
+	return &Breakpoint{Spec: fmt.Sprintf("pc %03X", addr), kind: kindPC, pc: addr}
+}
+
+// Returns a breakpoint stopping before instructions matching pattern: four
+// characters that are either a hex digit the opcode must have there, or one
+// of X, Y, N, K, ? and . matching any digit. For example D..F stops at any
+// sprite drawn 15 rows tall.
+func OnOpcode(pattern string) (*Breakpoint, error) {
+	if len(pattern) != 4 {
+		return nil, fmt.Errorf("debugger: opcode pattern %q is not 4 characters", pattern)
+	}
+	bp := &Breakpoint{Spec: "op " + strings.ToUpper(pattern), kind: kindOpcode}
+	for _, c := range strings.ToUpper(pattern) {
+		bp.mask <<= 4
+		bp.value <<= 4
+		if digit, err := strconv.ParseUint(string(c), 16, 4); err == nil {
+			bp.mask |= 0xF
+			bp.value |= uint16(digit)
+		} else if !strings.ContainsRune("XYNK?.", c) {
+			return nil, fmt.Errorf("debugger: invalid character %q in opcode pattern %q", c, pattern)
+		}
+	}
+	return bp, nil
+}
+
+// Returns a breakpoint stopping after an instruction changes a value. expr
+// names the value, V0 to VF, I, PC, SP, DT, ST or a memory address in
+// brackets like [2F0], optionally followed by == and a hex value to stop
+// only when it changes to that value.
+func Watch(expr string) (*Breakpoint, error) {
+	want := -1
+	name := expr
+	if i := strings.Index(expr, "=="); i >= 0 {
+		name = expr[:i]
+		v, err := strconv.ParseUint(trimHex(expr[i+2:]), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("debugger: invalid value in watch %q", expr)
+		}
+		want = int(v)
+	}
+	name = strings.ToUpper(strings.TrimSpace(name))
+	watch, err := watchValue(name)
+	if err != nil {
+		return nil, err
+	}
+	spec := "watch " + name
+	if want >= 0 {
+		spec += fmt.Sprintf(" == %X", want)
+	}
+	return &Breakpoint{Spec: spec, kind: kindWatch, watch: watch, want: want}, nil
+}
+
+// Returns a function reading the value called name
+func watchValue(name string) (func(m *chip8.Machine) int, error) {
+	switch name {
+	case "I":
+		return func(m *chip8.Machine) int { return int(m.I()) }, nil
+	case "PC":
+		return func(m *chip8.Machine) int { return int(m.PC()) }, nil
+	case "SP":
+		return func(m *chip8.Machine) int { return int(m.SP()) }, nil
+	case "DT":
+		return func(m *chip8.Machine) int { return int(m.DelayTimer()) }, nil
+	case "ST":
+		return func(m *chip8.Machine) int { return int(m.SoundTimer()) }, nil
+	}
+	if len(name) == 2 && name[0] == 'V' {
+		reg, err := strconv.ParseUint(name[1:], 16, 4)
+		if err == nil {
+			return func(m *chip8.Machine) int { return int(m.Registers()[reg]) }, nil
+		}
+	}
+	if strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]") {
+		addr, err := strconv.ParseUint(trimHex(name[1:len(name)-1]), 16, 16)
+		if err == nil {
+			return func(m *chip8.Machine) int { return int(m.ReadMemory(uint16(addr))) }, nil
+		}
+	}
+	return nil, fmt.Errorf("debugger: cannot watch %q", name)
+}
+
+// Parses a breakpoint: "pc ADDR" or just "ADDR" for AtPC, "op PATTERN" for
+// OnOpcode and "watch EXPR" for Watch. Numbers are hex, with or without 0x.
+func Parse(spec string) (*Breakpoint, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("debugger: empty breakpoint")
+	}
+	switch strings.ToLower(fields[0]) {
+	case "op":
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("debugger: expected op PATTERN, got %q", spec)
+		}
+		return OnOpcode(fields[1])
+	case "watch":
+		return Watch(strings.Join(fields[1:], " "))
+	case "pc":
+		fields = fields[1:]
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("debugger: expected pc ADDR, got %q", spec)
+	}
+	addr, err := strconv.ParseUint(trimHex(fields[0]), 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("debugger: invalid address %q", fields[0])
+	}
+	return AtPC(uint16(addr)), nil
+}
+
+// Strips spaces and a 0x prefix from a hex number
+func trimHex(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return s[2:]
+	}
+	return s
+}
+
+// Starts watching from the current state of m
+func (bp *Breakpoint) reset(m *chip8.Machine) {
+	if bp.watch != nil {
+		bp.last = bp.watch(m)
+	}
+}
+
+// Reports whether the breakpoint stops m before its next instruction
+func (bp *Breakpoint) hit(m *chip8.Machine) bool {
+	switch bp.kind {
+	case kindPC:
+		return m.PC() == bp.pc
+	case kindOpcode:
+		return opcode(m, m.PC())&bp.mask == bp.value
+	}
+	v := bp.watch(m)
+	changed := v != bp.last
+	bp.last = v
+	return changed && (bp.want < 0 || v == bp.want)
+}
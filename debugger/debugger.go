@@ -0,0 +1,209 @@
+// Package debugger pauses a Chip-8 machine on breakpoints and steps it one
+// instruction at a time. It holds no user interface, see the terminal
+// frontend for one.
+package debugger
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/albertseo/chip8"
+)
+
+// ErrNotInSubroutine is returned by StepOut when the stack is empty
+var ErrNotInSubroutine = errors.New("debugger: not in a subroutine")
+
+// Debugger controls a machine through its break hook. The machine is run
+// as usual with RunFrame or a Scheduler, which return chip8.ErrBreak once
+// the debugger pauses it. While paused the machine must not be run, and it
+// is resumed with Continue or one of the steps.
+type Debugger struct {
+	m           *chip8.Machine
+	breakpoints []*Breakpoint
+	nextID      int
+	monitors    []Monitor
+	paused      bool
+	reason      string
+	// Stops the machine once true, for the steps
+	until func(m *chip8.Machine) bool
+	// Cycle the machine was resumed at. Breakpoints at the instruction it
+	// was paused on are ignored so that it can move on.
+	resumeCycle uint64
+	resuming    bool
+}
+
+// Returns a Debugger controlling m, starting paused. The break hook of m
+// is replaced.
+func New(m *chip8.Machine) *Debugger {
+	d := &Debugger{m: m, paused: true, reason: "paused", nextID: 1}
+	m.SetBreakHook(d.check)
+	return d
+}
+
+// Reports whether the machine is paused
+func (d *Debugger) Paused() bool {
+	return d.paused
+}
+
+// Returns why the machine was last paused
+func (d *Debugger) Reason() string {
+	return d.reason
+}
+
+// Pauses the machine. Call only while the machine is not running, for
+// instance after a Scheduler has been stopped or has returned a fault.
+func (d *Debugger) Pause(reason string) {
+	d.paused = true
+	d.reason = reason
+	d.until = nil
+}
+
+// Resumes the machine until a breakpoint is hit
+func (d *Debugger) Continue() {
+	d.resume(nil)
+}
+
+// Resumes the machine for one instruction
+func (d *Debugger) Step() {
+	d.resume(func(m *chip8.Machine) bool { return true })
+}
+
+// Like Step, but a CALL runs until the subroutine returns
+func (d *Debugger) StepOver() {
+	pc, sp := d.m.PC(), d.m.SP()
+	if opcode(d.m, pc)&0xF000 != 0x2000 {
+		d.Step()
+		return
+	}
+	d.resume(func(m *chip8.Machine) bool {
+		return m.SP() == sp && m.PC() == pc+2
+	})
+}
+
+// Resumes the machine until the current subroutine returns
+func (d *Debugger) StepOut() error {
+	sp := d.m.SP()
+	if sp == 0 {
+		return ErrNotInSubroutine
+	}
+	d.resume(func(m *chip8.Machine) bool { return m.SP() < sp })
+	return nil
+}
+
+func (d *Debugger) resume(until func(m *chip8.Machine) bool) {
+	d.paused = false
+	d.reason = ""
+	d.until = until
+	d.resumeCycle = d.m.Cycles()
+	d.resuming = true
+}
+
+// The break hook of the machine
+func (d *Debugger) check(m *chip8.Machine) bool {
+	if d.paused {
+		return true
+	}
+	if d.resuming && m.Cycles() == d.resumeCycle {
+		// Keep the watches up to date
+		for _, bp := range d.breakpoints {
+			bp.hit(m)
+		}
+		return false
+	}
+	d.resuming = false
+
+	reason := ""
+	for _, bp := range d.breakpoints {
+		// Every watch is checked so that it sees each change once
+		if bp.hit(m) && reason == "" {
+			name := bp.Spec
+			if bp.Name != "" {
+				name = bp.Name
+			}
+			reason = fmt.Sprintf("breakpoint %d: %s", bp.ID, name)
+		}
+	}
+	if reason == "" && d.until != nil && d.until(m) {
+		reason = "step"
+	}
+	if reason == "" {
+		return false
+	}
+	d.Pause(reason)
+	return true
+}
+
+// Adds a breakpoint and returns its ID
+func (d *Debugger) Add(bp *Breakpoint) int {
+	bp.ID = d.nextID
+	d.nextID++
+	bp.reset(d.m)
+	d.breakpoints = append(d.breakpoints, bp)
+	return bp.ID
+}
+
+// Removes the breakpoint with the given ID, reporting whether it existed
+func (d *Debugger) Remove(id int) bool {
+	for i, bp := range d.breakpoints {
+		if bp.ID == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the breakpoints in the order they were added
+func (d *Debugger) Breakpoints() []*Breakpoint {
+	return append([]*Breakpoint(nil), d.breakpoints...)
+}
+
+// Removes the breakpoint at addr if there is one and adds one otherwise
+func (d *Debugger) Toggle(addr uint16) {
+	for _, bp := range d.breakpoints {
+		if bp.pc == addr && bp.kind == kindPC {
+			d.Remove(bp.ID)
+			return
+		}
+	}
+	d.Add(AtPC(addr))
+}
+
+// Reports whether a breakpoint stops the machine at addr
+func (d *Debugger) HasBreakpoint(addr uint16) bool {
+	for _, bp := range d.breakpoints {
+		if bp.pc == addr && bp.kind == kindPC {
+			return true
+		}
+	}
+	return false
+}
+
+// Line is a disassembled instruction
+type Line struct {
+	Addr   uint16
+	Opcode uint16
+	// Opcode and mnemonic, see chip8.Mnemonic
+	Text string
+}
+
+// Disassembles the instructions around the PC, before of them in front of
+// it and after past it
+func (d *Debugger) Disassemble(before, after int) []Line {
+	pc := int(d.m.PC())
+	start := pc - 2*before
+	for start < 0 {
+		start += 2
+	}
+	var lines []Line
+	for addr := start; addr <= pc+2*after && addr < 0x10000; addr += 2 {
+		op := opcode(d.m, uint16(addr))
+		lines = append(lines, Line{Addr: uint16(addr), Opcode: op, Text: chip8.Mnemonic(op)})
+	}
+	return lines
+}
+
+// Returns the instruction at addr
+func opcode(m *chip8.Machine, addr uint16) uint16 {
+	return uint16(m.ReadMemory(addr))<<8 | uint16(m.ReadMemory(addr+1))
+}
@@ -0,0 +1,173 @@
+package debugger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/albertseo/chip8"
+)
+
+// Counts up in V0 and calls a subroutine adding 2 to V1
+var testROM = []byte{
+	0x70, 0x01, // 200: ADD V0, 01
+	0x22, 0x08, // 202: CALL 0x208
+	0xA3, 0x00, // 204: MVI I 0x300
+	0x12, 0x00, // 206: JUMP 0x200
+	0x71, 0x01, // 208: ADD V1, 01
+	0x71, 0x01, // 20A: ADD V1, 01
+	0x00, 0xEE, // 20C: RTS
+}
+
+func newTestDebugger(t *testing.T) (*chip8.Machine, *Debugger) {
+	m, err := chip8.NewFromROM(bytes.NewReader(testROM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, New(m)
+}
+
+// Runs frames until the debugger pauses the machine
+func runUntilBreak(t *testing.T, m *chip8.Machine) {
+	for i := 0; i < 100; i++ {
+		err := m.RunFrame()
+		if err == chip8.ErrBreak {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Fatalf("Expected the machine to break")
+}
+
+func TestStartsPaused(t *testing.T) {
+	m, d := newTestDebugger(t)
+	if !d.Paused() {
+		t.Errorf("Expected a new debugger to be paused")
+	}
+	if err := m.RunFrame(); err != chip8.ErrBreak {
+		t.Errorf("Expected ErrBreak, got %v instead", err)
+	}
+	if m.Cycles() != 0 {
+		t.Errorf("Expected no instructions to run, got %d instead", m.Cycles())
+	}
+}
+
+func TestStep(t *testing.T) {
+	m, d := newTestDebugger(t)
+	d.Step()
+	runUntilBreak(t, m)
+	if m.PC() != 0x202 || m.Cycles() != 1 {
+		t.Errorf("Expected to stop at 0x202 after 1 cycle, got 0x%04X after %d instead", m.PC(), m.Cycles())
+	}
+	d.Step()
+	runUntilBreak(t, m)
+	if m.PC() != 0x208 {
+		t.Errorf("Expected to step into the CALL, got pc 0x%04X instead", m.PC())
+	}
+}
+
+func TestStepOverAndOut(t *testing.T) {
+	m, d := newTestDebugger(t)
+	d.Step()
+	runUntilBreak(t, m)
+	d.StepOver()
+	runUntilBreak(t, m)
+	if m.PC() != 0x204 || m.Registers()[1] != 2 {
+		t.Errorf("Expected to step over the CALL to 0x204, got pc 0x%04X instead", m.PC())
+	}
+	if err := d.StepOut(); err != ErrNotInSubroutine {
+		t.Errorf("Expected ErrNotInSubroutine, got %v instead", err)
+	}
+
+	d.Add(AtPC(0x20A))
+	d.Continue()
+	runUntilBreak(t, m)
+	if m.PC() != 0x20A {
+		t.Errorf("Expected to stop at the breakpoint, got pc 0x%04X instead", m.PC())
+	}
+	if err := d.StepOut(); err != nil {
+		t.Fatal(err)
+	}
+	runUntilBreak(t, m)
+	if m.PC() != 0x204 || m.SP() != 0 {
+		t.Errorf("Expected to return to 0x204, got pc 0x%04X instead", m.PC())
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	m, d := newTestDebugger(t)
+	for _, spec := range []string{"pc 0x208", "op 00EE", "watch I", "watch V0 == 3"} {
+		bp, err := Parse(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Add(bp)
+	}
+
+	expected := []struct {
+		pc     uint16
+		reason string
+	}{
+		{0x208, "breakpoint 1: pc 208"},
+		{0x20C, "breakpoint 2: op 00EE"},
+		{0x206, "breakpoint 3: watch I"},
+		{0x208, "breakpoint 1: pc 208"},
+		{0x20C, "breakpoint 2: op 00EE"},
+		{0x202, "breakpoint 4: watch V0 == 3"},
+	}
+	for _, e := range expected {
+		d.Continue()
+		runUntilBreak(t, m)
+		if m.PC() != e.pc || d.Reason() != e.reason {
+			t.Errorf("Expected %q at 0x%04X, got %q at 0x%04X instead", e.reason, e.pc, d.Reason(), m.PC())
+		}
+	}
+
+	d.Remove(1)
+	d.Remove(2)
+	d.Remove(3)
+	if len(d.Breakpoints()) != 1 {
+		t.Errorf("Expected 1 breakpoint left, got %d instead", len(d.Breakpoints()))
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{"", "pc", "pc xyz", "op D1", "op G000", "watch VG", "watch [300] == zz", "watch Q"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	_, d := newTestDebugger(t)
+	lines := d.Disassemble(1, 2)
+	if len(lines) != 4 || lines[0].Addr != 0x1FE || lines[3].Text != "A300 MVI I 0x0300" {
+		t.Errorf("Expected 4 lines from 0x1FE, got %v instead", lines)
+	}
+}
+
+func TestNamedBreakpoint(t *testing.T) {
+	m, d := newTestDebugger(t)
+	bp := AtPC(0x208)
+	bp.Name = "add"
+	d.Add(bp)
+	d.Continue()
+	runUntilBreak(t, m)
+	if d.Reason() != "breakpoint 1: add" {
+		t.Errorf("Expected the breakpoint's name, got %q instead", d.Reason())
+	}
+}
+
+func TestMonitors(t *testing.T) {
+	_, d := newTestDebugger(t)
+	d.AddMonitor(Monitor{Name: "code", Addr: 0x200, Length: 4})
+	d.AddMonitor(Monitor{Name: "call", Addr: 0x202, Format: "%x to %2i, %b%"})
+	expected := []string{"code: 70 01 22 08", "call: 22 to 2211, 00000000%"}
+	for i, mon := range d.Monitors() {
+		if got := d.ReadMonitor(mon); got != expected[i] {
+			t.Errorf("Expected %q, got %q instead", expected[i], got)
+		}
+	}
+}
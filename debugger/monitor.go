@@ -0,0 +1,79 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Monitor is a range of memory shown next to the machine while it is
+// debugged, like the :monitor directive of Octo
+type Monitor struct {
+	Name string
+	Addr uint16
+	// Bytes shown in hex, when there is no Format
+	Length int
+	// Text with a value in place of each %i (decimal), %x (hex), %b
+	// (binary) and %c (character). A digit after the % reads that many
+	// bytes as one big endian number, as in %2i.
+	Format string
+}
+
+// Adds a monitor, shown by Monitors
+func (d *Debugger) AddMonitor(mon Monitor) {
+	d.monitors = append(d.monitors, mon)
+}
+
+// Returns the monitors in the order they were added
+func (d *Debugger) Monitors() []Monitor {
+	return append([]Monitor(nil), d.monitors...)
+}
+
+// Returns the memory of mon formatted as it asks, prefixed with its name
+func (d *Debugger) ReadMonitor(mon Monitor) string {
+	addr := mon.Addr
+	read := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<8 | int(d.m.ReadMemory(addr))
+			addr++
+		}
+		return v
+	}
+
+	if mon.Format == "" {
+		bytes := make([]string, mon.Length)
+		for i := range bytes {
+			bytes[i] = fmt.Sprintf("%02X", read(1))
+		}
+		return mon.Name + ": " + strings.Join(bytes, " ")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(mon.Name + ": ")
+	for i := 0; i < len(mon.Format); i++ {
+		c := mon.Format[i]
+		if c != '%' || i+1 == len(mon.Format) {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		n := 1
+		if d := mon.Format[i]; d >= '1' && d <= '4' && i+1 < len(mon.Format) {
+			n = int(d - '0')
+			i++
+		}
+		switch mon.Format[i] {
+		case 'i':
+			fmt.Fprintf(&sb, "%d", read(n))
+		case 'x':
+			fmt.Fprintf(&sb, "%0*X", 2*n, read(n))
+		case 'b':
+			fmt.Fprintf(&sb, "%0*b", 8*n, read(n))
+		case 'c':
+			sb.WriteByte(byte(read(1)))
+		default:
+			sb.WriteByte(mon.Format[i])
+		}
+	}
+	return sb.String()
+}
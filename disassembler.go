@@ -0,0 +1,347 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ByteKind tells what a byte of a program is used for
+type ByteKind uint8
+
+const (
+	// Not reached as code nor drawn as a sprite
+	KindData ByteKind = iota
+	// Part of an instruction that can be executed
+	KindCode
+	// Sprite data drawn by DXYN after I was loaded with ANNN
+	KindGraphics
+)
+
+// Disassembly of a program, telling code from data by following the
+// instructions that can be reached from the start of the program
+type Disassembly struct {
+	rom     []uint8
+	origin  uint16
+	variant Variant
+	kinds   []ByteKind
+	// Length of the instruction starting at each offset into rom
+	starts map[int]int
+	labels map[uint16]string
+}
+
+// A place the tracer has to carry on from, and the value of I there if it
+// is known from an ANNN on the way, -1 otherwise
+type tracePoint struct {
+	addr int
+	i    int
+}
+
+// Disassembles a program loaded at origin for the instruction set of
+// variant. Code is traced from origin following jumps, calls and skips.
+// The target of BNNN is traced from NNN only, since the offset is not
+// known.
+func Disassemble(rom []uint8, origin uint16, variant Variant) *Disassembly {
+	d := &Disassembly{
+		rom:     rom,
+		origin:  origin,
+		variant: variant,
+		kinds:   make([]ByteKind, len(rom)),
+		starts:  make(map[int]int),
+		labels:  make(map[uint16]string),
+	}
+	// Why each address is referenced, to name its label
+	calls := make(map[uint16]bool)
+	jumps := make(map[uint16]bool)
+	loads := make(map[uint16]bool)
+	graphics := make(map[int]bool)
+
+	work := []tracePoint{{addr: int(origin), i: -1}}
+	seen := make(map[tracePoint]bool)
+	for len(work) > 0 {
+		p := work[len(work)-1]
+		work = work[:len(work)-1]
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		op, size, ok := d.decode(p.addr)
+		if !ok {
+			continue
+		}
+		off := p.addr - int(origin)
+		d.starts[off] = size
+		for j := off; j < off+size; j++ {
+			d.kinds[j] = KindCode
+		}
+
+		nnn := op & 0x0FFF
+		next := p.addr + size
+		i := p.i
+		switch {
+		case op == 0x00EE || op == 0x00FD:
+			// Returns and exits end the path
+		case op&0xF000 == 0x1000:
+			jumps[nnn] = true
+			work = append(work, tracePoint{int(nnn), i})
+		case op&0xF000 == 0x2000:
+			calls[nnn] = true
+			// The subroutine may change I
+			work = append(work, tracePoint{int(nnn), -1}, tracePoint{next, -1})
+		case op&0xF000 == 0xB000:
+			jumps[nnn] = true
+			work = append(work, tracePoint{int(nnn), -1})
+		case isSkip(op):
+			work = append(work, tracePoint{next, i}, tracePoint{next + d.sizeAt(next), i})
+		default:
+			switch {
+			case op&0xF000 == 0xA000:
+				i = int(nnn)
+				loads[nnn] = true
+			case op == 0xF000:
+				long := uint16(d.rom[off+2])<<8 | uint16(d.rom[off+3])
+				i = int(long)
+				loads[long] = true
+			case op&0xF000 == 0xD000 && i >= 0:
+				rows := int(op & 0x000F)
+				if rows == 0 && d.variant >= VariantSCHIP {
+					rows = 32
+				}
+				for j := i - int(origin); j < i-int(origin)+rows; j++ {
+					graphics[j] = true
+				}
+			case op&0xF0FF == 0xF01E || op&0xF0FF == 0xF029 || op&0xF0FF == 0xF030 ||
+				op&0xF0FF == 0xF055 || op&0xF0FF == 0xF065:
+				i = -1
+			}
+			work = append(work, tracePoint{next, i})
+		}
+	}
+
+	for j := range graphics {
+		if j >= 0 && j < len(d.kinds) && d.kinds[j] == KindData {
+			d.kinds[j] = KindGraphics
+		}
+	}
+	sprites := make(map[uint16]bool)
+	for addr := range loads {
+		if d.Kind(addr) == KindGraphics {
+			sprites[addr] = true
+			delete(loads, addr)
+		}
+	}
+	d.nameLabels(calls, "sub")
+	d.nameLabels(jumps, "loc")
+	d.nameLabels(sprites, "sprite")
+	d.nameLabels(loads, "data")
+	d.dropHiddenLabels()
+	return d
+}
+
+// Returns the instruction at addr and its length in bytes, if addr holds
+// an instruction of the variant inside the program
+func (d *Disassembly) decode(addr int) (uint16, int, bool) {
+	off := addr - int(d.origin)
+	if off < 0 || off+1 >= len(d.rom) {
+		return 0, 0, false
+	}
+	op := uint16(d.rom[off])<<8 | uint16(d.rom[off+1])
+	if strings.HasPrefix(instruction(op).String(), "Instruction not") || opcodeVariant(op) > d.variant {
+		return 0, 0, false
+	}
+	if op == 0xF000 {
+		if off+3 >= len(d.rom) {
+			return 0, 0, false
+		}
+		return op, 4, true
+	}
+	return op, 2, true
+}
+
+// Returns the length of the instruction at addr as seen by a skip
+func (d *Disassembly) sizeAt(addr int) int {
+	if op, size, ok := d.decode(addr); ok && op == 0xF000 {
+		return size
+	}
+	return 2
+}
+
+// Gives the addresses in the program without a label one named after
+// prefix, preferring the labels given first
+func (d *Disassembly) nameLabels(addrs map[uint16]bool, prefix string) {
+	for addr := range addrs {
+		if _, ok := d.labels[addr]; ok {
+			continue
+		}
+		off := int(addr) - int(d.origin)
+		if off < 0 || off >= len(d.rom) {
+			continue
+		}
+		d.labels[addr] = fmt.Sprintf("%s_%04X", prefix, addr)
+	}
+}
+
+// Removes the labels that fall inside an instruction, which the listing
+// can't place. Instructions referring to them use the address instead.
+func (d *Disassembly) dropHiddenLabels() {
+	for off := 0; off < len(d.rom); {
+		size := d.starts[off]
+		if size == 0 {
+			off++
+			continue
+		}
+		for j := off + 1; j < off+size; j++ {
+			delete(d.labels, uint16(int(d.origin)+j))
+		}
+		off += size
+	}
+}
+
+// Returns what the byte at addr is used for
+func (d *Disassembly) Kind(addr uint16) ByteKind {
+	off := int(addr) - int(d.origin)
+	if off < 0 || off >= len(d.kinds) {
+		return KindData
+	}
+	return d.kinds[off]
+}
+
+// Returns the label of addr, if anything refers to it
+func (d *Disassembly) Label(addr uint16) (string, bool) {
+	l, ok := d.labels[addr]
+	return l, ok
+}
+
+// Returns the addresses with labels in increasing order
+func (d *Disassembly) LabelAddresses() []uint16 {
+	var addrs []uint16
+	for addr := range d.labels {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(a, b int) bool { return addrs[a] < addrs[b] })
+	return addrs
+}
+
+// Writes the listing of the program, which the assembler turns back into
+// the same bytes. Instructions are written with the mnemonics of
+// instruction.String and the address and opcode in a comment, sprites as
+// one DB per row in binary and other data as DB in hex.
+func (d *Disassembly) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	fmt.Fprintf(bw, "\tORG 0x%04X\n", d.origin)
+	for off := 0; off < len(d.rom); {
+		addr := uint16(int(d.origin) + off)
+		if l, ok := d.labels[addr]; ok {
+			fmt.Fprintf(bw, "\n%s:\n", l)
+		}
+		if size := d.starts[off]; size > 0 {
+			op := uint16(d.rom[off])<<8 | uint16(d.rom[off+1])
+			code := fmt.Sprintf("%04X", op)
+			var long uint16
+			if size == 4 {
+				long = uint16(d.rom[off+2])<<8 | uint16(d.rom[off+3])
+				code += fmt.Sprintf(" %04X", long)
+			}
+			fmt.Fprintf(bw, "\t%-24s; %04X  %s\n", d.format(op, long), addr, code)
+			off += size
+			continue
+		}
+		if d.kinds[off] == KindGraphics {
+			b := d.rom[off]
+			row := strings.NewReplacer("0", ".", "1", "#").Replace(fmt.Sprintf("%08b", b))
+			fmt.Fprintf(bw, "\t%-24s; %04X  %s\n", fmt.Sprintf("DB 0b%08b", b), addr, row)
+			off++
+			continue
+		}
+		// A line of up to 8 data bytes, ending before anything else
+		end := off + 1
+		for end < len(d.rom) && end-off < 8 && d.kinds[end] == KindData && d.starts[end] == 0 {
+			if _, ok := d.labels[uint16(int(d.origin)+end)]; ok {
+				break
+			}
+			end++
+		}
+		bytes := make([]string, 0, end-off)
+		for _, b := range d.rom[off:end] {
+			bytes = append(bytes, fmt.Sprintf("0x%02X", b))
+		}
+		fmt.Fprintf(bw, "\t%-24s; %04X\n", "DB "+strings.Join(bytes, ", "), addr)
+		off = end
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Returns the listing of the program, see WriteTo
+func (d *Disassembly) String() string {
+	var sb strings.Builder
+	d.WriteTo(&sb)
+	return sb.String()
+}
+
+// Formats an instruction for the listing, naming addresses by their labels.
+// long is the address following F000.
+func (d *Disassembly) format(op uint16, long uint16) string {
+	target := func(addr uint16) string {
+		if l, ok := d.labels[addr]; ok {
+			return l
+		}
+		return fmt.Sprintf("0x%04X", addr)
+	}
+	nnn := op & 0x0FFF
+	switch {
+	case op&0xF000 == 0x1000:
+		return "JUMP " + target(nnn)
+	case op&0xF000 == 0x2000:
+		return "CALL " + target(nnn)
+	case op&0xF000 == 0xA000:
+		return "MVI I " + target(nnn)
+	case op&0xF000 == 0xB000:
+		return "JUMP " + target(nnn) + "(V0)"
+	case op == 0xF000:
+		return "MVI.L I " + target(long)
+	}
+	// Drop the opcode in front of the mnemonic
+	return instruction(op).String()[5:]
+}
+
+// Reports whether op is a conditional skip
+func isSkip(op uint16) bool {
+	switch op & 0xF000 {
+	case 0x3000, 0x4000:
+		return true
+	case 0x5000, 0x9000:
+		return op&0x000F == 0
+	case 0xE000:
+		return op&0x00FF == 0x9E || op&0x00FF == 0xA1
+	}
+	return false
+}
+
+// Returns the first variant op is valid in
+func opcodeVariant(op uint16) Variant {
+	switch {
+	case op&0xFFF0 == 0x00C0, op >= 0x00FB && op <= 0x00FF,
+		op&0xF0FF == 0xF030, op&0xF0FF == 0xF075, op&0xF0FF == 0xF085:
+		return VariantSCHIP
+	case op&0xFFF0 == 0x00D0, op == 0xF000, op == 0xF002, op&0xF00F == 0x5002,
+		op&0xF00F == 0x5003, op&0xF0FF == 0xF001, op&0xF0FF == 0xF03A:
+		return VariantXOCHIP
+	}
+	return VariantCHIP8
+}
+
+// Counts the bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
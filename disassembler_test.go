@@ -0,0 +1,67 @@
+package chip8
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDisassembleFishie(t *testing.T) {
+	rom, err := ioutil.ReadFile("Fishie.ch8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Disassemble(rom, 0x200, VariantCHIP8)
+
+	kinds := map[uint16]ByteKind{0x200: KindCode, 0x21B: KindCode, 0x21C: KindData, 0x220: KindGraphics, 0x227: KindGraphics, 0x240: KindData}
+	for addr, kind := range kinds {
+		if d.Kind(addr) != kind {
+			t.Errorf("Expected kind %d at 0x%04X, got %d instead", kind, addr, d.Kind(addr))
+		}
+	}
+	if l, ok := d.Label(0x220); !ok || l != "sprite_0220" {
+		t.Errorf("Expected the label sprite_0220, got %q instead", l)
+	}
+
+	listing := d.String()
+	for _, line := range []string{
+		"\tMVI I sprite_0220       ; 0202  A220\n",
+		"\nloc_0210:\n\tSPRITE V1, V0, 8        ; 0210  D108\n",
+		"\tJUMP loc_0208           ; 0218  1208\n",
+		"\tDB 0b00111100           ; 0226  ..####..\n",
+	} {
+		if !strings.Contains(listing, line) {
+			t.Errorf("Expected the listing to contain %q", line)
+		}
+	}
+}
+
+func TestDisassembleControlFlow(t *testing.T) {
+	rom := []uint8{
+		0x30, 0x01, // 200: SKIP.EQ V0, 01
+		0xF0, 0x00, // 202: MVI.L I 0x0210
+		0x02, 0x10,
+		0x22, 0x0C, // 206: CALL 0x20C
+		0x00, 0xFD, // 208: EXIT
+		0xFF, 0xFF, // 20A: data
+		0x00, 0xEE, // 20C: RTS
+	}
+	d := Disassemble(rom, 0x200, VariantXOCHIP)
+	for addr := uint16(0x200); addr < 0x20A; addr++ {
+		if d.Kind(addr) != KindCode {
+			t.Errorf("Expected code at 0x%04X", addr)
+		}
+	}
+	if d.Kind(0x20A) != KindData || d.Kind(0x20C) != KindCode {
+		t.Errorf("Expected data at 0x020A and code at 0x020C")
+	}
+	if _, ok := d.Label(0x20C); !ok {
+		t.Errorf("Expected a label for the subroutine")
+	}
+
+	// The same program is mostly data to CHIP-8
+	d = Disassemble(rom, 0x200, VariantCHIP8)
+	if d.Kind(0x202) != KindData || d.Kind(0x206) != KindData {
+		t.Errorf("Expected F000 to end the trace on CHIP-8")
+	}
+}
@@ -0,0 +1,97 @@
+package chip8
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrStackOverflow is raised by a CALL with 16 return addresses on the stack
+	ErrStackOverflow = errors.New("chip8: stack overflow")
+	// ErrStackUnderflow is raised by a return with an empty stack
+	ErrStackUnderflow = errors.New("chip8: stack underflow")
+	// ErrInvalidOpcode is raised by an instruction that doesn't exist
+	ErrInvalidOpcode = errors.New("chip8: invalid opcode")
+	// ErrMemoryOutOfBounds is raised by an instruction fetch, load or store
+	// past the end of memory
+	ErrMemoryOutOfBounds = errors.New("chip8: memory access out of bounds")
+)
+
+// Fault describes an instruction the machine could not execute
+type Fault struct {
+	// Err is one of ErrStackOverflow, ErrStackUnderflow, ErrInvalidOpcode or
+	// ErrMemoryOutOfBounds
+	Err error
+	// Address and value of the instruction
+	PC     uint16
+	Opcode uint16
+	// State of the machine before the instruction
+	State *State
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("%v: %04X at 0x%04X", f.Err, f.Opcode, f.PC)
+}
+
+func (f *Fault) Unwrap() error {
+	return f.Err
+}
+
+// FaultPolicy decides what the machine does when an instruction faults
+type FaultPolicy int
+
+const (
+	// FaultHalt stops the machine. Step returns the fault until the
+	// machine is reset.
+	FaultHalt FaultPolicy = iota
+	// FaultTrap calls the trap handler, which decides whether to carry on
+	FaultTrap
+	// FaultIgnore skips the instruction as if it were a no-op
+	FaultIgnore
+)
+
+// TrapHandler is called when an instruction faults under FaultTrap. The
+// machine is left at the faulting instruction, so the handler may fix the
+// machine up and return nil to carry on, or return an error to halt.
+type TrapHandler func(m *Machine, f *Fault) error
+
+// Sets what the machine does when an instruction faults, FaultHalt if not set
+func WithFaultPolicy(policy FaultPolicy) Option {
+	return func(c8 *Machine) {
+		c8.faultPolicy = policy
+	}
+}
+
+// Calls h when an instruction faults, setting the policy to FaultTrap
+func WithTrapHandler(h TrapHandler) Option {
+	return func(c8 *Machine) {
+		c8.faultPolicy = FaultTrap
+		c8.trap = h
+	}
+}
+
+// Returns the fault that halted the machine, nil while it is running
+func (c8 *Machine) Fault() *Fault {
+	return c8.halted
+}
+
+// Applies the fault policy to err raised by the instruction inst at pc
+func (c8 *Machine) fault(err error, pc uint16, inst uint16) error {
+	// Leave the machine at the faulting instruction
+	c8.pc = pc
+	f := &Fault{Err: err, PC: pc, Opcode: inst, State: c8.Snapshot()}
+
+	switch c8.faultPolicy {
+	case FaultIgnore:
+		c8.pc = pc + 2
+		return nil
+	case FaultTrap:
+		if c8.trap != nil {
+			if err := c8.trap(c8, f); err == nil {
+				return nil
+			}
+		}
+	}
+	c8.halted = f
+	return f
+}
@@ -0,0 +1,118 @@
+package chip8
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		rom   []uint8
+		steps int
+		err   error
+		pc    uint16
+	}{
+		{"invalid opcode", []uint8{0x60, 0x01, 0x01, 0xEE}, 2, ErrInvalidOpcode, 0x202},
+		{"invalid 8XYN", []uint8{0x80, 0x0F}, 1, ErrInvalidOpcode, 0x200},
+		{"invalid FXNN", []uint8{0xF1, 0x99}, 1, ErrInvalidOpcode, 0x200},
+		{"stack underflow", []uint8{0x00, 0xEE}, 1, ErrStackUnderflow, 0x200},
+		{"stack overflow", []uint8{0x22, 0x00}, 17, ErrStackOverflow, 0x200},
+		{"sprite past memory", []uint8{0xAF, 0xFC, 0xD0, 0x05}, 2, ErrMemoryOutOfBounds, 0x202},
+		{"store past memory", []uint8{0xAF, 0xFE, 0xF3, 0x55}, 2, ErrMemoryOutOfBounds, 0x202},
+		{"load past memory", []uint8{0xAF, 0xFF, 0xF1, 0x65}, 2, ErrMemoryOutOfBounds, 0x202},
+		{"bcd past memory", []uint8{0xAF, 0xFE, 0xF0, 0x33}, 2, ErrMemoryOutOfBounds, 0x202},
+		{"fetch past memory", []uint8{0x1F, 0xFF}, 2, ErrMemoryOutOfBounds, 0xFFF},
+	}
+	for _, test := range tests {
+		c8 := New()
+		if _, err := c8.LoadROMBytes(test.rom); err != nil {
+			t.Fatal(err)
+		}
+		err := c8.RunFor(test.steps)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v instead", test.name, test.err, err)
+			continue
+		}
+		var f *Fault
+		if !errors.As(err, &f) {
+			t.Errorf("%s: expected a *Fault, got %T instead", test.name, err)
+			continue
+		}
+		if f.PC != test.pc || c8.PC() != test.pc {
+			t.Errorf("%s: expected fault at 0x%04X, got 0x%04X and pc 0x%04X instead", test.name, test.pc, f.PC, c8.PC())
+		}
+		if f.State == nil || f.State.PC != test.pc {
+			t.Errorf("%s: expected a snapshot at the faulting instruction", test.name)
+		}
+	}
+}
+
+func TestFaultHalts(t *testing.T) {
+	c8 := New()
+	c8.LoadROMBytes([]uint8{0x00, 0xEE})
+	err := c8.Step()
+	if err == nil {
+		t.Fatal("Expected a fault")
+	}
+	if c8.Step() != err || c8.Fault() != err {
+		t.Errorf("Expected the machine to stay halted")
+	}
+	if c8.Cycles() != 1 {
+		t.Errorf("Expected 1 cycle, got %d instead", c8.Cycles())
+	}
+	c8.Reset()
+	if c8.Fault() != nil {
+		t.Errorf("Expected Reset to clear the fault")
+	}
+}
+
+func TestFaultIgnore(t *testing.T) {
+	c8 := New(WithFaultPolicy(FaultIgnore))
+	// RTS, invalid, ADD V0 01
+	c8.LoadROMBytes([]uint8{0x00, 0xEE, 0x01, 0x23, 0x70, 0x01})
+	if err := c8.RunFor(3); err != nil {
+		t.Fatal(err)
+	}
+	if c8.reg[0] != 1 || c8.PC() != 0x206 {
+		t.Errorf("Expected faulting instructions to be skipped, got V0 %d and pc 0x%04X", c8.reg[0], c8.PC())
+	}
+}
+
+func TestFaultTrap(t *testing.T) {
+	var trapped []*Fault
+	c8 := New(WithTrapHandler(func(m *Machine, f *Fault) error {
+		trapped = append(trapped, f)
+		if len(trapped) == 1 {
+			// Skip the first fault
+			m.pc += 2
+			return nil
+		}
+		return f
+	}))
+	c8.LoadROMBytes([]uint8{0x00, 0xEE, 0x00, 0xEE})
+	if err := c8.Step(); err != nil {
+		t.Errorf("Expected the handler to recover, got %v", err)
+	}
+	if err := c8.Step(); !errors.Is(err, ErrStackUnderflow) {
+		t.Errorf("Expected the handler to halt, got %v", err)
+	}
+	if len(trapped) != 2 || trapped[1].PC != 0x202 {
+		t.Errorf("Expected 2 traps, got %d", len(trapped))
+	}
+}
+
+func TestCallReturn(t *testing.T) {
+	c8 := New()
+	// CALL 0x206, ADD V1 01, JUMP 0x204, ADD V0 01, RTS
+	c8.LoadROMBytes([]uint8{0x22, 0x06, 0x71, 0x01, 0x12, 0x04, 0x70, 0x01, 0x00, 0xEE})
+	if err := c8.RunFor(4); err != nil {
+		t.Fatal(err)
+	}
+	if c8.reg[0] != 1 || c8.reg[1] != 1 {
+		t.Errorf("Expected the subroutine and the code after the call to run once, got V0 %d V1 %d", c8.reg[0], c8.reg[1])
+	}
+	if c8.SP() != 0 {
+		t.Errorf("Expected an empty stack, got sp %d instead", c8.SP())
+	}
+}
@@ -0,0 +1,54 @@
+package chip8
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// FlagStore keeps the SUPER-CHIP RPL user flags written by FX75 so they
+// survive the machine, the way the HP-48 kept them between programs
+type FlagStore interface {
+	// LoadFlags returns the saved flags, or none if nothing was saved yet
+	LoadFlags() ([]uint8, error)
+	// SaveFlags replaces the saved flags
+	SaveFlags(flags []uint8) error
+}
+
+// FileFlagStore is a FlagStore keeping the flags in the named file
+type FileFlagStore string
+
+// Reads the flags from the file, a missing file has no flags
+func (name FileFlagStore) LoadFlags() ([]uint8, error) {
+	flags, err := ioutil.ReadFile(string(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return flags, err
+}
+
+// Writes the flags to the file
+func (name FileFlagStore) SaveFlags(flags []uint8) error {
+	return ioutil.WriteFile(string(name), flags, 0644)
+}
+
+// Persists the RPL user flags to store. Without a store the flags only
+// live as long as the machine.
+func WithFlagStore(store FlagStore) Option {
+	return func(c8 *Machine) {
+		c8.flagStore = store
+	}
+}
+
+// Reads the flags from the store the first time they are needed
+func (c8 *Machine) loadFlags() error {
+	if c8.flagsLoaded || c8.flagStore == nil {
+		return nil
+	}
+	flags, err := c8.flagStore.LoadFlags()
+	if err != nil {
+		return err
+	}
+	copy(c8.rpl[:], flags)
+	c8.flagsLoaded = true
+	return nil
+}
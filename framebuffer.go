@@ -0,0 +1,264 @@
+package chip8
+
+// Screen sizes in pixels. SUPER-CHIP programs can switch to the high
+// resolution mode.
+const (
+	loresWidth  = 64
+	loresHeight = 32
+	hiresWidth  = 128
+	hiresHeight = 64
+)
+
+// Framebuffer holds the screen of the machine, 64x32 pixels or 128x64 pixels
+// in high resolution mode. XO-CHIP has two bitplanes, so each pixel has one
+// of four colors: bit 0 is set when the pixel is lit in the first plane and
+// bit 1 when it is lit in the second.
+type Framebuffer struct {
+	buffer [hiresWidth * hiresHeight]uint8
+	// (0,0) - - - - (63, 0)
+	//  |              |
+	//  |              |
+	// (0, 31) - - - (63, 31)
+	hires bool
+	// Planes drawn to, cleared and scrolled, 1 unless changed by FN01
+	planes uint8
+}
+
+// Number of bitplanes of the screen
+const numPlanes = 2
+
+// How a sprite is drawn: whether the parts past the edges of the screen are
+// cut off instead of wrapping around, and whether the collision flag counts
+// rows, see Quirks
+type spriteMode struct {
+	clip      bool
+	countRows bool
+}
+
+// XORs a sprite onto the screen with its top left corner at (xStart, yStart).
+// Each byte of memory is one row of the sprite, most significant bit on the
+// left. When several planes are selected memory holds a sprite of height
+// rows for each of them in turn. The start position wraps around the
+// screen; the parts of the sprite past the edges are cut off when clipping
+// and wrap around otherwise. Returns 1 if any lit pixel was turned off, 0
+// otherwise, or when counting rows the number of rows that turned off a
+// lit pixel or were cut off at the bottom.
+func (disp *Framebuffer) drawSprite(xStart uint8, yStart uint8, height uint16, memory []uint8, mode spriteMode) uint8 {
+	rows := make([]uint16, height)
+	return disp.drawPlanes(func(n int, plane uint8) uint8 {
+		for i := range rows {
+			rows[i] = uint16(memory[n*int(height)+i]) << 8
+		}
+		return disp.draw(xStart, yStart, rows, plane, mode)
+	})
+}
+
+// Like drawSprite for the 16x16 sprites of SUPER-CHIP, where each row is two
+// bytes of memory
+func (disp *Framebuffer) drawWideSprite(xStart uint8, yStart uint8, memory []uint8, mode spriteMode) uint8 {
+	rows := make([]uint16, 16)
+	return disp.drawPlanes(func(n int, plane uint8) uint8 {
+		for i := range rows {
+			rows[i] = uint16(memory[n*32+2*i])<<8 | uint16(memory[n*32+2*i+1])
+		}
+		return disp.draw(xStart, yStart, rows, plane, mode)
+	})
+}
+
+// Calls draw with the bit of each selected plane and its position n among
+// the selected planes, which is where its sprite is stored in memory, and
+// returns the highest of the collision flags
+func (disp *Framebuffer) drawPlanes(draw func(n int, plane uint8) uint8) uint8 {
+	flipFlag := uint8(0)
+	n := 0
+	for plane := uint8(1); plane < 1<<numPlanes; plane <<= 1 {
+		if disp.planes&plane == 0 {
+			continue
+		}
+		if f := draw(n, plane); f > flipFlag {
+			flipFlag = f
+		}
+		n++
+	}
+	return flipFlag
+}
+
+// Returns the number of planes selected
+func (disp *Framebuffer) selectedPlanes() int {
+	n := 0
+	for plane := uint8(1); plane < 1<<numPlanes; plane <<= 1 {
+		if disp.planes&plane != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// XORs rows of up to 16 pixels onto plane of the screen, most significant
+// bit on the left, see drawSprite
+func (disp *Framebuffer) draw(xStart uint8, yStart uint8, rows []uint16, plane uint8, mode spriteMode) uint8 {
+	// Whether a lit pixel was turned off, and the rows that turned one off
+	// or were cut off
+	flipFlag := uint8(0)
+	hitRows := 0
+	x0 := int(xStart) % disp.Width()
+	y0 := int(yStart) % disp.Height()
+
+	for i, row := range rows {
+		// For each row of the sprite
+		y := y0 + i
+		if y >= disp.Height() {
+			if mode.clip {
+				hitRows += len(rows) - i
+				break
+			}
+			y %= disp.Height()
+		}
+		hit := false
+		for j := 0; j < 16; j++ {
+			// For each bit in the row of the sprite
+			if (row>>uint(15-j))&1 == 0 {
+				continue
+			}
+			x := x0 + j
+			if x >= disp.Width() {
+				if mode.clip {
+					break
+				}
+				x %= disp.Width()
+			}
+			graphicsCoord := y*disp.Width() + x
+			if disp.buffer[graphicsCoord]&plane != 0 {
+				flipFlag = 1
+				hit = true
+			}
+			disp.buffer[graphicsCoord] ^= plane
+		}
+		if hit {
+			hitRows++
+		}
+	}
+	if mode.countRows {
+		return uint8(hitRows)
+	}
+	return flipFlag
+}
+
+// Returns the width of the screen in pixels
+func (disp *Framebuffer) Width() int {
+	if disp.hires {
+		return hiresWidth
+	}
+	return loresWidth
+}
+
+// Returns the height of the screen in pixels
+func (disp *Framebuffer) Height() int {
+	if disp.hires {
+		return hiresHeight
+	}
+	return loresHeight
+}
+
+// Reports whether the screen is in the 128x64 high resolution mode
+func (disp *Framebuffer) HiRes() bool {
+	return disp.hires
+}
+
+// Reports whether the pixel at (x, y) is lit in any plane
+func (disp *Framebuffer) Pixel(x, y int) bool {
+	return disp.Color(x, y) != 0
+}
+
+// Returns the color of the pixel at (x, y), 0 when it is off. Bit 0 is set
+// when the pixel is lit in the first plane and bit 1 when it is lit in the
+// second, which only XO-CHIP programs draw to.
+func (disp *Framebuffer) Color(x, y int) uint8 {
+	return disp.pixelAt(x, y)
+}
+
+// Returns the mask of the planes drawn to, see Color
+func (disp *Framebuffer) Planes() uint8 {
+	return disp.planes
+}
+
+// Switches between the low and high resolution modes, clearing every plane
+func (disp *Framebuffer) setHiRes(hires bool) {
+	disp.hires = hires
+	disp.buffer = [hiresWidth * hiresHeight]uint8{}
+}
+
+// Selects the planes drawn to, cleared and scrolled
+func (disp *Framebuffer) selectPlanes(planes uint8) {
+	disp.planes = planes & (1<<numPlanes - 1)
+}
+
+// Moves the screen contents down by n rows, blanking the rows at the top
+func (disp *Framebuffer) scrollDown(n int) {
+	w, h := disp.Width(), disp.Height()
+	for y := h - 1; y >= 0; y-- {
+		for x := 0; x < w; x++ {
+			disp.scrollPixel(y*w+x, disp.pixelAt(x, y-n))
+		}
+	}
+}
+
+// Moves the screen contents up by n rows, blanking the rows at the bottom
+func (disp *Framebuffer) scrollUp(n int) {
+	w, h := disp.Width(), disp.Height()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			disp.scrollPixel(y*w+x, disp.pixelAt(x, y+n))
+		}
+	}
+}
+
+// Moves the screen contents right by n columns, blanking the columns on
+// the left
+func (disp *Framebuffer) scrollRight(n int) {
+	w, h := disp.Width(), disp.Height()
+	for y := 0; y < h; y++ {
+		for x := w - 1; x >= 0; x-- {
+			disp.scrollPixel(y*w+x, disp.pixelAt(x-n, y))
+		}
+	}
+}
+
+// Moves the screen contents left by n columns, blanking the columns on the
+// right
+func (disp *Framebuffer) scrollLeft(n int) {
+	w, h := disp.Width(), disp.Height()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			disp.scrollPixel(y*w+x, disp.pixelAt(x+n, y))
+		}
+	}
+}
+
+// Replaces the selected planes of the pixel at index i with those of color
+func (disp *Framebuffer) scrollPixel(i int, color uint8) {
+	disp.buffer[i] = disp.buffer[i]&^disp.planes | color&disp.planes
+}
+
+// Returns the value of the pixel at (x, y), 0 outside the screen
+func (disp *Framebuffer) pixelAt(x, y int) uint8 {
+	if x < 0 || x >= disp.Width() || y < 0 || y >= disp.Height() {
+		return 0
+	}
+	return disp.buffer[y*disp.Width()+x]
+}
+
+// Clear the selected planes of the internal buffer
+func (disp *Framebuffer) clear() {
+	for i := range disp.buffer {
+		disp.buffer[i] &^= disp.planes
+	}
+}
+
+// Blanks the screen and returns to the low resolution mode drawing to the
+// first plane, as when the machine starts
+func (disp *Framebuffer) reset() {
+	disp.buffer = [hiresWidth * hiresHeight]uint8{}
+	disp.hires = false
+	disp.planes = 1
+}
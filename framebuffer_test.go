@@ -0,0 +1,134 @@
+package chip8
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDrawSprite(t *testing.T) {
+	fb := new(Framebuffer)
+	fb.reset()
+	flag := fb.drawSprite(2, 1, 2, []uint8{0x80, 0x41}, spriteMode{})
+	if flag != 0 {
+		t.Errorf("Expected no collision, got %d instead", flag)
+	}
+	lit := [][2]int{{2, 1}, {3, 2}, {9, 2}}
+	for _, p := range lit {
+		if !fb.Pixel(p[0], p[1]) {
+			t.Errorf("Expected pixel (%d, %d) to be lit", p[0], p[1])
+		}
+	}
+	if fb.Pixel(2, 2) || fb.Pixel(9, 1) {
+		t.Errorf("Expected only the sprite bits to be lit")
+	}
+
+	// Drawing the same sprite again erases it and reports the collision
+	flag = fb.drawSprite(2, 1, 2, []uint8{0x80, 0x41}, spriteMode{})
+	if flag != 1 {
+		t.Errorf("Expected a collision, got %d instead", flag)
+	}
+	for _, p := range lit {
+		if fb.Pixel(p[0], p[1]) {
+			t.Errorf("Expected pixel (%d, %d) to be erased", p[0], p[1])
+		}
+	}
+}
+
+// Returns the lit pixels of the screen, row by row
+func litPixels(fb *Framebuffer) [][2]int {
+	var lit [][2]int
+	for y := 0; y < fb.Height(); y++ {
+		for x := 0; x < fb.Width(); x++ {
+			if fb.Pixel(x, y) {
+				lit = append(lit, [2]int{x, y})
+			}
+		}
+	}
+	return lit
+}
+
+// Returns the pixels of a width by height block at (x, y), row by row
+func block(x, y, width, height int) [][2]int {
+	var pixels [][2]int
+	for dy := 0; dy < height; dy++ {
+		for dx := 0; dx < width; dx++ {
+			pixels = append(pixels, [2]int{x + dx, y + dy})
+		}
+	}
+	return pixels
+}
+
+func TestDrawSpriteEdges(t *testing.T) {
+	// A 3x3 ring drawn at (127, 63), which wraps to the bottom right corner
+	// of the low resolution screen
+	ring := []uint8{0xE0, 0xA0, 0xE0}
+	tests := []struct {
+		clip bool
+		lit  [][2]int
+	}{
+		{false, [][2]int{{1, 0}, {63, 0}, {0, 1}, {1, 1}, {63, 1}, {0, 31}, {1, 31}, {63, 31}}},
+		{true, [][2]int{{63, 31}}},
+	}
+	for _, test := range tests {
+		fb := new(Framebuffer)
+		fb.reset()
+		if flag := fb.drawSprite(127, 63, 3, ring, spriteMode{clip: test.clip}); flag != 0 {
+			t.Errorf("Clip %v: expected no collision, got %d instead", test.clip, flag)
+		}
+		if lit := litPixels(fb); !reflect.DeepEqual(lit, test.lit) {
+			t.Errorf("Clip %v: expected pixels %v, got %v instead", test.clip, test.lit, lit)
+		}
+		// Only the pixels drawn count as collisions
+		if flag := fb.drawSprite(127, 63, 3, ring, spriteMode{clip: test.clip}); flag != 1 {
+			t.Errorf("Clip %v: expected a collision, got %d instead", test.clip, flag)
+		}
+		if lit := litPixels(fb); lit != nil {
+			t.Errorf("Clip %v: expected the sprite to be erased, got %v instead", test.clip, lit)
+		}
+	}
+}
+
+func TestDrawWideSpriteEdges(t *testing.T) {
+	square := make([]uint8, 32)
+	for i := range square {
+		square[i] = 0xFF
+	}
+
+	fb := new(Framebuffer)
+	fb.reset()
+	fb.setHiRes(true)
+	fb.drawWideSprite(120, 60, square, spriteMode{clip: true})
+	if lit := litPixels(fb); !reflect.DeepEqual(lit, block(120, 60, 8, 4)) {
+		t.Errorf("Expected the square to be cut off at 8x4 pixels, got %v instead", lit)
+	}
+
+	fb.clear()
+	fb.drawWideSprite(120, 60, square, spriteMode{})
+	var expected [][2]int
+	for _, p := range block(0, 0, 128, 64) {
+		if (p[0] >= 120 || p[0] < 8) && (p[1] >= 60 || p[1] < 12) {
+			expected = append(expected, p)
+		}
+	}
+	if lit := litPixels(fb); !reflect.DeepEqual(lit, expected) {
+		t.Errorf("Expected the square to wrap to the four corners, got %v instead", lit)
+	}
+}
+
+func TestDrawSpriteCollisionRows(t *testing.T) {
+	fb := new(Framebuffer)
+	fb.reset()
+	fb.setHiRes(true)
+	bar := []uint8{0x80, 0x80, 0x80, 0x80}
+	mode := spriteMode{clip: true, countRows: true}
+	if flag := fb.drawSprite(5, 60, 4, bar, mode); flag != 0 {
+		t.Errorf("Expected no rows to collide, got %d instead", flag)
+	}
+	// Rows 62 and 63 collide and the last two rows are cut off
+	if flag := fb.drawSprite(5, 62, 4, bar, mode); flag != 4 {
+		t.Errorf("Expected 4 rows, got %d instead", flag)
+	}
+	if lit := litPixels(fb); !reflect.DeepEqual(lit, [][2]int{{5, 60}, {5, 61}}) {
+		t.Errorf("Expected the overlap to be erased, got %v instead", lit)
+	}
+}
@@ -0,0 +1,38 @@
+package terminal
+
+import "io"
+
+// Sample rate of the bell, just enough to tell sound from silence
+const bellSampleRate = 600
+
+// Bell is a chip8.AudioSink ringing the terminal bell each time the
+// machine starts sounding, for terminals that can't play the tone itself
+type Bell struct {
+	w       io.Writer
+	ringing bool
+}
+
+// Returns a Bell ringing by writing to w, usually os.Stdout
+func NewBell(w io.Writer) *Bell {
+	return &Bell{w: w}
+}
+
+// Returns a low rate, since the samples are only checked for silence
+func (b *Bell) SampleRate() int {
+	return bellSampleRate
+}
+
+// Rings the bell if the samples sound after a silent frame
+func (b *Bell) Play(samples []int16) {
+	sounding := false
+	for _, v := range samples {
+		if v != 0 {
+			sounding = true
+			break
+		}
+	}
+	if sounding && !b.ringing {
+		b.w.Write([]byte{'\a'})
+	}
+	b.ringing = sounding
+}
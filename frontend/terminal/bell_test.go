@@ -0,0 +1,18 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBell(t *testing.T) {
+	var out bytes.Buffer
+	b := NewBell(&out)
+	silence, tone := make([]int16, 10), []int16{100, -100}
+	for _, frame := range [][]int16{silence, tone, tone, silence, tone} {
+		b.Play(frame)
+	}
+	if out.String() != "\a\a" {
+		t.Errorf("Expected the bell to ring each time the sound starts, got %q instead", out.String())
+	}
+}
@@ -0,0 +1,279 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/albertseo/chip8"
+	"github.com/albertseo/chip8/debugger"
+	"github.com/nsf/termbox-go"
+)
+
+// Width of the debugger panel left of the screen, in cells
+const panelWidth = 36
+
+// Instructions disassembled before and after the PC
+const (
+	linesBefore = 4
+	linesAfter  = 8
+)
+
+// Keys of the debugger while the machine is paused
+const debuggerHelp = "s step  n over  o out  c run  b break  : command  q quit"
+
+// Debugger runs a machine under a debugger.Debugger, showing the
+// disassembly, registers, stack and breakpoints next to the screen. Tab
+// pauses a running program. While paused, single keys step and continue,
+// and commands are typed after a colon:
+//
+//	break pc 2A0, break op DXYN, break watch V3 == 05
+//	watch [300]
+//	delete 2
+//	screenshot shot.png 4
+type Debugger struct {
+	disp *Display
+	kp   *Keypad
+	m    *chip8.Machine
+	dbg  *debugger.Debugger
+	// Command being typed, while typing
+	typing bool
+	input  []rune
+	// Result of the last command
+	message string
+}
+
+// Returns a Debugger drawing to disp and reading commands from kp, which
+// must be the renderer and keypad of m
+func NewDebugger(disp *Display, kp *Keypad, m *chip8.Machine, dbg *debugger.Debugger) *Debugger {
+	d := &Debugger{disp: disp, kp: kp, m: m, dbg: dbg}
+	disp.SetOverlay(panelWidth+1, d.drawPanel)
+	return d
+}
+
+// Runs the machine until quit is closed or the user quits from the
+// debugger. Faults and the program exiting pause the machine.
+func (d *Debugger) Run(quit <-chan struct{}) {
+	for {
+		if d.dbg.Paused() {
+			d.kp.SetCapture(true)
+			d.show()
+			select {
+			case <-quit:
+				return
+			case ev := <-d.kp.Commands():
+				if d.handle(ev) {
+					return
+				}
+			}
+			continue
+		}
+
+		d.kp.SetCapture(false)
+		// Stop at the end of a frame when Tab is pressed or the user quits
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case ev := <-d.kp.Commands():
+					if ev.Key != termbox.KeyTab {
+						continue
+					}
+				case <-quit:
+				case <-done:
+					return
+				}
+				close(stop)
+				return
+			}
+		}()
+		err := chip8.NewScheduler(d.m, chip8.NewWallClock()).Run(stop)
+		close(done)
+
+		switch {
+		case err == nil:
+			select {
+			case <-quit:
+				return
+			default:
+			}
+			d.dbg.Pause("break")
+		case errors.Is(err, chip8.ErrExit):
+			d.dbg.Pause("program exited")
+		case !errors.Is(err, chip8.ErrBreak):
+			d.dbg.Pause(err.Error())
+		}
+	}
+}
+
+// Redraws the screen and the panel
+func (d *Debugger) show() {
+	d.disp.Render(d.m.Framebuffer())
+}
+
+// Carries out a key pressed while paused, reporting whether the user quit
+func (d *Debugger) handle(ev termbox.Event) bool {
+	if d.typing {
+		switch {
+		case ev.Key == termbox.KeyEnter:
+			d.typing = false
+			d.message = d.command(string(d.input))
+		case ev.Key == termbox.KeyEsc:
+			d.typing = false
+		case ev.Key == termbox.KeyBackspace || ev.Key == termbox.KeyBackspace2:
+			if len(d.input) > 0 {
+				d.input = d.input[:len(d.input)-1]
+			}
+		case ev.Key == termbox.KeySpace:
+			d.input = append(d.input, ' ')
+		case ev.Ch != 0:
+			d.input = append(d.input, ev.Ch)
+		}
+		return false
+	}
+
+	d.message = ""
+	switch ev.Ch {
+	case 's':
+		d.dbg.Step()
+	case 'n':
+		d.dbg.StepOver()
+	case 'o':
+		if err := d.dbg.StepOut(); err != nil {
+			d.message = err.Error()
+		}
+	case 'c':
+		d.dbg.Continue()
+	case 'b':
+		d.dbg.Toggle(d.m.PC())
+	case ':':
+		d.typing = true
+		d.input = d.input[:0]
+	case 'q':
+		return true
+	}
+	return false
+}
+
+// Runs a typed command and returns the message to show
+func (d *Debugger) command(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	args := strings.Join(fields[1:], " ")
+	switch fields[0] {
+	case "break", "b":
+		return d.add(args)
+	case "watch", "w":
+		return d.add("watch " + args)
+	case "delete", "d":
+		id, err := strconv.Atoi(args)
+		if err != nil || !d.dbg.Remove(id) {
+			return fmt.Sprintf("no breakpoint %q", args)
+		}
+		return fmt.Sprintf("deleted breakpoint %d", id)
+	case "screenshot", "shot":
+		return d.screenshot(fields[1:])
+	}
+	return fmt.Sprintf("unknown command %q", fields[0])
+}
+
+// Adds the breakpoint spec and returns the message to show
+func (d *Debugger) add(spec string) string {
+	bp, err := debugger.Parse(spec)
+	if err != nil {
+		return err.Error()
+	}
+	id := d.dbg.Add(bp)
+	return fmt.Sprintf("breakpoint %d: %s", id, bp.Spec)
+}
+
+// Writes the screen to the image file named by args, optionally followed
+// by the scale, and returns the message to show
+func (d *Debugger) screenshot(args []string) string {
+	if len(args) == 0 || len(args) > 2 {
+		return "usage: screenshot file.png|.pbm|.pgm [scale]"
+	}
+	scale := 1
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Sprintf("bad scale %q", args[1])
+		}
+		scale = n
+	}
+	if err := chip8.WriteScreenshot(args[0], d.m.Framebuffer().Image(scale, nil)); err != nil {
+		return err.Error()
+	}
+	return "wrote " + args[0]
+}
+
+// Draws the debugger panel left of the screen
+func (d *Debugger) drawPanel() {
+	var lines []string
+	if d.dbg.Paused() {
+		lines = append(lines, "PAUSED: "+d.dbg.Reason())
+	} else {
+		lines = append(lines, "RUNNING, Tab to pause")
+	}
+	lines = append(lines, "")
+
+	for _, l := range d.dbg.Disassemble(linesBefore, linesAfter) {
+		marker := "  "
+		if d.dbg.HasBreakpoint(l.Addr) {
+			marker = "* "
+		}
+		if l.Addr == d.m.PC() {
+			marker = marker[:1] + ">"
+		}
+		lines = append(lines, fmt.Sprintf("%s%04X %s", marker, l.Addr, l.Text))
+	}
+	lines = append(lines, "")
+
+	v := d.m.Registers()
+	for r := 0; r < 16; r += 4 {
+		lines = append(lines, fmt.Sprintf("V%X %02X  V%X %02X  V%X %02X  V%X %02X", r, v[r], r+1, v[r+1], r+2, v[r+2], r+3, v[r+3]))
+	}
+	lines = append(lines, fmt.Sprintf("I %04X  PC %04X  SP %X", d.m.I(), d.m.PC(), d.m.SP()))
+	lines = append(lines, fmt.Sprintf("DT %02X  ST %02X  cycles %d", d.m.DelayTimer(), d.m.SoundTimer(), d.m.Cycles()))
+	stack := "stack"
+	for _, addr := range d.m.Stack() {
+		stack += fmt.Sprintf(" %03X", addr)
+	}
+	lines = append(lines, stack, "")
+
+	for _, bp := range d.dbg.Breakpoints() {
+		lines = append(lines, fmt.Sprintf("%d: %s", bp.ID, bp.Spec))
+	}
+	for _, mon := range d.dbg.Monitors() {
+		lines = append(lines, d.dbg.ReadMonitor(mon))
+	}
+
+	for y, line := range lines {
+		printCells(0, y, panelWidth, line)
+	}
+	// The bottom line spans the whole terminal
+	w, h := termbox.Size()
+	switch {
+	case d.typing:
+		printCells(0, h-1, w, ":"+string(d.input))
+	case d.message != "":
+		printCells(0, h-1, w, d.message)
+	case d.dbg.Paused():
+		printCells(0, h-1, w, debuggerHelp)
+	}
+}
+
+// Writes text starting at cell (x, y), cut off at column width
+func printCells(x, y, width int, text string) {
+	for _, c := range text {
+		if x >= width {
+			return
+		}
+		termbox.SetCell(x, y, c, termbox.ColorDefault, termbox.ColorDefault)
+		x++
+	}
+}
@@ -0,0 +1,52 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/albertseo/chip8"
+	"github.com/albertseo/chip8/debugger"
+	"github.com/nsf/termbox-go"
+)
+
+func TestDebuggerCommands(t *testing.T) {
+	m, err := chip8.NewFromROM(bytes.NewReader([]byte{0x12, 0x00}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Debugger{m: m, dbg: debugger.New(m)}
+
+	// Typing ":break 2A0" and Enter
+	keys := []termbox.Event{{Ch: ':'}, {Ch: 'b'}, {Ch: 'r'}, {Ch: 'e'}, {Ch: 'a'}, {Ch: 'k'}, {Key: termbox.KeySpace}, {Ch: '2'}, {Ch: 'A'}, {Ch: '0'}, {Key: termbox.KeyEnter}}
+	for _, ev := range keys {
+		d.handle(ev)
+	}
+	if d.message != "breakpoint 1: pc 2A0" || !d.dbg.HasBreakpoint(0x2A0) {
+		t.Errorf("Expected a breakpoint at 0x2A0, got %q instead", d.message)
+	}
+	if d.command("delete 1") != "deleted breakpoint 1" || d.dbg.HasBreakpoint(0x2A0) {
+		t.Errorf("Expected the breakpoint to be deleted")
+	}
+	if d.command("delete 1") != `no breakpoint "1"` {
+		t.Errorf("Expected deleting a missing breakpoint to fail")
+	}
+
+	if msg := d.command("screenshot shot.bmp"); msg != `chip8: unknown image format ".bmp", expected .png, .pbm or .pgm` {
+		t.Errorf("Expected an error for a screenshot in an unknown format, got %q instead", msg)
+	}
+	if msg := d.command("screenshot shot.png 0"); msg != `bad scale "0"` {
+		t.Errorf("Expected an error for a zero scale, got %q instead", msg)
+	}
+
+	// b toggles a breakpoint at the PC
+	d.handle(termbox.Event{Ch: 'b'})
+	if !d.dbg.HasBreakpoint(0x200) {
+		t.Errorf("Expected a breakpoint at the PC")
+	}
+	if d.handle(termbox.Event{Ch: 'c'}) || d.dbg.Paused() {
+		t.Errorf("Expected c to continue")
+	}
+	if !d.handle(termbox.Event{Ch: 'q'}) {
+		t.Errorf("Expected q to quit")
+	}
+}
@@ -0,0 +1,125 @@
+// Package terminal draws a Chip-8 machine in a terminal using termbox-go.
+package terminal
+
+import (
+	"github.com/albertseo/chip8"
+	"github.com/nsf/termbox-go"
+)
+
+// ColorScheme is the terminal colors pixels are drawn in. XO-CHIP pixels lit
+// only in the second plane are drawn in Plane2 and pixels lit in both planes
+// in Both, and both fall back to On when left as ColorDefault.
+type ColorScheme struct {
+	Off    termbox.Attribute
+	On     termbox.Attribute
+	Plane2 termbox.Attribute
+	Both   termbox.Attribute
+}
+
+// Named color schemes
+var ColorSchemes = map[string]ColorScheme{
+	"default": {Off: termbox.ColorDefault, On: termbox.ColorWhite, Plane2: termbox.ColorRed, Both: termbox.ColorYellow},
+	"green":   {Off: termbox.ColorBlack, On: termbox.ColorGreen, Plane2: termbox.ColorCyan, Both: termbox.ColorWhite},
+	"amber":   {Off: termbox.ColorBlack, On: termbox.ColorYellow, Plane2: termbox.ColorRed, Both: termbox.ColorWhite},
+	"inverse": {Off: termbox.ColorWhite, On: termbox.ColorBlack, Plane2: termbox.ColorBlue, Both: termbox.ColorRed},
+}
+
+// Display is a chip8.Renderer that draws the framebuffer as terminal cells
+type Display struct {
+	// Colors for Termbox Display
+	colors ColorScheme
+	// Terminal cells per low resolution pixel, horizontally and vertically.
+	// Cells are about twice as tall as they are wide so pixels are twice as
+	// wide by default. High resolution pixels take half as many cells.
+	scaleX int
+	scaleY int
+	// Column the screen starts at, and a function drawing more cells before
+	// they are flushed, see SetOverlay
+	originX int
+	overlay func()
+}
+
+// Initializes termbox and returns a Display drawing to the terminal. Each
+// pixel is drawn scale cells tall and twice as many cells wide. Close must
+// be called to restore the terminal.
+func Open(scale int, colors ColorScheme) (*Display, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	// Initialize termbox-go
+	if err := termbox.Init(); err != nil {
+		return nil, err
+	}
+	// Hide the termbox cursor
+	termbox.HideCursor()
+
+	disp := &Display{colors: colors, scaleX: 2 * scale, scaleY: scale}
+	return disp, nil
+}
+
+// Draws every lit pixel of the framebuffer and flushes it to the screen
+func (disp *Display) Render(fb *chip8.Framebuffer) {
+	termbox.Clear(disp.colors.On, disp.colors.Off)
+	w, h := disp.pixelSize(fb)
+	for y := 0; y < fb.Height(); y++ {
+		for x := 0; x < fb.Width(); x++ {
+			if color := fb.Color(x, y); color != 0 {
+				disp.fill(disp.originX+x*w, y*h, w, h, disp.colors.color(color))
+			}
+		}
+	}
+	if disp.overlay != nil {
+		disp.overlay()
+	}
+	termbox.Flush()
+}
+
+// Moves the screen to start at column x, and calls overlay each time the
+// screen is drawn to draw more cells next to it
+func (disp *Display) SetOverlay(x int, overlay func()) {
+	disp.originX = x
+	disp.overlay = overlay
+}
+
+// Returns the terminal color of a lit pixel of the given framebuffer color
+func (colors ColorScheme) color(color uint8) termbox.Attribute {
+	attr := colors.On
+	switch color {
+	case 2:
+		attr = colors.Plane2
+	case 3:
+		attr = colors.Both
+	}
+	if attr == termbox.ColorDefault {
+		return colors.On
+	}
+	return attr
+}
+
+// Returns the number of cells a pixel of fb covers horizontally and
+// vertically, at least one
+func (disp *Display) pixelSize(fb *chip8.Framebuffer) (int, int) {
+	w := disp.scaleX * 64 / fb.Width()
+	h := disp.scaleY * 32 / fb.Height()
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
+
+// Paints the w by h cells with their top left corner at (x, y)
+func (disp *Display) fill(x, y, w, h int, color termbox.Attribute) {
+	for cy := y; cy < y+h; cy++ {
+		for cx := x; cx < x+w; cx++ {
+			termbox.SetCell(cx, cy, ' ', color, color)
+		}
+	}
+}
+
+// Restores the terminal
+func (disp *Display) Close() {
+	termbox.Close()
+}
@@ -0,0 +1,137 @@
+package terminal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/albertseo/chip8"
+	"github.com/nsf/termbox-go"
+)
+
+// How long a key counts as held after the terminal last reported it, used
+// when no hold time is given to NewKeypad
+const DefaultHoldTime = 200 * time.Millisecond
+
+// Keypad is a chip8.Keypad reading the keyboard through termbox. Terminals
+// only report key presses, repeated while the key is held, so a key is
+// released once no press has been reported for the hold time.
+type Keypad struct {
+	keyMap chip8.KeyMap
+	hold   time.Duration
+
+	mu sync.Mutex
+	// Events not yet returned by Poll
+	events []chip8.KeyEvent
+	// When each key was last reported by the terminal
+	lastSeen [16]time.Time
+	held     [16]bool
+
+	quit     chan struct{}
+	quitOnce sync.Once
+
+	// Key events that are not keypad keys, and whether all key events are
+	// sent there instead of to the keypad
+	commands chan termbox.Event
+	capture  bool
+}
+
+// Most events Commands buffers, later ones are dropped until it is read
+const commandBuffer = 16
+
+// Starts reading key presses from the terminal, which must have been set
+// up with Open. Keys not in the key map are sent to Commands; Esc and
+// Ctrl-C close the channel returned by Quit.
+func NewKeypad(km chip8.KeyMap, hold time.Duration) *Keypad {
+	if hold <= 0 {
+		hold = DefaultHoldTime
+	}
+	kp := &Keypad{
+		keyMap:   km,
+		hold:     hold,
+		quit:     make(chan struct{}),
+		commands: make(chan termbox.Event, commandBuffer),
+	}
+	go kp.run()
+	return kp
+}
+
+func (kp *Keypad) run() {
+	for {
+		ev := termbox.PollEvent()
+		switch ev.Type {
+		case termbox.EventKey:
+			kp.handle(ev, time.Now())
+		case termbox.EventInterrupt, termbox.EventError:
+			return
+		}
+	}
+}
+
+// Records a key event from the terminal that happened at now
+func (kp *Keypad) handle(ev termbox.Event, now time.Time) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	if ev.Key == termbox.KeyCtrlC || ev.Key == termbox.KeyEsc && !kp.capture {
+		kp.quitOnce.Do(func() { close(kp.quit) })
+		return
+	}
+	key, ok := kp.keyMap[ev.Ch]
+	if !ok || kp.capture {
+		select {
+		case kp.commands <- ev:
+		default:
+		}
+		return
+	}
+
+	kp.lastSeen[key] = now
+	if !kp.held[key] {
+		kp.held[key] = true
+		kp.events = append(kp.events, chip8.KeyEvent{Key: key, Pressed: true})
+	}
+}
+
+// Returns the key events since the last call, releasing keys that have not
+// been reported for the hold time
+func (kp *Keypad) Poll() []chip8.KeyEvent {
+	return kp.poll(time.Now())
+}
+
+func (kp *Keypad) poll(now time.Time) []chip8.KeyEvent {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	for key := range kp.held {
+		if kp.held[key] && now.Sub(kp.lastSeen[key]) >= kp.hold {
+			kp.held[key] = false
+			kp.events = append(kp.events, chip8.KeyEvent{Key: uint8(key), Pressed: false})
+		}
+	}
+	events := kp.events
+	kp.events = nil
+	return events
+}
+
+// Returns the key events that are not keypad keys, and all key events but
+// Ctrl-C while capturing
+func (kp *Keypad) Commands() <-chan termbox.Event {
+	return kp.commands
+}
+
+// Sends all key events but Ctrl-C to Commands while capture is set, for
+// instance while a debugger prompt is shown. Esc only quits while not
+// capturing.
+func (kp *Keypad) SetCapture(capture bool) {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	kp.capture = capture
+}
+
+// Returns a channel that is closed when the user asks to quit
+func (kp *Keypad) Quit() <-chan struct{} {
+	return kp.quit
+}
+
+// Stops reading from the terminal. Call before closing the Display.
+func (kp *Keypad) Close() {
+	termbox.Interrupt()
+}
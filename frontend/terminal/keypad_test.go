@@ -0,0 +1,47 @@
+package terminal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/albertseo/chip8"
+	"github.com/nsf/termbox-go"
+)
+
+func TestKeypadHold(t *testing.T) {
+	kp := &Keypad{keyMap: chip8.DefaultKeyMap, hold: 100 * time.Millisecond, quit: make(chan struct{})}
+	start := time.Now()
+
+	kp.handle(termbox.Event{Type: termbox.EventKey, Ch: 'w'}, start)
+	kp.handle(termbox.Event{Type: termbox.EventKey, Ch: 'w'}, start.Add(50*time.Millisecond))
+	events := kp.poll(start.Add(60 * time.Millisecond))
+	if len(events) != 1 || events[0] != (chip8.KeyEvent{Key: 0x5, Pressed: true}) {
+		t.Errorf("Expected a single press of key 5, got %v instead", events)
+	}
+
+	events = kp.poll(start.Add(140 * time.Millisecond))
+	if len(events) != 0 {
+		t.Errorf("Expected no events while the key repeats, got %v instead", events)
+	}
+	events = kp.poll(start.Add(150 * time.Millisecond))
+	if len(events) != 1 || events[0] != (chip8.KeyEvent{Key: 0x5, Pressed: false}) {
+		t.Errorf("Expected a release of key 5, got %v instead", events)
+	}
+}
+
+func TestKeypadQuit(t *testing.T) {
+	kp := &Keypad{keyMap: chip8.DefaultKeyMap, hold: DefaultHoldTime, quit: make(chan struct{})}
+	kp.handle(termbox.Event{Type: termbox.EventKey, Ch: 'p'}, time.Now())
+	select {
+	case <-kp.Quit():
+		t.Errorf("Expected an unmapped key not to quit")
+	default:
+	}
+	kp.handle(termbox.Event{Type: termbox.EventKey, Key: termbox.KeyEsc}, time.Now())
+	kp.handle(termbox.Event{Type: termbox.EventKey, Key: termbox.KeyEsc}, time.Now())
+	select {
+	case <-kp.Quit():
+	default:
+		t.Errorf("Expected Esc to quit")
+	}
+}
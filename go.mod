@@ -0,0 +1,5 @@
+module github.com/albertseo/chip8
+
+go 1.16
+
+require github.com/nsf/termbox-go v1.1.1
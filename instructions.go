@@ -7,6 +7,12 @@ import (
 // New type to represent a chip8 instruction
 type instruction uint16
 
+// Returns a human readable description of the instruction op, the opcode
+// in hex followed by its mnemonic
+func Mnemonic(op uint16) string {
+	return instruction(op).String()
+}
+
 // Retruns a human readable description of a chip8 instruction
 func (inst instruction) String() string {
 	// Formats instruction as two words, separated by a space
@@ -21,7 +27,30 @@ func (inst instruction) String() string {
 		case 0x00EE:
 			// Return from subroutine
 			return preamble + "RTS"
+		case 0x00FB:
+			// SUPER-CHIP: Scroll the screen right by 4 pixels
+			return preamble + "SCROLL.RIGHT"
+		case 0x00FC:
+			// SUPER-CHIP: Scroll the screen left by 4 pixels
+			return preamble + "SCROLL.LEFT"
+		case 0x00FD:
+			// SUPER-CHIP: Exit the interpreter
+			return preamble + "EXIT"
+		case 0x00FE:
+			// SUPER-CHIP: Switch to low resolution
+			return preamble + "LORES"
+		case 0x00FF:
+			// SUPER-CHIP: Switch to high resolution
+			return preamble + "HIRES"
 		default:
+			if inst&0x0FF0 == 0x00C0 {
+				// SUPER-CHIP: Scroll the screen down by N pixels
+				return preamble + fmt.Sprintf("SCROLL.DOWN %X", uint16(inst&0x000F))
+			}
+			if inst&0x0FF0 == 0x00D0 {
+				// XO-CHIP: Scroll the screen up by N pixels
+				return preamble + fmt.Sprintf("SCROLL.UP %X", uint16(inst&0x000F))
+			}
 			return "Instruction not recognized"
 		}
 	case 0x1000:
@@ -43,14 +72,24 @@ func (inst instruction) String() string {
 		imm := inst & 0x00FF
 		return preamble + fmt.Sprintf("SKIP.NEQ V%X, %02X", uint16(reg), uint16(imm))
 	case 0x5000:
-    if inst & 0x000F == 0x0 {
-		// SKIP next instruction if VX == VY
-		regX := inst >> 8 & 0x0F
-		regY := inst >> 4 & 0x00F
-		return preamble + fmt.Sprintf("SKIP.EQ V%X, V%X", uint16(regX), uint16(regY))
-    } else {
-      return "Instruction not recognized"
-    }
+		if inst&0x000F == 0x0 {
+			// SKIP next instruction if VX == VY
+			regX := inst >> 8 & 0x0F
+			regY := inst >> 4 & 0x00F
+			return preamble + fmt.Sprintf("SKIP.EQ V%X, V%X", uint16(regX), uint16(regY))
+		} else if inst&0x000F == 0x2 {
+			// XO-CHIP: Store VX to VY in memory starting at I
+			regX := inst >> 8 & 0x0F
+			regY := inst >> 4 & 0x00F
+			return preamble + fmt.Sprintf("STORE.R (I), V%X-V%X", uint16(regX), uint16(regY))
+		} else if inst&0x000F == 0x3 {
+			// XO-CHIP: Load VX to VY from memory starting at I
+			regX := inst >> 8 & 0x0F
+			regY := inst >> 4 & 0x00F
+			return preamble + fmt.Sprintf("LOAD.R V%X-V%X, (I)", uint16(regX), uint16(regY))
+		} else {
+			return "Instruction not recognized"
+		}
 	case 0x6000:
 		// MOVE immediate into register VX
 		reg := inst >> 8 & 0x0F
@@ -67,44 +106,44 @@ func (inst instruction) String() string {
 		regY := inst >> 4 & 0x00F
 		switch inst & 0x000F {
 		case 0x0:
-      // Set VX to the value of VY
-		  return preamble + fmt.Sprintf("MOV V%X, V%X", uint16(regX), uint16(regY))
+			// Set VX to the value of VY
+			return preamble + fmt.Sprintf("MOV V%X, V%X", uint16(regX), uint16(regY))
 		case 0x1:
-      // Set VX to the value of VY | VX
-		  return preamble + fmt.Sprintf("OR V%X, V%X", uint16(regX), uint16(regY))
+			// Set VX to the value of VY | VX
+			return preamble + fmt.Sprintf("OR V%X, V%X", uint16(regX), uint16(regY))
 		case 0x2:
-      // Set VX to the value of VY & VX
-		  return preamble + fmt.Sprintf("AND V%X, V%X", uint16(regX), uint16(regY))
+			// Set VX to the value of VY & VX
+			return preamble + fmt.Sprintf("AND V%X, V%X", uint16(regX), uint16(regY))
 		case 0x3:
-      // Set VX to the value of VY ^ VX
-		  return preamble + fmt.Sprintf("XOR V%X, V%X", uint16(regX), uint16(regY))
+			// Set VX to the value of VY ^ VX
+			return preamble + fmt.Sprintf("XOR V%X, V%X", uint16(regX), uint16(regY))
 		case 0x4:
-      // Set VX to the value of VY + VX, VF set to 1 if there is a carry over
-		  return preamble + fmt.Sprintf("ADD. V%X, V%X", uint16(regX), uint16(regY))
+			// Set VX to the value of VY + VX, VF set to 1 if there is a carry over
+			return preamble + fmt.Sprintf("ADD. V%X, V%X", uint16(regX), uint16(regY))
 		case 0x5:
-      // Set VX to the value of VX - VY, VF set to 1 if need to borrow
-		  return preamble + fmt.Sprintf("SUB. V%X, V%X", uint16(regX), uint16(regY))
+			// Set VX to the value of VX - VY, VF set to 1 if need to borrow
+			return preamble + fmt.Sprintf("SUB. V%X, V%X", uint16(regX), uint16(regY))
 		case 0x6:
-      // Set VX to the value of VY >> 1, VF set to least significant digit of VY
-		  return preamble + fmt.Sprintf("SHR. V%X, V%X", uint16(regX), uint16(regY))
+			// Set VX to the value of VY >> 1, VF set to least significant digit of VY
+			return preamble + fmt.Sprintf("SHR. V%X, V%X", uint16(regX), uint16(regY))
 		case 0x7:
-      // Set VX to the value of VY - VX, VH set to 1 if need to borrow
-		  return preamble + fmt.Sprintf("SUBB. V%X, V%X", uint16(regX), uint16(regY))
+			// Set VX to the value of VY - VX, VH set to 1 if need to borrow
+			return preamble + fmt.Sprintf("SUBB. V%X, V%X", uint16(regX), uint16(regY))
 		case 0xE:
-      // Set VX and VY to VY << 1, VF set to most significant digit of VY before shift.
-		  return preamble + fmt.Sprintf("SHL. V%X, V%X", uint16(regX), uint16(regY))
+			// Set VX and VY to VY << 1, VF set to most significant digit of VY before shift.
+			return preamble + fmt.Sprintf("SHL. V%X, V%X", uint16(regX), uint16(regY))
 		}
 	case 0x9000:
-    if inst & 0x000F == 0x0 {
-		// SKIP next instruction if VX != VY
-		regX := inst >> 8 & 0x0F
-		regY := inst >> 4 & 0x00F
-		return preamble + fmt.Sprintf("SKIP.NEQ V%X, V%X", uint16(regX), uint16(regY))
-    } else {
-      return "Instruction not recognized"
-    }
+		if inst&0x000F == 0x0 {
+			// SKIP next instruction if VX != VY
+			regX := inst >> 8 & 0x0F
+			regY := inst >> 4 & 0x00F
+			return preamble + fmt.Sprintf("SKIP.NEQ V%X, V%X", uint16(regX), uint16(regY))
+		} else {
+			return "Instruction not recognized"
+		}
 	case 0xA000:
-    // Set register I to imm
+		// Set register I to imm
 		imm := inst & 0x0fff
 		return preamble + fmt.Sprintf("MVI I 0x%04X", uint16(imm))
 	case 0xB000:
@@ -112,63 +151,90 @@ func (inst instruction) String() string {
 		imm := inst & 0x0fff
 		return preamble + fmt.Sprintf("JUMP 0x%04X(V0)", uint16(imm))
 	case 0xC000:
-    // Set register VX to Imm & rand(0,255)
+		// Set register VX to Imm & rand(0,255)
 		reg := inst >> 8 & 0x0F
 		imm := inst & 0x00ff
 		return preamble + fmt.Sprintf("RAND V%X 0x%04X", uint16(reg), uint16(imm))
 	case 0xD000:
 		regX := inst >> 8 & 0x0F
 		regY := inst >> 4 & 0x00F
-    N := inst & 0x000F
+		N := inst & 0x000F
 		return preamble + fmt.Sprintf("SPRITE V%X, V%X, %X", uint16(regX), uint16(regY), uint16(N))
 	case 0xE000:
-    switch inst & 0x00FF {
-    case 0x9E:
-      // SKIP next instruction if key stored in VX is held
-		  reg := inst >> 8 & 0x0F
-      return preamble + fmt.Sprintf("SKIP.KEY V%X", uint16(reg))
-    case 0xA1:
-      // SKIP next instruction if key stored in VX isn't held
-		  reg := inst >> 8 & 0x0F
-      return preamble + fmt.Sprintf("SKIP.NKEY V%X", uint16(reg))
-    default:
-      return "Instruction not recognized"
-    }
+		switch inst & 0x00FF {
+		case 0x9E:
+			// SKIP next instruction if key stored in VX is held
+			reg := inst >> 8 & 0x0F
+			return preamble + fmt.Sprintf("SKIP.KEY V%X", uint16(reg))
+		case 0xA1:
+			// SKIP next instruction if key stored in VX isn't held
+			reg := inst >> 8 & 0x0F
+			return preamble + fmt.Sprintf("SKIP.NKEY V%X", uint16(reg))
+		default:
+			return "Instruction not recognized"
+		}
 	case 0xF000:
 		reg := inst >> 8 & 0x0F
-    switch inst & 0x00FF {
-    case 0x07:
-      // Set VX to value of delay timer
-      return preamble + fmt.Sprintf("MOV V%X DELAY", uint16(reg))
-    case 0x0A:
-      // Wait for keypress, then store in VX
-      return preamble + fmt.Sprintf("WAITKEY V%X", uint16(reg))
-    case 0x15:
-      // Set delay timer to value in VX
-      return preamble + fmt.Sprintf("MOV DELAY V%X", uint16(reg))
-    case 0x18:
-      // Set sound timer to value in VX
-      return preamble + fmt.Sprintf("MOV SOUND V%X", uint16(reg))
-    case 0x1E:
-      // ADDS VX to I
-      return preamble + fmt.Sprintf("ADD I V%X", uint16(reg))
-    case 0x29:
-      // Sets I to the location of sprite of character in VX
-      return preamble + fmt.Sprintf("SPRITECHAR I V%X", uint16(reg))
-    case 0x33:
-      // Stores BCD of VX at I, I+1, I+2
-      return preamble + fmt.Sprintf("MOVBCD V%X", uint16(reg))
-    case 0x55:
-      // Stores V0 to VX in memory starting at I
-      return preamble + fmt.Sprintf("STORE (I), V0-V%X", uint16(reg))
-    case 0x65:
-      // Load values at V0 to VX starting at memory address I
-      return preamble + fmt.Sprintf("LOAD V0-V%X, (I)", uint16(reg))
-    default:
-      return "Instruction not recognized"
-    }
-  default:
-    return "Instruction not valid"
+		switch inst & 0x00FF {
+		case 0x00:
+			// XO-CHIP: Set I to the 16-bit address in the next word
+			if reg != 0 {
+				return "Instruction not recognized"
+			}
+			return preamble + "MVI.L I"
+		case 0x01:
+			// XO-CHIP: Select the planes to draw to
+			return preamble + fmt.Sprintf("PLANE %X", uint16(reg))
+		case 0x02:
+			// XO-CHIP: Load the audio pattern from memory at I
+			if reg != 0 {
+				return "Instruction not recognized"
+			}
+			return preamble + "AUDIO (I)"
+		case 0x07:
+			// Set VX to value of delay timer
+			return preamble + fmt.Sprintf("MOV V%X DELAY", uint16(reg))
+		case 0x0A:
+			// Wait for keypress, then store in VX
+			return preamble + fmt.Sprintf("WAITKEY V%X", uint16(reg))
+		case 0x15:
+			// Set delay timer to value in VX
+			return preamble + fmt.Sprintf("MOV DELAY V%X", uint16(reg))
+		case 0x18:
+			// Set sound timer to value in VX
+			return preamble + fmt.Sprintf("MOV SOUND V%X", uint16(reg))
+		case 0x1E:
+			// ADDS VX to I
+			return preamble + fmt.Sprintf("ADD I V%X", uint16(reg))
+		case 0x29:
+			// Sets I to the location of sprite of character in VX
+			return preamble + fmt.Sprintf("SPRITECHAR I V%X", uint16(reg))
+		case 0x30:
+			// SUPER-CHIP: Sets I to the location of big sprite of character in VX
+			return preamble + fmt.Sprintf("BIGSPRITECHAR I V%X", uint16(reg))
+		case 0x3A:
+			// XO-CHIP: Set the audio pitch to VX
+			return preamble + fmt.Sprintf("PITCH V%X", uint16(reg))
+		case 0x33:
+			// Stores BCD of VX at I, I+1, I+2
+			return preamble + fmt.Sprintf("MOVBCD V%X", uint16(reg))
+		case 0x55:
+			// Stores V0 to VX in memory starting at I
+			return preamble + fmt.Sprintf("STORE (I), V0-V%X", uint16(reg))
+		case 0x65:
+			// Load values at V0 to VX starting at memory address I
+			return preamble + fmt.Sprintf("LOAD V0-V%X, (I)", uint16(reg))
+		case 0x75:
+			// SUPER-CHIP: Stores V0 to VX in the user flags
+			return preamble + fmt.Sprintf("STORE FLAGS, V0-V%X", uint16(reg))
+		case 0x85:
+			// SUPER-CHIP: Loads V0 to VX from the user flags
+			return preamble + fmt.Sprintf("LOAD V0-V%X, FLAGS", uint16(reg))
+		default:
+			return "Instruction not recognized"
+		}
+	default:
+		return "Instruction not valid"
 	}
-  return "Instruction not valid"
+	return "Instruction not valid"
 }
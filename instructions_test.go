@@ -1,8 +1,8 @@
 package chip8
 
 import (
-	"testing"
 	"fmt"
+	"testing"
 )
 
 func TestString(t *testing.T) {
@@ -19,6 +19,42 @@ func TestString(t *testing.T) {
 	if result != expected {
 		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
 	}
+	inst = instruction(0x00C5)
+	result = fmt.Sprintf("%v", inst)
+	expected = "00C5 SCROLL.DOWN 5"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0x00FB)
+	result = fmt.Sprintf("%v", inst)
+	expected = "00FB SCROLL.RIGHT"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0x00FC)
+	result = fmt.Sprintf("%v", inst)
+	expected = "00FC SCROLL.LEFT"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0x00FD)
+	result = fmt.Sprintf("%v", inst)
+	expected = "00FD EXIT"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0x00FE)
+	result = fmt.Sprintf("%v", inst)
+	expected = "00FE LORES"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0x00FF)
+	result = fmt.Sprintf("%v", inst)
+	expected = "00FF HIRES"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
 	inst = instruction(0x01EE)
 	result = fmt.Sprintf("%v", inst)
 	expected = "Instruction not recognized"
@@ -265,6 +301,60 @@ func TestString(t *testing.T) {
 	if result != expected {
 		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
 	}
+	inst = instruction(0xFA30)
+	result = fmt.Sprintf("%v", inst)
+	expected = "FA30 BIGSPRITECHAR I VA"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0xF775)
+	result = fmt.Sprintf("%v", inst)
+	expected = "F775 STORE FLAGS, V0-V7"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0xF385)
+	result = fmt.Sprintf("%v", inst)
+	expected = "F385 LOAD V0-V3, FLAGS"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0xF000)
+	result = fmt.Sprintf("%v", inst)
+	expected = "F000 MVI.L I"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0x5AB2)
+	result = fmt.Sprintf("%v", inst)
+	expected = "5AB2 STORE.R (I), VA-VB"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0x5313)
+	result = fmt.Sprintf("%v", inst)
+	expected = "5313 LOAD.R V3-V1, (I)"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0xF201)
+	result = fmt.Sprintf("%v", inst)
+	expected = "F201 PLANE 2"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0xF002)
+	result = fmt.Sprintf("%v", inst)
+	expected = "F002 AUDIO (I)"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
+	inst = instruction(0xF53A)
+	result = fmt.Sprintf("%v", inst)
+	expected = "F53A PITCH V5"
+	if result != expected {
+		t.Errorf("toString was incorrect, got %v, expected %v", result, expected)
+	}
 	inst = instruction(0xF199)
 	result = fmt.Sprintf("%v", inst)
 	expected = "Instruction not recognized"
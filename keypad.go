@@ -0,0 +1,158 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeyEvent is a key of the hexadecimal keypad being pressed or released
+type KeyEvent struct {
+	// Key is the keypad value, 0x0 to 0xF
+	Key uint8
+	// Pressed is true when the key went down and false when it came up
+	Pressed bool
+}
+
+// Keypad is a source of key events for the machine. Poll is called before
+// every instruction and must not block; it returns the events that happened
+// since the previous call, oldest first.
+type Keypad interface {
+	Poll() []KeyEvent
+}
+
+// KeyMap translates characters typed on a keyboard to keypad values
+type KeyMap map[rune]uint8
+
+// Order of the keypad values as they are laid out on the COSMAC VIP
+//
+//	1 2 3 C
+//	4 5 6 D
+//	7 8 9 E
+//	A 0 B F
+var keypadLayout = [16]uint8{
+	0x1, 0x2, 0x3, 0xC,
+	0x4, 0x5, 0x6, 0xD,
+	0x7, 0x8, 0x9, 0xE,
+	0xA, 0x0, 0xB, 0xF,
+}
+
+// Named key maps placing the keypad on the left hand side of the keyboard
+var KeyMaps = map[string]KeyMap{
+	"qwerty":  mustParseKeyMap("1234qwerasdfzxcv"),
+	"azerty":  mustParseKeyMap("1234azerqsdfwxcv"),
+	"dvorak":  mustParseKeyMap("1234',.paoeu;qjk"),
+	"colemak": mustParseKeyMap("1234qwfparstzxcd"),
+}
+
+// Key map used when none is configured
+var DefaultKeyMap = KeyMaps["qwerty"]
+
+// Builds a KeyMap from the 16 characters covering the keypad row by row,
+// so "1234qwerasdfzxcv" maps '1' to 0x1, '4' to 0xC and 'v' to 0xF.
+// A name from KeyMaps is also accepted.
+func ParseKeyMap(layout string) (KeyMap, error) {
+	if km, ok := KeyMaps[strings.ToLower(layout)]; ok {
+		return km, nil
+	}
+	return parseLayout(layout)
+}
+
+func parseLayout(layout string) (KeyMap, error) {
+	runes := []rune(layout)
+	if len(runes) != len(keypadLayout) {
+		return nil, fmt.Errorf("chip8: key map %q must have 16 characters, got %d", layout, len(runes))
+	}
+	km := make(KeyMap)
+	for i, r := range runes {
+		if _, ok := km[r]; ok {
+			return nil, fmt.Errorf("chip8: key map %q uses %q twice", layout, r)
+		}
+		km[r] = keypadLayout[i]
+	}
+	return km, nil
+}
+
+func mustParseKeyMap(layout string) KeyMap {
+	km, err := parseLayout(layout)
+	if err != nil {
+		panic(err)
+	}
+	return km
+}
+
+// Marks a key as held down
+func (c8 *Machine) PressKey(key uint8) {
+	c8.setKey(key&0x0F, true)
+}
+
+// Marks a key as released
+func (c8 *Machine) ReleaseKey(key uint8) {
+	c8.setKey(key&0x0F, false)
+}
+
+// Reports whether a key is held down
+func (c8 *Machine) KeyPressed(key uint8) bool {
+	return c8.key[key&0x0F]
+}
+
+// Reports whether the machine is suspended in FX0A waiting for a key
+func (c8 *Machine) WaitingForKey() bool {
+	return c8.waitingKey
+}
+
+// Changes the state of a key, recording the change for rewinding
+func (c8 *Machine) setKey(key uint8, pressed bool) {
+	if c8.key[key] == pressed {
+		return
+	}
+	c8.key[key] = pressed
+	if c8.rewind != nil && !c8.rewind.replaying {
+		c8.rewind.recordKey(c8.cycles, key, pressed)
+	}
+	if c8.movie != nil && !c8.moviePlaying && !c8.replaying() {
+		c8.recordMovieKey(key, pressed)
+	}
+}
+
+// Applies the pending events of the keypad to the key state. While
+// rewinding the recorded key changes are applied instead, and while playing
+// a movie its key changes.
+func (c8 *Machine) pollKeypad() {
+	if c8.replaying() {
+		c8.rewind.replayKeys(c8)
+		return
+	}
+	if c8.movie != nil && c8.moviePlaying {
+		c8.playMovieKeys()
+		return
+	}
+	if c8.keypad == nil {
+		return
+	}
+	for _, ev := range c8.keypad.Poll() {
+		if ev.Pressed {
+			c8.PressKey(ev.Key)
+		} else {
+			c8.ReleaseKey(ev.Key)
+		}
+	}
+}
+
+// Advances the FX0A wait. Like the COSMAC VIP the wait is over once a key
+// has been pressed and released again, and that key is stored in VX.
+func (c8 *Machine) waitForKey() {
+	if c8.waitKey < 0 {
+		for k := 0; k < len(c8.key); k++ {
+			if c8.key[k] {
+				c8.waitKey = k
+				break
+			}
+		}
+		return
+	}
+	if !c8.key[c8.waitKey] {
+		c8.reg[c8.waitReg] = uint8(c8.waitKey)
+		c8.waitingKey = false
+		c8.waitKey = -1
+	}
+}
@@ -0,0 +1,107 @@
+package chip8
+
+import (
+	"testing"
+)
+
+// Keypad returning a fixed list of events, one slice per call to Poll
+type scriptedKeypad struct {
+	polls [][]KeyEvent
+}
+
+func (kp *scriptedKeypad) Poll() []KeyEvent {
+	if len(kp.polls) == 0 {
+		return nil
+	}
+	events := kp.polls[0]
+	kp.polls = kp.polls[1:]
+	return events
+}
+
+func TestParseKeyMap(t *testing.T) {
+	km, err := ParseKeyMap("1234qwerasdfzxcv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if km['1'] != 0x1 || km['4'] != 0xC || km['x'] != 0x0 || km['v'] != 0xF {
+		t.Errorf("Expected the COSMAC VIP layout, got %v instead", km)
+	}
+	if _, err := ParseKeyMap("azerty"); err != nil {
+		t.Errorf("Expected a named key map to parse, got %v", err)
+	}
+	if _, err := ParseKeyMap("1234"); err == nil {
+		t.Errorf("Expected an error for a short key map")
+	}
+	if _, err := ParseKeyMap("1134qwerasdfzxcv"); err == nil {
+		t.Errorf("Expected an error for a repeated key")
+	}
+}
+
+func TestWaitForKey(t *testing.T) {
+	kp := &scriptedKeypad{polls: [][]KeyEvent{
+		nil,
+		nil,
+		{{Key: 0xB, Pressed: true}},
+		nil,
+		{{Key: 0xB, Pressed: false}},
+	}}
+	c8 := New(WithKeypad(kp))
+	// WAITKEY V3, ADD V4 01
+	c8.memory[0x200] = 0xF3
+	c8.memory[0x201] = 0x0A
+	c8.memory[0x202] = 0x74
+	c8.memory[0x203] = 0x01
+
+	c8.Step()
+	if !c8.WaitingForKey() {
+		t.Errorf("Expected the machine to wait for a key")
+	}
+	// Polling, pressed, still held
+	c8.RunFor(3)
+	if !c8.WaitingForKey() {
+		t.Errorf("Expected the machine to wait until the key is released")
+	}
+	if c8.PC() != 0x202 {
+		t.Errorf("Expected pc of 0x202, got 0x%04X instead", c8.PC())
+	}
+	// Released
+	c8.Step()
+	if c8.WaitingForKey() {
+		t.Errorf("Expected the wait to be over")
+	}
+	if c8.reg[3] != 0xB {
+		t.Errorf("Expected value of 0x0B, got 0x%02X instead", c8.reg[3])
+	}
+	c8.Step()
+	if c8.reg[4] != 0x01 {
+		t.Errorf("Expected execution to resume, got V4 0x%02X instead", c8.reg[4])
+	}
+	if c8.Cycles() != 6 {
+		t.Errorf("Expected 6 cycles, got %d instead", c8.Cycles())
+	}
+}
+
+func TestSkipKey(t *testing.T) {
+	// EX9E
+	c8 := New()
+	c8.reg[0xD] = 0x7
+	c8.PressKey(0x7)
+	c8.executeInstruction(0xED9E)
+	if c8.pc != 0x0204 {
+		t.Errorf("Expected pc of 0x204 , got 0x%04X instead", c8.pc)
+	}
+
+	// EXA1
+	c8 = New()
+	c8.reg[0xD] = 0x7
+	c8.PressKey(0x7)
+	c8.executeInstruction(0xEDA1)
+	if c8.pc != 0x0202 {
+		t.Errorf("Expected pc of 0x202 , got 0x%04X instead", c8.pc)
+	}
+	c8.ReleaseKey(0x7)
+	c8.executeInstruction(0xEDA1)
+	if c8.pc != 0x0206 {
+		t.Errorf("Expected pc of 0x206 , got 0x%04X instead", c8.pc)
+	}
+}
@@ -0,0 +1,403 @@
+package chip8
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrExit is returned by Step once the program has exited with the
+// SUPER-CHIP instruction 00FD
+var ErrExit = errors.New("chip8: program exited")
+
+// ErrBreak is returned by RunFrame when the break hook stops the machine
+// before an instruction
+var ErrBreak = errors.New("chip8: break")
+
+// BreakHook is called by RunFrame before each instruction. Returning true
+// stops the machine before the instruction, and the next RunFrame carries
+// on with the rest of the frame.
+type BreakHook func(m *Machine) bool
+
+// Machine is a complete Chip-8 system: CPU, memory, timers, keypad and
+// framebuffer. The zero value is not ready for use, create one with New.
+type Machine struct {
+	// Memory is 4096 bytes, 65536 bytes for XO-CHIP
+	memory []uint8
+	// Graphics is 2048 bits
+	graphics Framebuffer
+	// There are 16 registers, each with 8 bits of memory
+	reg [16]uint8
+	// Memory address register I of 16 bits
+	i uint16
+	// Program Counter register PC of 16 bits
+	pc uint16
+	// Timer delay and sound delay
+	timerDelay uint8
+	soundDelay uint8
+	// Stack limited to 16 subcalls
+	stack [16]uint16
+	// Stack pointer
+	sp uint16
+	// Array to record which key is held
+	key [16]bool
+	// Source of key events, nil when keys are only set through PressKey
+	keypad Keypad
+	// State of FX0A: the register to store the key in and the key that has
+	// been pressed so far, -1 while no key has been pressed
+	waitingKey bool
+	waitReg    uint8
+	waitKey    int
+	// Flag to see if need to flush graphics to screen
+	drawFlag bool
+	// Frontend the screen is presented on
+	renderer Renderer
+	// Copy of the loaded program, used to restore memory on Reset
+	rom []uint8
+	// Address the program is loaded to and starts executing from
+	loadAddr uint16
+	// Number of instructions executed since the last reset
+	cycles uint64
+	// Number of 60 Hz frames run since the last reset
+	frames uint64
+	// Instructions executed per second of emulated time
+	ips int
+	// What to do when an instruction faults, and the fault that halted the
+	// machine
+	faultPolicy FaultPolicy
+	trap        TrapHandler
+	halted      *Fault
+	// Interpretation of ambiguous instructions
+	quirks Quirks
+	// Set by DXYN under the DisplayWait quirk to end the frame early
+	vblankWait bool
+	// Instruction set the machine runs
+	variant Variant
+	// Set once the program has exited with 00FD
+	exited bool
+	// SUPER-CHIP RPL user flags, and where they are persisted
+	rpl         [16]uint8
+	flagStore   FlagStore
+	flagsLoaded bool
+	// XO-CHIP audio pattern buffer and its playback pitch
+	pattern [16]uint8
+	pitch   uint8
+	// Snapshots and input to rewind with, nil unless enabled by WithRewind
+	rewind *rewindBuffer
+	// Called before each instruction of a frame, and the instructions of the
+	// current frame run so far
+	breakHook BreakHook
+	frameStep uint64
+	// Given each instruction executed, nil unless tracing
+	tracer Tracer
+	// Set until the seed has been traced, see TraceEntry.Seed
+	traceSeed bool
+	// Given the screen at the end of each frame, nil unless recording
+	recorder *Recorder
+	// Plays the sound, nil when silent, with the frequency of the square
+	// wave, the position in the wave or pattern and the samples of a frame
+	audio      AudioSink
+	tone       float64
+	audioPhase float64
+	audioBuf   []int16
+	// Random numbers for CXNN, seeded with seed on every reset, and the
+	// number of bytes drawn since
+	random      RandomSource
+	seed        int64
+	randomDraws uint64
+	// Movie being recorded or played, see RecordMovie
+	movie        *Movie
+	moviePlaying bool
+	moviePos     int
+}
+
+// Rate the delay and sound timers count down at, and the machine runs frames
+const FrameRate = 60
+
+// Instruction rate used when none is configured
+const DefaultInstructionsPerSecond = 600
+
+// Pitch of the XO-CHIP audio pattern after a reset, playing it at 4000 bits
+// per second
+const defaultPitch = 64
+
+// Option configures a Machine when it is created
+type Option func(*Machine)
+
+// Presents the screen on r. Without this option the machine is headless.
+func WithRenderer(r Renderer) Option {
+	return func(c8 *Machine) {
+		c8.renderer = r
+	}
+}
+
+// Loads programs to addr instead of DefaultLoadAddress, for instance
+// ETI660LoadAddress for programs written for the ETI-660
+func WithLoadAddress(addr uint16) Option {
+	return func(c8 *Machine) {
+		c8.loadAddr = addr
+	}
+}
+
+// Reads key presses from kp
+func WithKeypad(kp Keypad) Option {
+	return func(c8 *Machine) {
+		c8.keypad = kp
+	}
+}
+
+// Runs ips instructions per second of emulated time. Rates that are not a
+// multiple of the frame rate are spread evenly across frames.
+func WithInstructionsPerSecond(ips int) Option {
+	return func(c8 *Machine) {
+		if ips < 0 {
+			ips = 0
+		}
+		c8.ips = ips
+	}
+}
+
+// Runs n instructions in every frame
+func WithInstructionsPerFrame(n int) Option {
+	return WithInstructionsPerSecond(n * FrameRate)
+}
+
+// Returns a new Machine with the fonts loaded and PC at the program start
+func New(opts ...Option) *Machine {
+	c8 := new(Machine)
+	c8.renderer = HeadlessRenderer{}
+	c8.ips = DefaultInstructionsPerSecond
+	c8.loadAddr = DefaultLoadAddress
+	c8.quirks = DefaultQuirks
+	c8.tone = DefaultTone
+	c8.random = new(SeededSource)
+	c8.seed = time.Now().UnixNano()
+	for _, opt := range opts {
+		opt(c8)
+	}
+	c8.Reset()
+	return c8
+}
+
+// Returns a new Machine with the program read from r already loaded
+func NewFromROM(r io.Reader, opts ...Option) (*Machine, error) {
+	c8 := New(opts...)
+	if _, err := c8.LoadROM(r); err != nil {
+		return nil, err
+	}
+	return c8, nil
+}
+
+// Puts the machine back in its power-on state, keeping the loaded program
+func (c8 *Machine) Reset() {
+	c8.memory = make([]uint8, c8.variant.memorySize())
+	c8.graphics.reset()
+	c8.reg = [16]uint8{}
+	c8.i = 0
+	c8.pc = c8.loadAddr
+	c8.timerDelay = 0
+	c8.soundDelay = 0
+	c8.stack = [16]uint16{}
+	c8.sp = 0
+	c8.key = [16]bool{}
+	c8.waitingKey = false
+	c8.waitReg = 0
+	c8.waitKey = -1
+	c8.drawFlag = false
+	c8.cycles = 0
+	c8.frames = 0
+	c8.halted = nil
+	c8.vblankWait = false
+	c8.exited = false
+	c8.pattern = [16]uint8{}
+	c8.pitch = defaultPitch
+	c8.audioPhase = 0
+	c8.random.Seed(c8.seed)
+	c8.randomDraws = 0
+	c8.traceSeed = true
+	c8.frameStep = 0
+	if c8.rewind != nil {
+		c8.rewind.clear()
+	}
+	c8.loadSprites()
+	if int(c8.loadAddr) < len(c8.memory) {
+		copy(c8.memory[c8.loadAddr:], c8.rom)
+	}
+}
+
+// Executes a single instruction. While the machine is waiting for a key the
+// cycle is spent polling the keypad. Returns a *Fault if the instruction
+// could not be executed, or if the machine was already halted by one, and
+// ErrExit once the program has exited.
+func (c8 *Machine) Step() error {
+	if c8.halted != nil {
+		return c8.halted
+	}
+	if c8.exited {
+		return ErrExit
+	}
+	c8.pollKeypad()
+	c8.cycles++
+	if c8.waitingKey {
+		c8.waitForKey()
+		return nil
+	}
+	return c8.emulateOneCycle()
+}
+
+// Executes the given number of instructions, presenting the screen if it
+// changed. Timers are not touched, see RunFrame. Stops at the first fault.
+func (c8 *Machine) RunFor(cycles int) error {
+	defer c8.present()
+	for n := 0; n < cycles; n++ {
+		if err := c8.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Runs one 60th of a second: the instructions due in this frame followed by
+// a tick of the timers. The screen is presented if it changed. A fault ends
+// the frame early without ticking the timers, and so does drawing a sprite
+// under the DisplayWait quirk.
+func (c8 *Machine) RunFrame() error {
+	defer c8.present()
+	if c8.frameStep == 0 {
+		if c8.rewind != nil {
+			c8.rewind.capture(c8)
+		}
+		c8.vblankWait = false
+	}
+	// Instructions run so far at the start and end of this frame, so that
+	// rates which are not a multiple of 60 don't drift
+	start := c8.frames * uint64(c8.ips) / FrameRate
+	end := (c8.frames + 1) * uint64(c8.ips) / FrameRate
+	for ; start+c8.frameStep < end && !c8.vblankWait; c8.frameStep++ {
+		if c8.breakHook != nil && !c8.replaying() && c8.breakHook(c8) {
+			return ErrBreak
+		}
+		if err := c8.Step(); err != nil {
+			return err
+		}
+	}
+	c8.frameStep = 0
+	if c8.audio != nil && !c8.replaying() {
+		c8.playAudio()
+	}
+	c8.TickTimers()
+	c8.frames++
+	if c8.recorder != nil && !c8.replaying() {
+		c8.recorder.Capture(&c8.graphics)
+	}
+	if c8.movie != nil && !c8.moviePlaying && !c8.replaying() {
+		c8.movie.Frames = c8.frames
+	}
+	return nil
+}
+
+// Sets the hook called before each instruction run by RunFrame, nil to
+// remove it. The hook is not called while rewinding.
+func (c8 *Machine) SetBreakHook(hook BreakHook) {
+	c8.breakHook = hook
+}
+
+// Counts the delay and sound timers down by one, stopping at zero
+func (c8 *Machine) TickTimers() {
+	if c8.timerDelay > 0 {
+		c8.timerDelay--
+	}
+	if c8.soundDelay > 0 {
+		c8.soundDelay--
+	}
+}
+
+// Hands the framebuffer to the renderer if it has been drawn to
+func (c8 *Machine) present() {
+	if c8.replaying() {
+		// Only the frame rewound to is shown
+		return
+	}
+	if c8.drawFlag {
+		c8.renderer.Render(&c8.graphics)
+		c8.drawFlag = false
+	}
+}
+
+// Returns a copy of the general purpose registers V0 to VF
+func (c8 *Machine) Registers() [16]uint8 {
+	return c8.reg
+}
+
+// Returns the value of the address register I
+func (c8 *Machine) I() uint16 {
+	return c8.i
+}
+
+// Returns the program counter
+func (c8 *Machine) PC() uint16 {
+	return c8.pc
+}
+
+// Returns the stack pointer, the number of return addresses on the stack
+func (c8 *Machine) SP() uint16 {
+	return c8.sp
+}
+
+// Returns a copy of the addresses of the CALLs currently on the stack,
+// oldest first
+func (c8 *Machine) Stack() []uint16 {
+	stack := make([]uint16, c8.sp)
+	copy(stack, c8.stack[:c8.sp])
+	return stack
+}
+
+// Returns the current value of the delay timer
+func (c8 *Machine) DelayTimer() uint8 {
+	return c8.timerDelay
+}
+
+// Returns the current value of the sound timer
+func (c8 *Machine) SoundTimer() uint8 {
+	return c8.soundDelay
+}
+
+// Returns the number of instructions executed since the last reset
+func (c8 *Machine) Cycles() uint64 {
+	return c8.cycles
+}
+
+// Reports whether the program has exited with 00FD
+func (c8 *Machine) Exited() bool {
+	return c8.exited
+}
+
+// Returns the number of frames run since the last reset
+func (c8 *Machine) Frames() uint64 {
+	return c8.frames
+}
+
+// Returns the screen contents. The framebuffer is owned by the machine and
+// changes as instructions execute.
+func (c8 *Machine) Framebuffer() *Framebuffer {
+	return &c8.graphics
+}
+
+// Returns the XO-CHIP audio pattern, 128 one-bit samples loaded by F002
+func (c8 *Machine) AudioPattern() [16]uint8 {
+	return c8.pattern
+}
+
+// Returns the XO-CHIP pitch set by FX3A. The pattern plays at
+// 4000*2^((pitch-64)/48) bits per second.
+func (c8 *Machine) Pitch() uint8 {
+	return c8.pitch
+}
+
+// Returns the byte of memory at addr, 0 past the end of memory
+func (c8 *Machine) ReadMemory(addr uint16) uint8 {
+	if int(addr) >= len(c8.memory) {
+		return 0
+	}
+	return c8.memory[addr]
+}
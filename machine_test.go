@@ -0,0 +1,80 @@
+package chip8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAccessors(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader([]byte{0x60, 0x0A, 0x22, 0x06, 0x00, 0x00, 0xA1, 0x23}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c8.RunFor(3)
+
+	if c8.Registers()[0] != 0x0A {
+		t.Errorf("Expected V0 of 0x0A, got 0x%02X instead", c8.Registers()[0])
+	}
+	if c8.I() != 0x123 {
+		t.Errorf("Expected I of 0x123, got 0x%04X instead", c8.I())
+	}
+	if c8.PC() != 0x208 {
+		t.Errorf("Expected pc of 0x208, got 0x%04X instead", c8.PC())
+	}
+	if c8.SP() != 1 {
+		t.Errorf("Expected sp of 1, got %d instead", c8.SP())
+	}
+	stack := c8.Stack()
+	if len(stack) != 1 || stack[0] != 0x202 {
+		t.Errorf("Expected stack of [0x202], got %X instead", stack)
+	}
+	stack[0] = 0
+	if c8.Stack()[0] != 0x202 {
+		t.Errorf("Expected Stack to return a copy")
+	}
+}
+
+func TestReset(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader([]byte{0x60, 0x0A, 0x70, 0x01}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c8.RunFor(2)
+	c8.memory[0x200] = 0x00
+	c8.Reset()
+
+	if c8.PC() != 0x200 {
+		t.Errorf("Expected pc of 0x200, got 0x%04X instead", c8.PC())
+	}
+	if c8.Registers()[0] != 0 {
+		t.Errorf("Expected V0 of 0x00, got 0x%02X instead", c8.Registers()[0])
+	}
+	if c8.Cycles() != 0 {
+		t.Errorf("Expected 0 cycles, got %d instead", c8.Cycles())
+	}
+	if c8.memory[0x200] != 0x60 {
+		t.Errorf("Expected rom to be restored, got 0x%02X instead", c8.memory[0x200])
+	}
+}
+
+func TestBreakHook(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader([]byte{0x70, 0x01, 0x12, 0x00}), WithInstructionsPerFrame(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c8.SetBreakHook(func(m *Machine) bool { return m.Cycles() == 4 })
+	if err := c8.RunFrame(); err != ErrBreak {
+		t.Errorf("Expected ErrBreak, got %v instead", err)
+	}
+	if c8.Cycles() != 4 || c8.Frames() != 0 {
+		t.Errorf("Expected to stop after 4 cycles, got %d instead", c8.Cycles())
+	}
+	// The rest of the frame runs once the hook lets it
+	c8.SetBreakHook(nil)
+	if err := c8.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if c8.Cycles() != 10 || c8.Frames() != 1 {
+		t.Errorf("Expected 10 cycles in the frame, got %d instead", c8.Cycles())
+	}
+}
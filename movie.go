@@ -0,0 +1,270 @@
+package chip8
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Version of the movie format written by Movie.Write
+const MovieVersion = 1
+
+var (
+	// ErrMovieROMMismatch is returned when playing a movie of a different
+	// program than the one loaded
+	ErrMovieROMMismatch = errors.New("chip8: movie is for a different rom")
+	// ErrInvalidMovie is returned when reading data that is not a movie
+	ErrInvalidMovie = errors.New("chip8: invalid movie")
+	// ErrMovieRandomSource is returned when recording a movie with a
+	// CryptoSource, whose numbers can't be drawn again to play it
+	ErrMovieRandomSource = errors.New("chip8: can't record a movie with crypto random numbers")
+)
+
+// Movie is the input of a session: every key press and release, and
+// everything else needed to run the session again exactly as it was.
+// Programs only see the keypad and the random numbers of CXNN, so a
+// machine playing the movie goes through the same states as the one that
+// recorded it.
+type Movie struct {
+	// SHA-256 of the program
+	ROMHash     [sha256.Size]byte
+	Variant     Variant
+	LoadAddress uint16
+	Quirks      Quirks
+	// Instructions per second
+	IPS int
+	// Name of the RandomSource and its seed. The machine playing the movie
+	// keeps its own source when it has the same name or the name is empty,
+	// so a FixedSource must be given the same bytes.
+	Random string
+	Seed   int64
+	// Frames run while recording
+	Frames uint64
+	// Key changes, oldest first
+	Input []MovieInput
+}
+
+// MovieInput is a key pressed or released during a movie
+type MovieInput struct {
+	// Instructions executed before the change, which is when it is played
+	Cycle uint64
+	// Frames run before the change
+	Frame   uint64
+	Key     uint8
+	Pressed bool
+}
+
+// Resets the machine and records its input into a movie until StopMovie.
+// The movie is updated as the machine runs. Rewinding drops the input of
+// the frames rewound over from the movie, so it plays back the session as
+// it ended up. The random source is part of the movie, and a CryptoSource
+// returns ErrMovieRandomSource instead as its numbers can't be repeated.
+func (c8 *Machine) RecordMovie() (*Movie, error) {
+	if _, ok := c8.random.(*CryptoSource); ok {
+		return nil, ErrMovieRandomSource
+	}
+	c8.Reset()
+	c8.movie = &Movie{
+		ROMHash:     sha256.Sum256(c8.rom),
+		Variant:     c8.variant,
+		LoadAddress: c8.loadAddr,
+		Quirks:      c8.quirks,
+		IPS:         c8.ips,
+		Random:      c8.random.Name(),
+		Seed:        c8.seed,
+	}
+	c8.moviePlaying = false
+	return c8.movie, nil
+}
+
+// Switches to the variant, load address, quirks, speed, random source and
+// seed of m, resets the machine and plays the input of m from then on. The
+// keypad is ignored while playing. The program m was recorded with must be
+// loaded, otherwise ErrMovieROMMismatch is returned and the machine is left
+// unchanged.
+func (c8 *Machine) PlayMovie(m *Movie) error {
+	if m.ROMHash != sha256.Sum256(c8.rom) {
+		return ErrMovieROMMismatch
+	}
+	if m.Random != "" && m.Random != c8.random.Name() {
+		random, err := ParseRandomSource(m.Random)
+		if err != nil {
+			return err
+		}
+		c8.random = random
+	}
+	c8.variant = m.Variant
+	c8.loadAddr = m.LoadAddress
+	c8.quirks = m.Quirks
+	c8.ips = m.IPS
+	c8.seed = m.Seed
+	c8.Reset()
+	c8.movie = m
+	c8.moviePlaying = true
+	c8.moviePos = 0
+	return nil
+}
+
+// Reports whether a movie being played has run all its frames
+func (c8 *Machine) MovieFinished() bool {
+	return c8.movie != nil && c8.moviePlaying && c8.frames >= c8.movie.Frames
+}
+
+// Stops recording or playing a movie. The keypad is read again.
+func (c8 *Machine) StopMovie() {
+	c8.movie = nil
+	c8.moviePlaying = false
+}
+
+// Drops the input of the movie being recorded from the current cycle on,
+// after rewinding
+func (c8 *Machine) truncateMovie() {
+	input := c8.movie.Input
+	n := len(input)
+	for n > 0 && input[n-1].Cycle >= c8.cycles {
+		n--
+	}
+	c8.movie.Input = input[:n]
+	c8.movie.Frames = c8.frames
+}
+
+// Records a key change into the movie being recorded
+func (c8 *Machine) recordMovieKey(key uint8, pressed bool) {
+	c8.movie.Input = append(c8.movie.Input, MovieInput{
+		Cycle:   c8.cycles,
+		Frame:   c8.frames,
+		Key:     key,
+		Pressed: pressed,
+	})
+}
+
+// Applies the key changes of the movie due before the next instruction
+func (c8 *Machine) playMovieKeys() {
+	input := c8.movie.Input
+	for c8.moviePos < len(input) && input[c8.moviePos].Cycle <= c8.cycles {
+		in := input[c8.moviePos]
+		c8.setKey(in.Key&0x0F, in.Pressed)
+		c8.moviePos++
+	}
+}
+
+// Movie as stored in a file
+type movieFile struct {
+	Format      string       `json:"format"`
+	Version     int          `json:"version"`
+	ROMHash     string       `json:"rom_sha256"`
+	Variant     string       `json:"variant"`
+	LoadAddress uint16       `json:"load_address"`
+	Quirks      movieQuirks  `json:"quirks"`
+	IPS         int          `json:"ips"`
+	Random      string       `json:"random"`
+	Seed        int64        `json:"seed"`
+	Frames      uint64       `json:"frames"`
+	Input       []movieEvent `json:"input"`
+}
+
+type movieQuirks struct {
+	ShiftVX       bool `json:"shift_vx"`
+	LoadStore     int  `json:"load_store"`
+	JumpVX        bool `json:"jump_vx"`
+	VFReset       bool `json:"vf_reset"`
+	DisplayWait   bool `json:"display_wait"`
+	Clip          bool `json:"clip"`
+	CollisionRows bool `json:"collision_rows"`
+}
+
+type movieEvent struct {
+	Cycle   uint64 `json:"cycle"`
+	Frame   uint64 `json:"frame"`
+	Key     uint8  `json:"key"`
+	Pressed bool   `json:"pressed"`
+}
+
+// Identifies movie files
+const movieFormat = "chip8-movie"
+
+// Writes the movie to w as JSON
+func (m *Movie) Write(w io.Writer) error {
+	f := movieFile{
+		Format:      movieFormat,
+		Version:     MovieVersion,
+		ROMHash:     hex.EncodeToString(m.ROMHash[:]),
+		Variant:     m.Variant.String(),
+		LoadAddress: m.LoadAddress,
+		Quirks: movieQuirks{
+			ShiftVX:       m.Quirks.ShiftVX,
+			LoadStore:     int(m.Quirks.LoadStore),
+			JumpVX:        m.Quirks.JumpVX,
+			VFReset:       m.Quirks.VFReset,
+			DisplayWait:   m.Quirks.DisplayWait,
+			Clip:          m.Quirks.Clip,
+			CollisionRows: m.Quirks.CollisionRows,
+		},
+		IPS:    m.IPS,
+		Random: m.Random,
+		Seed:   m.Seed,
+		Frames: m.Frames,
+		Input:  make([]movieEvent, len(m.Input)),
+	}
+	for i, in := range m.Input {
+		f.Input[i] = movieEvent(in)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(&f)
+}
+
+// Reads a movie written by Movie.Write
+func ReadMovie(r io.Reader) (*Movie, error) {
+	var f movieFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMovie, err)
+	}
+	if f.Format != movieFormat {
+		return nil, ErrInvalidMovie
+	}
+	if f.Version != MovieVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidMovie, f.Version)
+	}
+	m := &Movie{
+		LoadAddress: f.LoadAddress,
+		Quirks: Quirks{
+			ShiftVX:       f.Quirks.ShiftVX,
+			LoadStore:     LoadStore(f.Quirks.LoadStore),
+			JumpVX:        f.Quirks.JumpVX,
+			VFReset:       f.Quirks.VFReset,
+			DisplayWait:   f.Quirks.DisplayWait,
+			Clip:          f.Quirks.Clip,
+			CollisionRows: f.Quirks.CollisionRows,
+		},
+		IPS:    f.IPS,
+		Random: f.Random,
+		Seed:   f.Seed,
+		Frames: f.Frames,
+		Input:  make([]MovieInput, len(f.Input)),
+	}
+	hash, err := hex.DecodeString(f.ROMHash)
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("%w: bad rom hash %q", ErrInvalidMovie, f.ROMHash)
+	}
+	copy(m.ROMHash[:], hash)
+	if m.Variant, err = ParseVariant(f.Variant); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMovie, err)
+	}
+	if m.Random == "crypto" {
+		return nil, fmt.Errorf("%w: random source %q", ErrInvalidMovie, m.Random)
+	}
+	if m.Quirks.LoadStore < LoadStoreIncrement || m.Quirks.LoadStore > LoadStoreKeep || m.IPS < 0 {
+		return nil, ErrInvalidMovie
+	}
+	for i, ev := range f.Input {
+		if ev.Key > 0x0F {
+			return nil, fmt.Errorf("%w: key %d", ErrInvalidMovie, ev.Key)
+		}
+		m.Input[i] = MovieInput(ev)
+	}
+	return m, nil
+}
@@ -0,0 +1,174 @@
+package chip8
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSeed(t *testing.T) {
+	a, _ := NewFromROM(bytes.NewReader(rewindROM), WithSeed(42))
+	b, _ := NewFromROM(bytes.NewReader(rewindROM), WithSeed(42))
+	for f := 0; f < 10; f++ {
+		a.RunFrame()
+		b.RunFrame()
+	}
+	if !reflect.DeepEqual(a.Snapshot(), b.Snapshot()) {
+		t.Errorf("Expected machines with the same seed to run the same")
+	}
+	if a.Seed() != 42 {
+		t.Errorf("Expected seed 42, got %d instead", a.Seed())
+	}
+
+	// The random numbers start over on reset
+	after := a.Snapshot()
+	a.Reset()
+	for f := 0; f < 10; f++ {
+		a.RunFrame()
+	}
+	if !reflect.DeepEqual(a.Snapshot(), after) {
+		t.Errorf("Expected the same run after a reset")
+	}
+}
+
+func TestMovie(t *testing.T) {
+	// Key 0 goes down in the 5th poll and up in the 300th
+	polls := make([][]KeyEvent, 300)
+	polls[4] = []KeyEvent{{Key: 0, Pressed: true}}
+	polls[299] = []KeyEvent{{Key: 0, Pressed: false}}
+	kp := &scriptedKeypad{polls: polls}
+	rec, err := NewFromROM(bytes.NewReader(rewindROM), WithKeypad(kp), WithSeed(7), WithInstructionsPerSecond(600), WithRandomSource(new(FixedSource)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	movie, err := rec.RecordMovie()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f := 0; f < 60; f++ {
+		if err := rec.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if movie.Frames != 60 || len(movie.Input) != 2 {
+		t.Fatalf("Expected 60 frames and 2 key changes, got %d and %d instead", movie.Frames, len(movie.Input))
+	}
+	if in := movie.Input[1]; in.Cycle != 299 || in.Frame != 29 || in.Pressed {
+		t.Errorf("Expected the release at cycle 299 of frame 29, got %+v instead", in)
+	}
+	if movie.Random != "fixed" {
+		t.Errorf("Expected the fixed random source, got %q instead", movie.Random)
+	}
+
+	var buf bytes.Buffer
+	if err := movie.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadMovie(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, movie) {
+		t.Errorf("Expected %+v, got %+v instead", movie, read)
+	}
+
+	// A different seed, random source, speed and keypad are overridden by
+	// the movie
+	other := &scriptedKeypad{polls: [][]KeyEvent{{{Key: 0, Pressed: true}}}}
+	play, _ := NewFromROM(bytes.NewReader(rewindROM), WithKeypad(other), WithSeed(8))
+	if err := play.PlayMovie(read); err != nil {
+		t.Fatal(err)
+	}
+	for !play.MovieFinished() {
+		if err := play.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(play.Snapshot(), rec.Snapshot()) {
+		t.Errorf("Expected the movie to end in the recorded state")
+	}
+	if play.Frames() != 60 {
+		t.Errorf("Expected 60 frames, got %d instead", play.Frames())
+	}
+}
+
+func TestMovieFixedSource(t *testing.T) {
+	bytesSource := func() RandomSource { return &FixedSource{Bytes: []uint8{1, 2, 3}} }
+	rec, _ := NewFromROM(bytes.NewReader(rewindROM), WithRandomSource(bytesSource()))
+	movie, err := rec.RecordMovie()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runRewindFrames(t, rec, 50)
+
+	var buf bytes.Buffer
+	if err := movie.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadMovie(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Random != "fixed" {
+		t.Errorf("Expected the fixed random source, got %q instead", read.Random)
+	}
+	// The machine playing the movie keeps its own bytes
+	play, _ := NewFromROM(bytes.NewReader(rewindROM), WithRandomSource(bytesSource()))
+	if err := play.PlayMovie(read); err != nil {
+		t.Fatal(err)
+	}
+	for !play.MovieFinished() {
+		play.RunFrame()
+	}
+	if !reflect.DeepEqual(play.Snapshot(), rec.Snapshot()) {
+		t.Errorf("Expected the movie to end in the recorded state")
+	}
+}
+
+func TestMovieRewind(t *testing.T) {
+	rec, _ := NewFromROM(bytes.NewReader(rewindROM), WithRewind(10, 1<<20))
+	movie, _ := rec.RecordMovie()
+	runRewindFrames(t, rec, 50)
+	// The release in frame 47 is rewound over, and the key released earlier
+	if err := rec.Rewind(10); err != nil {
+		t.Fatal(err)
+	}
+	if len(movie.Input) != 1 || movie.Frames != 40 {
+		t.Fatalf("Expected the press in 40 frames, got %+v instead", movie)
+	}
+	rec.ReleaseKey(0)
+	for f := 0; f < 10; f++ {
+		rec.RunFrame()
+	}
+	if movie.Frames != 50 || len(movie.Input) != 2 || movie.Input[1].Frame != 40 {
+		t.Fatalf("Expected the release in frame 40 of 50, got %+v instead", movie)
+	}
+
+	play, _ := NewFromROM(bytes.NewReader(rewindROM))
+	play.PlayMovie(movie)
+	for !play.MovieFinished() {
+		play.RunFrame()
+	}
+	if !reflect.DeepEqual(play.Snapshot(), rec.Snapshot()) {
+		t.Errorf("Expected the movie to end in the state the session ended in")
+	}
+}
+
+func TestMovieROMMismatch(t *testing.T) {
+	rec, _ := NewFromROM(bytes.NewReader(rewindROM))
+	movie, _ := rec.RecordMovie()
+	play, _ := NewFromROM(bytes.NewReader([]byte{0x12, 0x00}))
+	if err := play.PlayMovie(movie); !errors.Is(err, ErrMovieROMMismatch) {
+		t.Errorf("Expected ErrMovieROMMismatch, got %v instead", err)
+	}
+	if _, err := ReadMovie(bytes.NewReader([]byte(`{"format": "something else"}`))); !errors.Is(err, ErrInvalidMovie) {
+		t.Errorf("Expected ErrInvalidMovie, got %v instead", err)
+	}
+
+	// Numbers from crypto/rand can't be played back
+	crypto, _ := NewFromROM(bytes.NewReader(rewindROM), WithRandomSource(new(CryptoSource)))
+	if _, err := crypto.RecordMovie(); !errors.Is(err, ErrMovieRandomSource) {
+		t.Errorf("Expected ErrMovieRandomSource, got %v instead", err)
+	}
+}
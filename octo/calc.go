@@ -0,0 +1,144 @@
+package octo
+
+import (
+	"math"
+)
+
+// Operators of :calc taking two values
+var binaryOps = map[string]func(a, b float64) float64{
+	"+":   func(a, b float64) float64 { return a + b },
+	"-":   func(a, b float64) float64 { return a - b },
+	"*":   func(a, b float64) float64 { return a * b },
+	"/":   func(a, b float64) float64 { return a / b },
+	"%":   func(a, b float64) float64 { return math.Mod(a, b) },
+	"&":   func(a, b float64) float64 { return float64(int64(a) & int64(b)) },
+	"|":   func(a, b float64) float64 { return float64(int64(a) | int64(b)) },
+	"^":   func(a, b float64) float64 { return float64(int64(a) ^ int64(b)) },
+	"<<":  func(a, b float64) float64 { return float64(int64(a) << uint(b)) },
+	">>":  func(a, b float64) float64 { return float64(int64(a) >> uint(b)) },
+	"pow": math.Pow,
+	"min": math.Min,
+	"max": math.Max,
+	"<":   func(a, b float64) float64 { return truth(a < b) },
+	">":   func(a, b float64) float64 { return truth(a > b) },
+	"<=":  func(a, b float64) float64 { return truth(a <= b) },
+	">=":  func(a, b float64) float64 { return truth(a >= b) },
+	"==":  func(a, b float64) float64 { return truth(a == b) },
+	"!=":  func(a, b float64) float64 { return truth(a != b) },
+}
+
+// Operators of :calc taking one value, written before it
+var unaryOps = map[string]func(a float64) float64{
+	"-":     func(a float64) float64 { return -a },
+	"~":     func(a float64) float64 { return float64(^int64(a)) },
+	"!":     func(a float64) float64 { return truth(a == 0) },
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"exp":   math.Exp,
+	"log":   math.Log,
+	"abs":   math.Abs,
+	"sqrt":  math.Sqrt,
+	"sign":  sign,
+	"ceil":  math.Ceil,
+	"floor": math.Floor,
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sign(a float64) float64 {
+	switch {
+	case a > 0:
+		return 1
+	case a < 0:
+		return -1
+	}
+	return 0
+}
+
+// Evaluates the expression in braces that follows. As in Octo, binary
+// operators have no precedence and group to the right, so 2 * 3 + 1 is 8;
+// parentheses group the rest. HERE is the current address, and @ reads a
+// byte the program has written.
+func (c *compiler) calc() float64 {
+	e := &calcParser{c: c, tokens: c.braces()}
+	v := e.expr()
+	if e.pos < len(e.tokens) {
+		c.fail("unexpected %s in expression", e.tokens[e.pos].text)
+	}
+	return v
+}
+
+type calcParser struct {
+	c      *compiler
+	tokens []token
+	pos    int
+}
+
+func (e *calcParser) next() token {
+	if e.pos >= len(e.tokens) {
+		e.c.fail("incomplete expression")
+	}
+	t := e.tokens[e.pos]
+	e.pos++
+	return t
+}
+
+func (e *calcParser) expr() float64 {
+	v := e.term()
+	if e.pos >= len(e.tokens) || e.tokens[e.pos].text == ")" {
+		return v
+	}
+	t := e.next()
+	op, ok := binaryOps[t.text]
+	if !ok {
+		e.c.fail("unknown operator %s", t.text)
+	}
+	return op(v, e.expr())
+}
+
+func (e *calcParser) term() float64 {
+	t := e.next()
+	if t.quoted {
+		e.c.fail("unexpected string %q in expression", t.text)
+	}
+	if op, ok := unaryOps[t.text]; ok {
+		return op(e.term())
+	}
+	switch t.text {
+	case "(":
+		v := e.expr()
+		if e.next().text != ")" {
+			e.c.fail("missing )")
+		}
+		return v
+	case "@":
+		addr := int(e.term())
+		if addr < 0 || addr >= len(e.c.rom) {
+			e.c.fail("@ 0x%X is outside memory", addr)
+		}
+		return float64(e.c.rom[addr])
+	case "HERE":
+		return float64(e.c.here)
+	case "PI":
+		return math.Pi
+	case "E":
+		return math.E
+	}
+	if v, ok := e.c.consts[t.text]; ok {
+		return v
+	}
+	if v, ok := e.c.labels[t.text]; ok {
+		return float64(v)
+	}
+	v, err := parseNumber(t.text)
+	if err != nil {
+		e.c.fail("undefined value %s", t.text)
+	}
+	return v
+}
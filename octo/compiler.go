@@ -0,0 +1,917 @@
+package octo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/albertseo/chip8"
+	"github.com/albertseo/chip8/debugger"
+)
+
+// Most macro expansions in a program, to catch macros expanding themselves
+const maxExpansions = 10000
+
+// Kinds of address references patched once labels are known
+type fixupKind int
+
+const (
+	// The low 12 bits of an instruction
+	fixupNNN fixupKind = iota
+	// A 16 bit address, following F000
+	fixupLong
+	// The operands of :unpack, v0 := nibble and high bits and v1 := low
+	// byte
+	fixupUnpackHigh
+	fixupUnpackLow
+)
+
+// A reference to a label not defined yet
+type fixup struct {
+	addr   int
+	kind   fixupKind
+	label  string
+	line   int
+	nibble int
+}
+
+// A :macro and its parameters
+type macro struct {
+	params []string
+	body   []token
+}
+
+// An open if ... begin or loop, with the addresses of the jumps to patch
+// once its end is known
+type block struct {
+	loop  bool
+	start int
+	jumps []int
+	line  int
+}
+
+type compiler struct {
+	file   string
+	tokens []token
+	pos    int
+	// Line of the last token read
+	line int
+
+	rom     []byte
+	written []bool
+	here    int
+	end     int
+
+	labels  map[string]int
+	consts  map[string]float64
+	aliases map[string]int
+	macros  map[string]*macro
+	fixups  []fixup
+	blocks  []block
+	// Label for the second byte of the next instruction, from :next
+	nextLabel  string
+	expansions int
+	// Whether 0x200 holds the jump to main
+	jumpToMain bool
+
+	breakpoints map[int]string
+	monitors    []debugger.Monitor
+}
+
+func newCompiler(file string, tokens []token) *compiler {
+	return &compiler{
+		file:        file,
+		tokens:      tokens,
+		rom:         make([]byte, 0x10000),
+		written:     make([]bool, 0x10000),
+		here:        chip8.DefaultLoadAddress,
+		end:         chip8.DefaultLoadAddress,
+		labels:      make(map[string]int),
+		consts:      make(map[string]float64),
+		aliases:     make(map[string]int),
+		macros:      make(map[string]*macro),
+		breakpoints: make(map[int]string),
+	}
+}
+
+// Stops compiling with an error at the current line
+func (c *compiler) fail(format string, args ...interface{}) {
+	panic(&Error{File: c.file, Line: c.line, Err: fmt.Errorf(format, args...)})
+}
+
+// Compiles the whole program
+func (c *compiler) compile() {
+	// Jump to main, dropped if main comes first
+	c.inst(0x10, 0x00)
+	c.jumpToMain = true
+	c.fixups = append(c.fixups, fixup{addr: chip8.DefaultLoadAddress, kind: fixupNNN, label: "main"})
+
+	for c.pos < len(c.tokens) {
+		c.statement()
+	}
+	if len(c.blocks) > 0 {
+		b := c.blocks[len(c.blocks)-1]
+		c.line = b.line
+		if b.loop {
+			c.fail("loop without again")
+		}
+		c.fail("begin without end")
+	}
+	if _, ok := c.labels["main"]; !ok {
+		c.line = 0
+		if len(c.tokens) > 0 {
+			c.line = c.tokens[len(c.tokens)-1].line
+		}
+		c.fail("the program has no main label")
+	}
+	for _, f := range c.fixups {
+		addr, ok := c.labels[f.label]
+		c.line = f.line
+		if !ok {
+			c.fail("undefined label %s", f.label)
+		}
+		c.patch(f, addr)
+	}
+}
+
+// Writes the address of a label into a reference to it
+func (c *compiler) patch(f fixup, addr int) {
+	switch f.kind {
+	case fixupNNN:
+		if addr > 0xFFF {
+			c.fail("%s at 0x%04X is out of reach of a 12 bit address", f.label, addr)
+		}
+		c.rom[f.addr] = c.rom[f.addr]&0xF0 | byte(addr>>8)
+		c.rom[f.addr+1] = byte(addr)
+	case fixupLong:
+		c.rom[f.addr] = byte(addr >> 8)
+		c.rom[f.addr+1] = byte(addr)
+	case fixupUnpackHigh:
+		c.rom[f.addr+1] = byte(f.nibble<<4 | addr>>8&0xF)
+		if f.nibble < 0 {
+			c.rom[f.addr+1] = byte(addr >> 8)
+		}
+	case fixupUnpackLow:
+		c.rom[f.addr+1] = byte(addr)
+	}
+}
+
+// Returns the next token
+func (c *compiler) next() token {
+	if c.pos >= len(c.tokens) {
+		c.fail("unexpected end of the program")
+	}
+	t := c.tokens[c.pos]
+	c.pos++
+	c.line = t.line
+	return t
+}
+
+// Returns the next token without reading it, or "" at the end
+func (c *compiler) peek() string {
+	if c.pos >= len(c.tokens) {
+		return ""
+	}
+	return c.tokens[c.pos].text
+}
+
+// Reads the token text, failing on anything else
+func (c *compiler) expect(text string) {
+	if t := c.next(); t.text != text || t.quoted {
+		c.fail("expected %s, got %s", text, t.text)
+	}
+}
+
+// Writes a byte at the current address
+func (c *compiler) emit(b byte) {
+	if c.here >= len(c.rom) {
+		c.fail("the program runs past the end of memory")
+	}
+	if c.written[c.here] {
+		c.fail("data overlaps at 0x%04X", c.here)
+	}
+	c.rom[c.here] = b
+	c.written[c.here] = true
+	c.here++
+	if c.here > c.end {
+		c.end = c.here
+	}
+}
+
+// Writes an instruction, labelling its second byte if :next asked for it
+func (c *compiler) inst(hi, lo byte) {
+	if c.nextLabel != "" {
+		c.define(c.nextLabel, c.here+1)
+		c.nextLabel = ""
+	}
+	c.emit(hi)
+	c.emit(lo)
+}
+
+// Defines a label at addr
+func (c *compiler) define(name string, addr int) {
+	if c.isDefined(name) {
+		c.fail("%s is already defined", name)
+	}
+	c.labels[name] = addr
+}
+
+// Reports whether name is taken by a label, constant, alias or macro
+func (c *compiler) isDefined(name string) bool {
+	_, label := c.labels[name]
+	_, constant := c.consts[name]
+	_, alias := c.aliases[name]
+	_, mac := c.macros[name]
+	return label || constant || alias || mac
+}
+
+// Returns a name for a new label, constant, alias or macro
+func (c *compiler) name() string {
+	t := c.next()
+	if t.quoted || !isName(t.text) || isKeyword(t.text) {
+		c.fail("invalid name %s", t.text)
+	}
+	if _, ok := register(t.text); ok {
+		c.fail("%s names a register", t.text)
+	}
+	return t.text
+}
+
+// Reports whether s can name a label
+func isName(s string) bool {
+	for i, r := range s {
+		switch {
+		case r == '_' || r == '.' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+		case (r == '-' || r >= '0' && r <= '9') && i > 0:
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Words of the language that can't name anything
+var keywords = map[string]bool{
+	"return": true, "clear": true, "bcd": true, "save": true, "load": true,
+	"saveflags": true, "loadflags": true, "sprite": true, "jump": true,
+	"jump0": true, "native": true, "hires": true, "lores": true, "exit": true,
+	"scroll-down": true, "scroll-up": true, "scroll-left": true,
+	"scroll-right": true, "plane": true, "audio": true, "pitch": true,
+	"delay": true, "buzzer": true, "if": true, "then": true, "begin": true,
+	"else": true, "end": true, "loop": true, "again": true, "while": true,
+	"key": true, "-key": true, "hex": true, "bighex": true, "long": true,
+	"random": true, "i": true,
+}
+
+func isKeyword(s string) bool {
+	return keywords[s]
+}
+
+// Returns the register named by the token, V0 to VF or an alias
+func (c *compiler) register(t token) (int, bool) {
+	if t.quoted {
+		return 0, false
+	}
+	if r, ok := c.aliases[t.text]; ok {
+		return r, true
+	}
+	return register(t.text)
+}
+
+func register(s string) (int, bool) {
+	if len(s) != 2 || s[0] != 'v' && s[0] != 'V' {
+		return 0, false
+	}
+	r, err := strconv.ParseUint(s[1:], 16, 4)
+	return int(r), err == nil
+}
+
+// Reads a register
+func (c *compiler) reg() int {
+	t := c.next()
+	r, ok := c.register(t)
+	if !ok {
+		c.fail("expected a register, got %s", t.text)
+	}
+	return r
+}
+
+// Returns the value of a number, constant or label token
+func (c *compiler) lookup(t token) (int, bool) {
+	if t.quoted {
+		return 0, false
+	}
+	if v, ok := c.consts[t.text]; ok {
+		return int(math.Floor(v)), true
+	}
+	if v, ok := c.labels[t.text]; ok {
+		return v, true
+	}
+	v, err := parseNumber(t.text)
+	return int(v), err == nil
+}
+
+// Parses a decimal, 0x hex or 0b binary number, possibly negative
+func parseNumber(s string) (float64, error) {
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	var v uint64
+	var err error
+	switch {
+	case strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X"):
+		v, err = strconv.ParseUint(s[2:], 16, 32)
+	case strings.HasPrefix(s, "0b") || strings.HasPrefix(s, "0B"):
+		v, err = strconv.ParseUint(s[2:], 2, 32)
+	default:
+		var f float64
+		f, err = strconv.ParseFloat(s, 64)
+		if err == nil && (s == "" || s[0] < '0' || s[0] > '9') {
+			err = strconv.ErrSyntax
+		}
+		if neg {
+			f = -f
+		}
+		return f, err
+	}
+	if neg {
+		return -float64(v), err
+	}
+	return float64(v), err
+}
+
+// Reads a value that must fit in bits, allowing negative values down to
+// -2^(bits-1)
+func (c *compiler) value(bits int) int {
+	t := c.next()
+	v, ok := c.lookup(t)
+	if !ok {
+		c.fail("undefined value %s", t.text)
+	}
+	if v < -(1<<(bits-1)) || v >= 1<<bits {
+		c.fail("%s does not fit in %d bits", t.text, bits)
+	}
+	return v & (1<<bits - 1)
+}
+
+// Writes an instruction taking an address in its low 12 bits, hi being the
+// top nibble. Labels not defined yet are patched later.
+func (c *compiler) addressInst(hi byte) {
+	t := c.next()
+	if v, ok := c.lookup(t); ok {
+		if v < 0 || v > 0xFFF {
+			c.fail("%s is out of reach of a 12 bit address", t.text)
+		}
+		c.inst(hi<<4|byte(v>>8), byte(v))
+		return
+	}
+	c.reference(t, fixupNNN, 0)
+	c.inst(hi<<4, 0)
+}
+
+// Records a reference to a label defined later at the current address
+func (c *compiler) reference(t token, kind fixupKind, nibble int) {
+	if t.quoted || !isName(t.text) || isKeyword(t.text) {
+		c.fail("expected an address, got %s", t.text)
+	}
+	c.fixups = append(c.fixups, fixup{addr: c.here, kind: kind, label: t.text, line: t.line, nibble: nibble})
+}
+
+// Compiles one statement or directive
+func (c *compiler) statement() {
+	t := c.next()
+	if t.quoted {
+		c.fail("unexpected string %q", t.text)
+	}
+	if strings.HasPrefix(t.text, ":") && t.text != ":" && t.text != ":=" {
+		c.directive(t.text)
+		return
+	}
+	if _, ok := c.register(t); ok {
+		c.pos--
+		c.registerStatement()
+		return
+	}
+
+	switch t.text {
+	case ":":
+		name := c.name()
+		if name == "main" && c.jumpToMain && c.here == chip8.DefaultLoadAddress+2 && c.end == c.here {
+			// main comes first, so the program needs no jump to it
+			c.written[c.here-2], c.written[c.here-1] = false, false
+			c.here -= 2
+			c.end = c.here
+			c.fixups = c.fixups[1:]
+		}
+		c.jumpToMain = false
+		c.define(name, c.here)
+	case "return", ";":
+		c.inst(0x00, 0xEE)
+	case "clear":
+		c.inst(0x00, 0xE0)
+	case "bcd":
+		c.inst(0xF0|byte(c.reg()), 0x33)
+	case "save", "load":
+		x := c.reg()
+		if c.peek() == "-" {
+			c.next()
+			y := c.reg()
+			lo := byte(0x02)
+			if t.text == "load" {
+				lo = 0x03
+			}
+			c.inst(0x50|byte(x), byte(y)<<4|lo)
+			return
+		}
+		lo := byte(0x55)
+		if t.text == "load" {
+			lo = 0x65
+		}
+		c.inst(0xF0|byte(x), lo)
+	case "saveflags":
+		c.inst(0xF0|byte(c.reg()), 0x75)
+	case "loadflags":
+		c.inst(0xF0|byte(c.reg()), 0x85)
+	case "sprite":
+		x, y := c.reg(), c.reg()
+		n := c.value(4)
+		c.inst(0xD0|byte(x), byte(y)<<4|byte(n))
+	case "jump":
+		c.addressInst(0x1)
+	case "jump0":
+		c.addressInst(0xB)
+	case "native":
+		c.addressInst(0x0)
+	case "hires":
+		c.inst(0x00, 0xFF)
+	case "lores":
+		c.inst(0x00, 0xFE)
+	case "exit":
+		c.inst(0x00, 0xFD)
+	case "scroll-down":
+		c.inst(0x00, 0xC0|byte(c.value(4)))
+	case "scroll-up":
+		c.inst(0x00, 0xD0|byte(c.value(4)))
+	case "scroll-left":
+		c.inst(0x00, 0xFC)
+	case "scroll-right":
+		c.inst(0x00, 0xFB)
+	case "plane":
+		n := c.value(4)
+		if n > 3 {
+			c.fail("plane %d is not 0 to 3", n)
+		}
+		c.inst(0xF0|byte(n), 0x01)
+	case "audio":
+		c.inst(0xF0, 0x02)
+	case "pitch":
+		c.expect(":=")
+		c.inst(0xF0|byte(c.reg()), 0x3A)
+	case "delay":
+		c.expect(":=")
+		c.inst(0xF0|byte(c.reg()), 0x15)
+	case "buzzer":
+		c.expect(":=")
+		c.inst(0xF0|byte(c.reg()), 0x18)
+	case "i":
+		c.iStatement()
+	case "if":
+		c.ifStatement()
+	case "else":
+		b := c.popBlock(false, "else without begin")
+		c.blocks = append(c.blocks, block{line: t.line, jumps: []int{c.here}})
+		c.inst(0x10, 0x00)
+		c.jumpTo(b.jumps, c.here)
+	case "end":
+		b := c.popBlock(false, "end without begin")
+		c.jumpTo(b.jumps, c.here)
+	case "loop":
+		c.blocks = append(c.blocks, block{loop: true, start: c.here, line: t.line})
+	case "while":
+		if !c.inLoop() {
+			c.fail("while outside of a loop")
+		}
+		c.skipUnless(c.condition(), true)
+		b := &c.blocks[c.loopIndex()]
+		b.jumps = append(b.jumps, c.here)
+		c.inst(0x10, 0x00)
+	case "again":
+		b := c.popBlock(true, "again without loop")
+		c.inst(0x10, 0x00)
+		c.jumpTo([]int{c.here - 2}, b.start)
+		c.jumpTo(b.jumps, c.here)
+	default:
+		if m, ok := c.macros[t.text]; ok {
+			c.expand(m)
+			return
+		}
+		if v, err := parseNumber(t.text); err == nil {
+			if v < -128 || v > 255 {
+				c.fail("%s does not fit in a byte", t.text)
+			}
+			c.emit(byte(int(v)))
+			return
+		}
+		if isKeyword(t.text) || !isName(t.text) {
+			c.fail("unexpected %s", t.text)
+		}
+		// Anything else calls a subroutine
+		c.pos--
+		c.addressInst(0x2)
+	}
+}
+
+// Compiles a statement starting with a register
+func (c *compiler) registerStatement() {
+	x := byte(c.reg())
+	op := c.next()
+	switch op.text {
+	case ":=":
+		t := c.next()
+		switch t.text {
+		case "key":
+			c.inst(0xF0|x, 0x0A)
+			return
+		case "delay":
+			c.inst(0xF0|x, 0x07)
+			return
+		case "random":
+			c.inst(0xC0|x, byte(c.value(8)))
+			return
+		}
+		if y, ok := c.register(t); ok {
+			c.inst(0x80|x, byte(y)<<4)
+			return
+		}
+		c.pos--
+		c.inst(0x60|x, byte(c.value(8)))
+	case "+=", "-=":
+		t := c.next()
+		if y, ok := c.register(t); ok {
+			lo := byte(0x4)
+			if op.text == "-=" {
+				lo = 0x5
+			}
+			c.inst(0x80|x, byte(y)<<4|lo)
+			return
+		}
+		c.pos--
+		n := c.value(8)
+		if op.text == "-=" {
+			n = -n & 0xFF
+		}
+		c.inst(0x70|x, byte(n))
+	default:
+		ops := map[string]byte{"|=": 0x1, "&=": 0x2, "^=": 0x3, ">>=": 0x6, "=-": 0x7, "<<=": 0xE}
+		lo, ok := ops[op.text]
+		if !ok {
+			c.fail("unknown operator %s", op.text)
+		}
+		c.inst(0x80|x, byte(c.reg())<<4|lo)
+	}
+}
+
+// Compiles a statement assigning to i
+func (c *compiler) iStatement() {
+	op := c.next()
+	switch op.text {
+	case "+=":
+		c.inst(0xF0|byte(c.reg()), 0x1E)
+		return
+	case ":=":
+	default:
+		c.fail("unknown operator %s for i", op.text)
+	}
+	switch c.peek() {
+	case "hex":
+		c.next()
+		c.inst(0xF0|byte(c.reg()), 0x29)
+	case "bighex":
+		c.next()
+		c.inst(0xF0|byte(c.reg()), 0x30)
+	case "long":
+		c.next()
+		c.inst(0xF0, 0x00)
+		t := c.next()
+		if v, ok := c.lookup(t); ok {
+			if v < 0 || v > 0xFFFF {
+				c.fail("%s is outside memory", t.text)
+			}
+			c.emit(byte(v >> 8))
+			c.emit(byte(v))
+			return
+		}
+		c.reference(t, fixupLong, 0)
+		c.emit(0)
+		c.emit(0)
+	default:
+		c.addressInst(0xA)
+	}
+}
+
+// A comparison in an if or while
+type condition struct {
+	x  int
+	op string
+	// The right hand side, a register if isReg
+	y     int
+	isReg bool
+}
+
+// The comparisons that hold when a comparison does not
+var negated = map[string]string{
+	"==": "!=", "!=": "==", "key": "-key", "-key": "key",
+	"<": ">=", ">=": "<", ">": "<=", "<=": ">",
+}
+
+// Reads the condition of an if or while
+func (c *compiler) condition() condition {
+	cond := condition{x: c.reg()}
+	cond.op = c.next().text
+	if _, ok := negated[cond.op]; !ok {
+		c.fail("unknown comparison %s", cond.op)
+	}
+	if cond.op == "key" || cond.op == "-key" {
+		return cond
+	}
+	if c.pos < len(c.tokens) {
+		if y, ok := c.register(c.tokens[c.pos]); ok {
+			c.next()
+			cond.y, cond.isReg = y, true
+			return cond
+		}
+	}
+	cond.y = c.value(8)
+	return cond
+}
+
+// Writes instructions skipping the next one unless cond holds, or unless
+// it does not hold if negate is set. <, >, <= and >= compare in VF.
+func (c *compiler) skipUnless(cond condition, negate bool) {
+	op := cond.op
+	if negate {
+		op = negated[op]
+	}
+	x, y := byte(cond.x), byte(cond.y)
+	switch op {
+	case "==", "!=":
+		switch {
+		case op == "==" && cond.isReg:
+			c.inst(0x90|x, y<<4)
+		case op == "==":
+			c.inst(0x40|x, y)
+		case cond.isReg:
+			c.inst(0x50|x, y<<4)
+		default:
+			c.inst(0x30|x, y)
+		}
+		return
+	case "key":
+		c.inst(0xE0|x, 0xA1)
+		return
+	case "-key":
+		c.inst(0xE0|x, 0x9E)
+		return
+	}
+
+	// VF gets the carry of x - y for < and >=, y - x for > and <=, which
+	// is set unless the subtraction borrows
+	xMinusY := op == "<" || op == ">="
+	if cond.isReg {
+		if xMinusY {
+			c.inst(0x8F, x<<4)
+			c.inst(0x8F, y<<4|0x5)
+		} else {
+			c.inst(0x8F, y<<4)
+			c.inst(0x8F, x<<4|0x5)
+		}
+	} else {
+		c.inst(0x6F, y)
+		if xMinusY {
+			c.inst(0x8F, x<<4|0x7)
+		} else {
+			c.inst(0x8F, x<<4|0x5)
+		}
+	}
+	if op == "<" || op == ">" {
+		// Holds when the subtraction borrowed
+		c.inst(0x4F, 0x00)
+	} else {
+		c.inst(0x4F, 0x01)
+	}
+}
+
+// Compiles if ... then and if ... begin
+func (c *compiler) ifStatement() {
+	line := c.line
+	cond := c.condition()
+	switch t := c.next(); t.text {
+	case "then":
+		c.skipUnless(cond, false)
+	case "begin":
+		c.skipUnless(cond, true)
+		c.blocks = append(c.blocks, block{line: line, jumps: []int{c.here}})
+		c.inst(0x10, 0x00)
+	default:
+		c.fail("expected then or begin, got %s", t.text)
+	}
+}
+
+// Removes the innermost block, which must be a loop if loop is set
+func (c *compiler) popBlock(loop bool, msg string) block {
+	if len(c.blocks) == 0 || c.blocks[len(c.blocks)-1].loop != loop {
+		c.fail("%s", msg)
+	}
+	b := c.blocks[len(c.blocks)-1]
+	c.blocks = c.blocks[:len(c.blocks)-1]
+	return b
+}
+
+// Returns the index of the innermost loop, -1 if there is none
+func (c *compiler) loopIndex() int {
+	for i := len(c.blocks) - 1; i >= 0; i-- {
+		if c.blocks[i].loop {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *compiler) inLoop() bool {
+	return c.loopIndex() >= 0
+}
+
+// Points the jumps at the given addresses to target
+func (c *compiler) jumpTo(jumps []int, target int) {
+	if target > 0xFFF {
+		c.fail("jump to 0x%04X is out of reach of a 12 bit address", target)
+	}
+	for _, addr := range jumps {
+		if addr+1 < len(c.rom) && c.written[addr] {
+			c.rom[addr] = 0x10 | byte(target>>8)
+			c.rom[addr+1] = byte(target)
+		}
+	}
+}
+
+// Replaces a macro call with the body of the macro
+func (c *compiler) expand(m *macro) {
+	c.expansions++
+	if c.expansions > maxExpansions {
+		c.fail("more than %d macro expansions, is a macro expanding itself?", maxExpansions)
+	}
+	args := make(map[string]token)
+	for _, p := range m.params {
+		args[p] = c.next()
+	}
+	line := c.line
+	body := make([]token, 0, len(m.body)+len(c.tokens)-c.pos)
+	for _, t := range m.body {
+		if a, ok := args[t.text]; ok && !t.quoted {
+			t = a
+		}
+		t.line = line
+		body = append(body, t)
+	}
+	c.tokens = append(body, c.tokens[c.pos:]...)
+	c.pos = 0
+}
+
+// Compiles a directive starting with a colon
+func (c *compiler) directive(name string) {
+	switch name {
+	case ":alias":
+		alias := c.name()
+		r := c.reg()
+		if _, ok := c.aliases[alias]; !ok && c.isDefined(alias) {
+			c.fail("%s is already defined", alias)
+		}
+		c.aliases[alias] = r
+	case ":const":
+		constant := c.name()
+		t := c.next()
+		if f, err := parseNumber(t.text); err == nil && !t.quoted {
+			c.defineConst(constant, f)
+			return
+		}
+		v, ok := c.lookup(t)
+		if !ok {
+			c.fail("undefined value %s", t.text)
+		}
+		c.defineConst(constant, float64(v))
+	case ":org":
+		addr := c.value(16)
+		if addr < chip8.DefaultLoadAddress {
+			c.fail(":org 0x%04X is before the start of the program", addr)
+		}
+		c.here = addr
+	case ":next":
+		c.nextLabel = c.name()
+	case ":unpack":
+		nibble := 0
+		if c.peek() == "long" {
+			c.next()
+			nibble = -1
+		} else {
+			nibble = c.value(4)
+		}
+		t := c.next()
+		addr, ok := c.lookup(t)
+		if !ok {
+			c.reference(t, fixupUnpackHigh, nibble)
+			c.inst(0x60, 0)
+			c.reference(t, fixupUnpackLow, 0)
+			c.inst(0x61, 0)
+			return
+		}
+		hi := nibble<<4 | addr>>8&0xF
+		if nibble < 0 {
+			hi = addr >> 8
+		}
+		c.inst(0x60, byte(hi))
+		c.inst(0x61, byte(addr))
+	case ":byte":
+		if c.peek() == "{" {
+			v := c.calc()
+			if v < -128 || v > 255 {
+				c.fail("%v does not fit in a byte", v)
+			}
+			c.emit(byte(int(math.Floor(v))))
+			return
+		}
+		c.emit(byte(c.value(8)))
+	case ":call":
+		c.addressInst(0x2)
+	case ":macro":
+		mac := c.name()
+		m := new(macro)
+		for c.peek() != "{" {
+			m.params = append(m.params, c.name())
+		}
+		m.body = c.braces()
+		if c.isDefined(mac) {
+			c.fail("%s is already defined", mac)
+		}
+		c.macros[mac] = m
+	case ":calc":
+		constant := c.name()
+		c.defineConst(constant, c.calc())
+	case ":assert":
+		msg := "assertion failed"
+		if c.pos < len(c.tokens) && c.tokens[c.pos].quoted {
+			msg = "assertion failed: " + c.next().text
+		}
+		if c.calc() == 0 {
+			c.fail("%s", msg)
+		}
+	case ":breakpoint":
+		c.breakpoints[c.here] = c.next().text
+	case ":monitor":
+		t := c.next()
+		addr, ok := c.lookup(t)
+		if !ok || addr < 0 || addr > 0xFFFF {
+			c.fail("cannot monitor %s", t.text)
+		}
+		mon := debugger.Monitor{Name: t.text, Addr: uint16(addr)}
+		if c.pos < len(c.tokens) && c.tokens[c.pos].quoted {
+			mon.Format = c.next().text
+		} else {
+			mon.Length = c.value(8)
+		}
+		c.monitors = append(c.monitors, mon)
+	default:
+		c.fail("unknown directive %s", name)
+	}
+}
+
+// Defines a constant
+func (c *compiler) defineConst(name string, v float64) {
+	if c.isDefined(name) {
+		c.fail("%s is already defined", name)
+	}
+	c.consts[name] = v
+}
+
+// Reads the tokens between braces, which may nest
+func (c *compiler) braces() []token {
+	c.expect("{")
+	var body []token
+	depth := 1
+	for {
+		t := c.next()
+		if !t.quoted {
+			switch t.text {
+			case "{":
+				depth++
+			case "}":
+				depth--
+				if depth == 0 {
+					return body
+				}
+			}
+		}
+		body = append(body, t)
+	}
+}
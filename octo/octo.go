@@ -0,0 +1,163 @@
+// Package octo compiles programs written in Octo, the assembly language
+// most Chip-8 programs are written in today, into a ROM for the machine.
+//
+// Statements, directives and operands are separated by whitespace, and #
+// starts a comment. The compiler supports the Chip-8, SUPER-CHIP and
+// XO-CHIP instructions of Octo, structured control flow with if, loop and
+// while, and the directives :, :alias, :const, :org, :next, :unpack,
+// :byte, :call, :macro, :calc, :assert, :breakpoint and :monitor. The
+// program starts with a jump to the label main, left out when main is the
+// first thing in the program.
+//
+// :breakpoint and :monitor are kept in the Program and installed in a
+// debugger.Debugger with Install.
+package octo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/albertseo/chip8"
+	"github.com/albertseo/chip8/debugger"
+)
+
+// Program is a compiled Octo program
+type Program struct {
+	// The program, loaded at chip8.DefaultLoadAddress
+	Bytes []byte
+	// Address of each label
+	Labels map[string]uint16
+	// Places :breakpoint stops the program, in order of address
+	Breakpoints []Breakpoint
+	// Memory shown by :monitor
+	Monitors []debugger.Monitor
+}
+
+// Breakpoint is a :breakpoint in the program
+type Breakpoint struct {
+	Addr uint16
+	Name string
+}
+
+// Error is the first problem found in the source. Octo stops at the first
+// error, since later ones are mostly caused by it.
+type Error struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Compiles the Octo file name
+func CompileFile(name string) (*Program, error) {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(name, src)
+}
+
+// Compiles the Octo source src, which is named name in errors. Errors are
+// returned as an *Error.
+func Compile(name string, src []byte) (p *Program, err error) {
+	c := newCompiler(name, tokenize(string(src)))
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			p, err = nil, e
+		}
+	}()
+	c.compile()
+	return c.program(), nil
+}
+
+// Adds the breakpoints and monitors of the program to d
+func (p *Program) Install(d *debugger.Debugger) {
+	for _, b := range p.Breakpoints {
+		bp := debugger.AtPC(b.Addr)
+		bp.Name = b.Name
+		d.Add(bp)
+	}
+	for _, mon := range p.Monitors {
+		d.AddMonitor(mon)
+	}
+}
+
+// Returns the compiled program once compile has succeeded
+func (c *compiler) program() *Program {
+	p := &Program{
+		Bytes:    append([]byte(nil), c.rom[chip8.DefaultLoadAddress:c.end]...),
+		Labels:   make(map[string]uint16),
+		Monitors: c.monitors,
+	}
+	for name, addr := range c.labels {
+		p.Labels[name] = uint16(addr)
+	}
+	for addr, name := range c.breakpoints {
+		p.Breakpoints = append(p.Breakpoints, Breakpoint{Addr: uint16(addr), Name: name})
+	}
+	sort.Slice(p.Breakpoints, func(i, j int) bool { return p.Breakpoints[i].Addr < p.Breakpoints[j].Addr })
+	return p
+}
+
+// A word of the source, or a string in double quotes
+type token struct {
+	text   string
+	line   int
+	quoted bool
+}
+
+// Splits src into tokens, dropping comments
+func tokenize(src string) []token {
+	var tokens []token
+	for n, line := range strings.Split(src, "\n") {
+		for i := 0; i < len(line); {
+			switch c := line[i]; {
+			case c == ' ' || c == '\t' || c == '\r':
+				i++
+			case c == '#':
+				i = len(line)
+			case c == '"':
+				end := i + 1
+				for end < len(line) && line[end] != '"' {
+					if line[end] == '\\' {
+						end++
+					}
+					end++
+				}
+				// An unterminated string runs to the end of the line
+				stop := end + 1
+				if stop > len(line) {
+					stop = len(line)
+				}
+				text := line[i:stop]
+				if s, err := strconv.Unquote(text); err == nil {
+					text = s
+				}
+				tokens = append(tokens, token{text: text, line: n + 1, quoted: true})
+				i = end + 1
+			default:
+				end := i
+				for end < len(line) && !strings.ContainsRune(" \t\r", rune(line[end])) {
+					end++
+				}
+				tokens = append(tokens, token{text: line[i:end], line: n + 1})
+				i = end
+			}
+		}
+	}
+	return tokens
+}
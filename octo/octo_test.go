@@ -0,0 +1,216 @@
+package octo
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/albertseo/chip8"
+	"github.com/albertseo/chip8/debugger"
+)
+
+func compile(t *testing.T, src string) *Program {
+	t.Helper()
+	p, err := Compile("test.8o", []byte(src))
+	if err != nil {
+		t.Fatalf("Expected the program to compile, got %v instead", err)
+	}
+	return p
+}
+
+// Runs a compiled program for a few frames and returns the machine
+func run(t *testing.T, p *Program) *chip8.Machine {
+	t.Helper()
+	m, err := chip8.NewFromROM(bytes.NewReader(p.Bytes), chip8.WithVariant(chip8.VariantXOCHIP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := m.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestCompileMain(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected []byte
+	}{
+		{": main clear loop again", []byte{0x00, 0xE0, 0x12, 0x02}},
+		{": sub return : main sub", []byte{0x12, 0x04, 0x00, 0xEE, 0x22, 0x02}},
+		{": main 1 2 0xFF -1 0b101", []byte{1, 2, 0xFF, 0xFF, 5}},
+	}
+	for _, test := range tests {
+		p := compile(t, test.src)
+		if !bytes.Equal(p.Bytes, test.expected) {
+			t.Errorf("%q: expected % X, got % X instead", test.src, test.expected, p.Bytes)
+		}
+	}
+}
+
+func TestCompileInstructions(t *testing.T) {
+	src := `
+: main
+	clear return ; bcd v1 save v2 load v3 save v1 - v4 load v4 - v1
+	saveflags v5 loadflags v6 sprite v1 v2 15 jump main jump0 main native main
+	hires lores exit scroll-down 3 scroll-up 4 scroll-left scroll-right
+	plane 3 audio pitch := v7 delay := v8 buzzer := v9
+	va := key vb := delay vc := random 0x0F vd := 12 ve := v1
+	v1 += 5 v1 -= 1 v1 += v2 v1 -= v2 v1 =- v2 v1 |= v2 v1 &= v2 v1 ^= v2
+	v1 >>= v2 v1 <<= v2
+	i := main i := hex v1 i := bighex v2 i := long 0x1234 i += v3
+`
+	expected := []uint16{
+		0x00E0, 0x00EE, 0x00EE, 0xF133, 0xF255, 0xF365, 0x5142, 0x5413,
+		0xF575, 0xF685, 0xD12F, 0x1200, 0xB200, 0x0200,
+		0x00FF, 0x00FE, 0x00FD, 0x00C3, 0x00D4, 0x00FC, 0x00FB,
+		0xF301, 0xF002, 0xF73A, 0xF815, 0xF918,
+		0xFA0A, 0xFB07, 0xCC0F, 0x6D0C, 0x8E10,
+		0x7105, 0x71FF, 0x8124, 0x8125, 0x8127, 0x8121, 0x8122, 0x8123,
+		0x8126, 0x812E,
+		0xA200, 0xF129, 0xF230, 0xF000, 0x1234, 0xF31E,
+	}
+	var expectedBytes []byte
+	for _, op := range expected {
+		expectedBytes = append(expectedBytes, byte(op>>8), byte(op))
+	}
+	p := compile(t, src)
+	if !bytes.Equal(p.Bytes, expectedBytes) {
+		t.Errorf("Expected\n% X\ngot\n% X\ninstead", expectedBytes, p.Bytes)
+	}
+}
+
+func TestCompileControlFlow(t *testing.T) {
+	// Each comparison sets a bit of v0 when it holds, checking both ways
+	src := `
+: main
+	v1 := 3 v2 := 7
+	if v1 == 3 then v0 += 1
+	if v1 != v2 then v0 += 2
+	if v1 < v2 then v0 += 4
+	if v2 < v1 then v0 += 0x80
+	if v1 > 2 then v0 += 8
+	if v1 <= 3 then v0 += 16
+	if v1 >= v2 then v0 += 0x80
+	if v2 >= 7 begin
+		v3 := 1
+	else
+		v3 := 2
+	end
+	if v1 > v2 begin
+		v4 := 1
+	else
+		v4 := 2
+	end
+	# Counts v5 up to 10 and sums 0 to 9 in v6
+	loop
+		while v5 != 10
+		v6 += v5
+		v5 += 1
+	again
+	loop again
+`
+	m := run(t, compile(t, src))
+	v := m.Registers()
+	expected := []uint8{0x1F, 3, 7, 1, 2, 10, 45}
+	for r, e := range expected {
+		if v[r] != e {
+			t.Errorf("Expected V%X to be %d, got %d instead", r, e, v[r])
+		}
+	}
+}
+
+func TestCompileDirectives(t *testing.T) {
+	src := `
+:const SPEED 3
+# Right to left, so 3 * (2 + 1)
+:calc DOUBLE { SPEED * 2 + 1 }
+:alias counter v4
+:macro add-twice reg n { reg += n reg += n }
+: main
+	counter := DOUBLE
+	add-twice counter SPEED
+	:unpack 0xA data
+	:next target
+	v5 := 0
+	i := long far
+	jump main
+: data
+	:byte { DOUBLE - 1 }
+	:byte SPEED
+:org 0x300
+: far
+	0xAB
+`
+	p := compile(t, src)
+	expected := []byte{
+		0x64, 0x09, 0x74, 0x03, 0x74, 0x03, 0x60, 0xA2, 0x61, 0x12, 0x65, 0x00,
+		0xF0, 0x00, 0x03, 0x00, 0x12, 0x00, 0x08, 0x03,
+	}
+	if !bytes.Equal(p.Bytes[:len(expected)], expected) {
+		t.Errorf("Expected % X, got % X instead", expected, p.Bytes[:len(expected)])
+	}
+	if len(p.Bytes) != 0x101 || p.Bytes[0x100] != 0xAB {
+		t.Errorf("Expected the program to end with 0xAB at 0x300, got %d bytes instead", len(p.Bytes))
+	}
+	if p.Labels["target"] != 0x20B || p.Labels["far"] != 0x300 {
+		t.Errorf("Expected target at 0x20B and far at 0x300, got %v instead", p.Labels)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{": start clear", "test.8o:1: the program has no main label"},
+		{": main\n\tjump nowhere", "test.8o:2: undefined label nowhere"},
+		{": main\n\n\tv1 := 256", "test.8o:3: 256 does not fit in 8 bits"},
+		{": main\n\tif v1 == 2 begin\n\tclear", "test.8o:2: begin without end"},
+		{": main\n\tagain", "test.8o:2: again without loop"},
+		{": main\n: main", "test.8o:2: main is already defined"},
+		{": main\n\tfrob :frob", "test.8o:2: unknown directive :frob"},
+		{": main\n:assert \"too big\" { 2 > 3 }", "test.8o:2: assertion failed: too big"},
+		{": main\n:assert \"100% sure\" { 0 }", "test.8o:2: assertion failed: 100% sure"},
+		{":macro loop-forever { loop-forever }\n: main loop-forever", "test.8o:2: more than 10000 macro expansions, is a macro expanding itself?"},
+	}
+	for _, test := range tests {
+		_, err := Compile("test.8o", []byte(test.src))
+		var e *Error
+		if !errors.As(err, &e) || err.Error() != test.err {
+			t.Errorf("%q: expected %q, got %v instead", test.src, test.err, err)
+		}
+	}
+}
+
+func TestInstall(t *testing.T) {
+	src := `
+: main
+	v0 += 1
+:breakpoint counted
+	v1 := v0
+:monitor main 2
+:monitor main "%x then %i"
+	jump main
+`
+	p := compile(t, src)
+	m, err := chip8.NewFromROM(bytes.NewReader(p.Bytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := debugger.New(m)
+	p.Install(d)
+	d.Continue()
+	for i := 0; i < 10 && !d.Paused(); i++ {
+		m.RunFrame()
+	}
+	if m.PC() != 0x202 || d.Reason() != "breakpoint 1: counted" {
+		t.Errorf("Expected to stop at counted, got %q at 0x%04X instead", d.Reason(), m.PC())
+	}
+	mons := d.Monitors()
+	if len(mons) != 2 || d.ReadMonitor(mons[0]) != "main: 70 01" || d.ReadMonitor(mons[1]) != "main: 70 then 1" {
+		t.Errorf("Expected two monitors of main, got %v instead", mons)
+	}
+}
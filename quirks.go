@@ -0,0 +1,142 @@
+package chip8
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LoadStore is how FX55 and FX65 leave the I register
+type LoadStore int
+
+const (
+	// I is left pointing after the last register, I + X + 1
+	LoadStoreIncrement LoadStore = iota
+	// I is left pointing at the last register, I + X
+	LoadStoreIncrementX
+	// I is left unchanged
+	LoadStoreKeep
+)
+
+// Quirks selects between the interpretations of instructions that differ
+// between Chip-8 implementations. Programs are usually written against one
+// platform and misbehave on the others.
+type Quirks struct {
+	// 8XY6 and 8XYE shift VX in place instead of shifting VY into VX
+	ShiftVX bool
+	// How FX55 and FX65 change I
+	LoadStore LoadStore
+	// BXNN jumps to XNN + VX instead of BNNN jumping to NNN + V0
+	JumpVX bool
+	// 8XY1, 8XY2 and 8XY3 set VF to 0
+	VFReset bool
+	// DXYN waits for the next frame, so at most one sprite is drawn per frame
+	DisplayWait bool
+	// Sprites are cut off at the edges of the screen instead of wrapping
+	// around to the other side
+	Clip bool
+	// DXYN in high resolution mode sets VF to the number of sprite rows
+	// that hit a lit pixel or are cut off at the bottom of the screen
+	// instead of to 1
+	CollisionRows bool
+}
+
+// Quirks of the original COSMAC VIP interpreter
+var QuirksCOSMAC = Quirks{
+	ShiftVX:       false,
+	LoadStore:     LoadStoreIncrement,
+	JumpVX:        false,
+	VFReset:       true,
+	DisplayWait:   true,
+	Clip:          true,
+	CollisionRows: false,
+}
+
+// Quirks of CHIP-48 on the HP-48
+var QuirksCHIP48 = Quirks{
+	ShiftVX:       true,
+	LoadStore:     LoadStoreIncrementX,
+	JumpVX:        true,
+	VFReset:       false,
+	DisplayWait:   false,
+	Clip:          true,
+	CollisionRows: false,
+}
+
+// Quirks of SUPER-CHIP 1.0
+var QuirksSCHIP10 = Quirks{
+	ShiftVX:       true,
+	LoadStore:     LoadStoreIncrementX,
+	JumpVX:        true,
+	VFReset:       false,
+	DisplayWait:   false,
+	Clip:          true,
+	CollisionRows: false,
+}
+
+// Quirks of SUPER-CHIP 1.1
+var QuirksSCHIP11 = Quirks{
+	ShiftVX:       true,
+	LoadStore:     LoadStoreKeep,
+	JumpVX:        true,
+	VFReset:       false,
+	DisplayWait:   false,
+	Clip:          true,
+	CollisionRows: true,
+}
+
+// Quirks of XO-CHIP as implemented by Octo
+var QuirksXOCHIP = Quirks{
+	ShiftVX:       false,
+	LoadStore:     LoadStoreIncrement,
+	JumpVX:        false,
+	VFReset:       false,
+	DisplayWait:   false,
+	Clip:          false,
+	CollisionRows: false,
+}
+
+// Quirks used when none are configured, the behavior this emulator has
+// always had
+var DefaultQuirks = QuirksXOCHIP
+
+// Named quirk profiles
+var QuirkProfiles = map[string]Quirks{
+	"default":  DefaultQuirks,
+	"cosmac":   QuirksCOSMAC,
+	"chip48":   QuirksCHIP48,
+	"schip1.0": QuirksSCHIP10,
+	"schip1.1": QuirksSCHIP11,
+	"xochip":   QuirksXOCHIP,
+}
+
+// Returns the quirk profile with the given name from QuirkProfiles
+func ParseQuirks(name string) (Quirks, error) {
+	q, ok := QuirkProfiles[strings.ToLower(name)]
+	if !ok {
+		return Quirks{}, fmt.Errorf("chip8: unknown quirk profile %q, expected one of %s", name, strings.Join(QuirkProfileNames(), ", "))
+	}
+	return q, nil
+}
+
+// Returns the names of the quirk profiles in alphabetical order
+func QuirkProfileNames() []string {
+	var names []string
+	for name := range QuirkProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Interprets ambiguous instructions according to q, DefaultQuirks if not set
+func WithQuirks(q Quirks) Option {
+	return func(c8 *Machine) {
+		c8.quirks = q
+	}
+}
+
+// Returns the quirks the machine runs with
+func (c8 *Machine) Quirks() Quirks {
+	return c8.quirks
+}
@@ -0,0 +1,188 @@
+package chip8
+
+import (
+	"testing"
+)
+
+func TestQuirkShift(t *testing.T) {
+	c8 := New(WithQuirks(Quirks{ShiftVX: true}))
+	c8.reg[0] = 0x81
+	c8.reg[0xA] = 0xFF
+	c8.executeInstruction(0x80A6)
+	if c8.reg[0] != 0x40 || c8.reg[0xF] != 1 {
+		t.Errorf("Expected V0 0x40 VF 1, got 0x%02X and %d instead", c8.reg[0], c8.reg[0xF])
+	}
+	c8.reg[0] = 0x81
+	c8.executeInstruction(0x80AE)
+	if c8.reg[0] != 0x02 || c8.reg[0xF] != 1 {
+		t.Errorf("Expected V0 0x02 VF 1, got 0x%02X and %d instead", c8.reg[0], c8.reg[0xF])
+	}
+}
+
+func TestQuirkLoadStore(t *testing.T) {
+	tests := []struct {
+		loadStore LoadStore
+		i         uint16
+	}{
+		{LoadStoreIncrement, 0x304},
+		{LoadStoreIncrementX, 0x303},
+		{LoadStoreKeep, 0x300},
+	}
+	for _, test := range tests {
+		c8 := New(WithQuirks(Quirks{LoadStore: test.loadStore}))
+		c8.i = 0x300
+		c8.executeInstruction(0xF355)
+		if c8.i != test.i {
+			t.Errorf("FX55: expected I of 0x%04X, got 0x%04X instead", test.i, c8.i)
+		}
+		c8.i = 0x300
+		c8.executeInstruction(0xF365)
+		if c8.i != test.i {
+			t.Errorf("FX65: expected I of 0x%04X, got 0x%04X instead", test.i, c8.i)
+		}
+	}
+}
+
+func TestQuirkJump(t *testing.T) {
+	c8 := New(WithQuirks(Quirks{JumpVX: true}))
+	c8.reg[0] = 0x01
+	c8.reg[2] = 0x10
+	c8.executeInstruction(0xB234)
+	if c8.pc != 0x244 {
+		t.Errorf("Expected pc of 0x244, got 0x%04X instead", c8.pc)
+	}
+}
+
+func TestQuirkVFReset(t *testing.T) {
+	for _, inst := range []uint16{0x8011, 0x8012, 0x8013} {
+		c8 := New(WithQuirks(QuirksCOSMAC))
+		c8.reg[0xF] = 1
+		c8.executeInstruction(inst)
+		if c8.reg[0xF] != 0 {
+			t.Errorf("%04X: expected VF to be reset, got %d instead", inst, c8.reg[0xF])
+		}
+
+		c8 = New(WithQuirks(QuirksSCHIP11))
+		c8.reg[0xF] = 1
+		c8.executeInstruction(inst)
+		if c8.reg[0xF] != 1 {
+			t.Errorf("%04X: expected VF to be kept, got %d instead", inst, c8.reg[0xF])
+		}
+	}
+}
+
+func TestQuirkDisplayWait(t *testing.T) {
+	// SPRITE V0 V0 1, JUMP 0x200
+	rom := []uint8{0xD0, 0x01, 0x12, 0x00}
+	c8 := New(WithQuirks(QuirksCOSMAC), WithInstructionsPerFrame(10))
+	c8.LoadROMBytes(rom)
+	c8.RunFrame()
+	if c8.Cycles() != 1 {
+		t.Errorf("Expected the frame to end after the sprite, got %d cycles instead", c8.Cycles())
+	}
+
+	c8 = New(WithQuirks(QuirksCHIP48), WithInstructionsPerFrame(10))
+	c8.LoadROMBytes(rom)
+	c8.RunFrame()
+	if c8.Cycles() != 10 {
+		t.Errorf("Expected a full frame, got %d cycles instead", c8.Cycles())
+	}
+}
+
+func TestQuirkClip(t *testing.T) {
+	c8 := New(WithQuirks(Quirks{Clip: true}))
+	c8.memory[0x300] = 0xFF
+	c8.memory[0x301] = 0xFF
+	c8.i = 0x300
+	c8.reg[0] = 60
+	c8.reg[1] = 31
+	c8.executeInstruction(0xD012)
+	if !c8.graphics.Pixel(63, 31) || c8.graphics.Pixel(0, 31) || c8.graphics.Pixel(60, 0) {
+		t.Errorf("Expected the sprite to be clipped at the edges")
+	}
+
+	c8 = New(WithQuirks(Quirks{Clip: false}))
+	c8.memory[0x300] = 0xFF
+	c8.memory[0x301] = 0xFF
+	c8.i = 0x300
+	c8.reg[0] = 60
+	c8.reg[1] = 31
+	c8.executeInstruction(0xD012)
+	if !c8.graphics.Pixel(63, 31) || !c8.graphics.Pixel(3, 31) || !c8.graphics.Pixel(60, 0) || !c8.graphics.Pixel(3, 0) {
+		t.Errorf("Expected the sprite to wrap around the edges")
+	}
+}
+
+func TestQuirkCollisionRows(t *testing.T) {
+	// VF after drawing a bar of 4 rows at (5, 62) over one at (5, 60), in
+	// high and low resolution where the bars wrap to (5, 30) and (5, 28)
+	tests := []struct {
+		profile     string
+		hires, lore uint8
+	}{
+		{"cosmac", 1, 1},
+		{"chip48", 1, 1},
+		{"schip1.0", 1, 1},
+		{"schip1.1", 4, 1},
+		{"xochip", 1, 1},
+	}
+	for _, test := range tests {
+		q, _ := ParseQuirks(test.profile)
+		for _, hires := range []bool{true, false} {
+			c8 := New(WithVariant(VariantSCHIP), WithQuirks(q))
+			if hires {
+				c8.executeInstruction(0x00FF)
+			}
+			copy(c8.memory[0x300:], []uint8{0x80, 0x80, 0x80, 0x80})
+			c8.i = 0x300
+			c8.reg[0] = 5
+			c8.reg[1] = 60
+			c8.reg[2] = 62
+			c8.executeInstruction(0xD014)
+			c8.executeInstruction(0xD024)
+			expected := test.lore
+			if hires {
+				expected = test.hires
+			}
+			if c8.reg[0xF] != expected {
+				t.Errorf("%s, hires %v: expected VF of %d, got %d instead", test.profile, hires, expected, c8.reg[0xF])
+			}
+		}
+	}
+}
+
+func TestFlagWrittenLast(t *testing.T) {
+	tests := []struct {
+		inst uint16
+		x, y uint8
+		vf   uint8
+	}{
+		{0x8F04, 0xFF, 0x01, 1},
+		{0x8F05, 0x01, 0x02, 0},
+		{0x8F06, 0x03, 0x03, 1},
+		{0x8F07, 0x02, 0x01, 0},
+		{0x8F0E, 0x80, 0x80, 1},
+	}
+	for _, test := range tests {
+		c8 := New()
+		c8.reg[0xF] = test.x
+		c8.reg[0x0] = test.y
+		c8.executeInstruction(test.inst)
+		if c8.reg[0xF] != test.vf {
+			t.Errorf("%04X: expected VF of %d, got %d instead", test.inst, test.vf, c8.reg[0xF])
+		}
+	}
+}
+
+func TestParseQuirks(t *testing.T) {
+	q, err := ParseQuirks("SCHIP1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q != QuirksSCHIP11 {
+		t.Errorf("Expected the SUPER-CHIP 1.1 quirks, got %+v instead", q)
+	}
+	if _, err := ParseQuirks("nope"); err == nil {
+		t.Errorf("Expected an error for an unknown profile")
+	}
+}
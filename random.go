@@ -0,0 +1,169 @@
+package chip8
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// RandomSource produces the random numbers of CXNN. Each machine owns its
+// source, so machines don't share random state.
+type RandomSource interface {
+	// Starts the numbers over from seed. Called on every reset, and to
+	// restore a saved state. Sources that can't repeat themselves ignore
+	// it.
+	Seed(seed int64)
+	// Returns the next random byte
+	Byte() uint8
+	// Returns the name of the source in RandomSources, or an empty name
+	// for sources that aren't in it
+	Name() string
+}
+
+// Named random sources, for picking one on the command line
+var RandomSources = map[string]func() RandomSource{
+	"seeded": func() RandomSource { return new(SeededSource) },
+	"fixed":  func() RandomSource { return new(FixedSource) },
+	"crypto": func() RandomSource { return new(CryptoSource) },
+}
+
+// Returns a new random source of the kind called name from RandomSources
+func ParseRandomSource(name string) (RandomSource, error) {
+	newSource, ok := RandomSources[strings.ToLower(name)]
+	if !ok {
+		var names []string
+		for n := range RandomSources {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("chip8: unknown random source %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return newSource(), nil
+}
+
+// Draws the random numbers of CXNN from src instead of a SeededSource
+func WithRandomSource(src RandomSource) Option {
+	return func(c8 *Machine) {
+		c8.random = src
+	}
+}
+
+// Seeds the random source with seed instead of one taken from the time, so
+// that runs with the same input are the same
+func WithSeed(seed int64) Option {
+	return func(c8 *Machine) {
+		c8.seed = seed
+	}
+}
+
+// Returns the seed of the random source, which starts over from it on
+// every reset
+func (c8 *Machine) Seed() int64 {
+	return c8.seed
+}
+
+// Returns a random byte for CXNN, recording it for rewinding. The bytes
+// drawn are counted so that restoring a state can put the source back where
+// it was.
+func (c8 *Machine) randomByte() uint8 {
+	b := c8.random.Byte()
+	c8.randomDraws++
+	if c8.rewind != nil {
+		return c8.rewind.randomByte(b)
+	}
+	return b
+}
+
+// Moves the random source to where it was after draws bytes since the
+// last reset, seeding it again to go back
+func (c8 *Machine) seekRandom(draws uint64) {
+	if draws < c8.randomDraws {
+		c8.random.Seed(c8.seed)
+		c8.randomDraws = 0
+	}
+	for ; c8.randomDraws < draws; c8.randomDraws++ {
+		c8.random.Byte()
+	}
+}
+
+// SeededSource is a RandomSource drawing from a math/rand generator. The
+// same seed gives the same numbers.
+type SeededSource struct {
+	rng *rand.Rand
+}
+
+// Starts the numbers over from seed
+func (s *SeededSource) Seed(seed int64) {
+	s.rng = rand.New(rand.NewSource(seed))
+}
+
+// Returns the next random byte, seeding with 0 if Seed wasn't called
+func (s *SeededSource) Byte() uint8 {
+	if s.rng == nil {
+		s.Seed(0)
+	}
+	return uint8(s.rng.Int())
+}
+
+// Returns "seeded"
+func (s *SeededSource) Name() string {
+	return "seeded"
+}
+
+// FixedSource is a RandomSource returning Bytes in order and starting over
+// at the end, for tests. Seed starts it over at the first byte. Without
+// bytes it returns zeros.
+type FixedSource struct {
+	Bytes []uint8
+	pos   int
+}
+
+// Starts the bytes over, whatever the seed
+func (s *FixedSource) Seed(seed int64) {
+	s.pos = 0
+}
+
+// Returns the next byte
+func (s *FixedSource) Byte() uint8 {
+	if len(s.Bytes) == 0 {
+		return 0
+	}
+	b := s.Bytes[s.pos%len(s.Bytes)]
+	s.pos = (s.pos + 1) % len(s.Bytes)
+	return b
+}
+
+// Returns "fixed"
+func (s *FixedSource) Name() string {
+	return "fixed"
+}
+
+// CryptoSource is a RandomSource reading crypto/rand. Its numbers can't be
+// repeated: runs using it can't be replayed, and restoring a state carries
+// on with new numbers.
+type CryptoSource struct {
+	buf [256]uint8
+	pos int
+}
+
+// Does nothing, the numbers can't be repeated
+func (s *CryptoSource) Seed(seed int64) {}
+
+// Returns the next random byte, read from crypto/rand a block at a time
+func (s *CryptoSource) Byte() uint8 {
+	if s.pos == 0 {
+		// Reading the system's random numbers doesn't fail on the systems
+		// Go supports
+		crand.Read(s.buf[:])
+	}
+	b := s.buf[s.pos]
+	s.pos = (s.pos + 1) % len(s.buf)
+	return b
+}
+
+// Returns "crypto"
+func (s *CryptoSource) Name() string {
+	return "crypto"
+}
@@ -0,0 +1,109 @@
+package chip8
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// Reads n bytes from src after seeding it
+func randomBytes(src RandomSource, seed int64, n int) []uint8 {
+	src.Seed(seed)
+	b := make([]uint8, n)
+	for i := range b {
+		b[i] = src.Byte()
+	}
+	return b
+}
+
+func TestRandomSources(t *testing.T) {
+	src, err := ParseRandomSource("seeded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := randomBytes(src, 99, 64), randomBytes(src, 99, 64)
+	if !bytes.Equal(a, b) {
+		t.Errorf("Expected the seeded numbers to repeat after seeding again")
+	}
+	if bytes.Equal(a, randomBytes(src, 100, 64)) {
+		t.Errorf("Expected other numbers for another seed")
+	}
+	for name, newSource := range RandomSources {
+		if src := newSource(); src.Name() != name {
+			t.Errorf("Expected %s to be called %q, got %q instead", name, name, src.Name())
+		}
+	}
+	if _, err := ParseRandomSource("dice"); err == nil {
+		t.Errorf("Expected an error for an unknown random source")
+	}
+
+	fixed := &FixedSource{Bytes: []uint8{1, 2, 3}}
+	if b := randomBytes(fixed, 0, 5); !bytes.Equal(b, []uint8{1, 2, 3, 1, 2}) {
+		t.Errorf("Expected the fixed bytes in a loop, got %v instead", b)
+	}
+	if b := randomBytes(new(CryptoSource), 0, 300); bytes.Equal(b, make([]uint8, 300)) {
+		t.Errorf("Expected random bytes from crypto/rand")
+	}
+}
+
+func TestRandomSourceCXNN(t *testing.T) {
+	c8 := New(WithRandomSource(&FixedSource{Bytes: []uint8{0xAB, 0xFF}}))
+	c8.executeInstruction(0xC0F0)
+	c8.executeInstruction(0xC10F)
+	if c8.reg[0] != 0xA0 || c8.reg[1] != 0x0F {
+		t.Errorf("Expected V0=A0 and V1=0F, got %02X and %02X instead", c8.reg[0], c8.reg[1])
+	}
+
+	// Machines don't share their numbers
+	a, b := New(WithSeed(1)), New(WithSeed(1))
+	a.executeInstruction(0xC0FF)
+	a.executeInstruction(0xC0FF)
+	b.executeInstruction(0xC0FF)
+	a.Reset()
+	a.executeInstruction(0xC0FF)
+	if a.reg[0] != b.reg[0] {
+		t.Errorf("Expected the same first number, got %02X and %02X instead", a.reg[0], b.reg[0])
+	}
+}
+
+func TestRandomRestore(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader(rewindROM), WithSeed(3), WithRewind(10, 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved bytes.Buffer
+	runRewindFrames(t, c8, 20)
+	if err := c8.SaveState(&saved); err != nil {
+		t.Fatal(err)
+	}
+	for f := 0; f < 30; f++ {
+		c8.RunFrame()
+	}
+	expected := c8.Snapshot()
+
+	// The numbers carry on from where they were at the frame rewound to
+	if err := c8.Rewind(15); err != nil {
+		t.Fatal(err)
+	}
+	for f := 0; f < 15; f++ {
+		c8.RunFrame()
+	}
+	if !reflect.DeepEqual(c8.Snapshot(), expected) {
+		t.Errorf("Expected the same frames after rewinding")
+	}
+
+	// and from where they were when the state was saved, with its seed
+	other, _ := NewFromROM(bytes.NewReader(rewindROM), WithSeed(4))
+	if err := other.LoadState(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if other.Seed() != 3 {
+		t.Errorf("Expected the seed of the state, got %d instead", other.Seed())
+	}
+	for f := 0; f < 30; f++ {
+		other.RunFrame()
+	}
+	if !reflect.DeepEqual(other.Snapshot(), expected) {
+		t.Errorf("Expected the same frames after loading the state")
+	}
+}
@@ -0,0 +1,372 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+)
+
+// RecordFormat is the animation format a Recorder writes
+type RecordFormat int
+
+const (
+	// Animated GIF
+	RecordGIF RecordFormat = iota
+	// Animated PNG
+	RecordAPNG
+)
+
+// Returns the recording format called name: gif or apng
+func ParseRecordFormat(name string) (RecordFormat, error) {
+	switch name {
+	case "gif":
+		return RecordGIF, nil
+	case "apng", "png":
+		return RecordAPNG, nil
+	}
+	return 0, fmt.Errorf("chip8: unknown recording format %q", name)
+}
+
+// Records the screen at the end of every frame to r. Frames run again
+// while rewinding are not recorded.
+func WithRecorder(r *Recorder) Option {
+	return func(c8 *Machine) {
+		c8.recorder = r
+	}
+}
+
+// Sets the recorder, nil to stop recording
+func (c8 *Machine) SetRecorder(r *Recorder) {
+	c8.recorder = r
+}
+
+// Recorder turns the screens of consecutive frames into an animation.
+// Frames the same as the one before are merged into it, and each frame
+// only holds the part of the screen that changed, which keeps recordings
+// of most programs small. The animation is 128x64 pixels times the scale
+// whatever the resolution, with low resolution pixels twice as big.
+//
+// A frame is encoded as soon as the next different screen is captured. A
+// GIF is written as it goes, while an APNG starts with its number of
+// frames, so its compressed frames are kept until Close. GIF counts time
+// in hundredths of a second and viewers slow down frames shorter than two,
+// so a GIF shows at most 50 frames per second, dropping the frames that
+// would be shown for less.
+type Recorder struct {
+	w       io.Writer
+	scale   int
+	palette color.Palette
+	enc     frameEncoder
+	// The screen last captured and the number of frames it was shown for,
+	// encoded once a different screen is captured
+	last     Framebuffer
+	duration int
+	frames   int
+	err      error
+}
+
+// Encodes the frames of an animation one at a time
+type frameEncoder interface {
+	// Adds img, shown for duration 60ths of a second
+	add(img *image.Paletted, duration int) error
+	// Writes what is left of the animation
+	close() error
+}
+
+// Returns a Recorder writing to w in the given format, each pixel of the
+// high resolution screen a square of scale by scale pixels. An empty
+// palette is DefaultPalette, see Framebuffer.Image.
+func NewRecorder(w io.Writer, format RecordFormat, scale int, palette color.Palette) *Recorder {
+	if len(palette) == 0 {
+		palette = DefaultPalette
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	r := &Recorder{w: w, scale: scale, palette: palette}
+	if format == RecordGIF {
+		r.enc = &gifEncoder{w: w}
+	} else {
+		r.enc = &apngEncoder{w: w}
+	}
+	return r
+}
+
+// Adds the screen fb, shown for a 60th of a second
+func (r *Recorder) Capture(fb *Framebuffer) {
+	r.frames++
+	if r.duration > 0 && r.last.hires == fb.hires && r.last.buffer == fb.buffer {
+		r.duration++
+		return
+	}
+	r.flush()
+	r.last = *fb
+	r.duration = 1
+}
+
+// Encodes the screen last captured, keeping the first error
+func (r *Recorder) flush() {
+	if r.duration == 0 || r.err != nil {
+		return
+	}
+	scale := r.scale
+	if !r.last.hires {
+		scale *= 2
+	}
+	r.err = r.enc.add(r.last.Image(scale, r.palette), r.duration)
+}
+
+// Returns the number of frames captured, counting those merged into the
+// frame before
+func (r *Recorder) Frames() int {
+	return r.frames
+}
+
+// Finishes the animation, returning the first error writing it. The
+// recorder must not be used afterwards, and w is left open.
+func (r *Recorder) Close() error {
+	if r.frames == 0 {
+		return fmt.Errorf("chip8: nothing was recorded")
+	}
+	r.flush()
+	if r.err != nil {
+		return r.err
+	}
+	return r.enc.close()
+}
+
+// Returns the smallest rectangle holding the pixels of img that differ from
+// prev, a single pixel if none do
+func changedBounds(prev, img *image.Paletted) image.Rectangle {
+	var changed image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row, prevRow := img.Pix[y*img.Stride:], prev.Pix[y*prev.Stride:]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if row[x] != prevRow[x] {
+				changed = changed.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if changed.Empty() {
+		return image.Rect(0, 0, 1, 1)
+	}
+	return changed
+}
+
+// Writes frames as a looping GIF. Each frame is encoded by image/gif on
+// its own, and its blocks copied into the animation.
+type gifEncoder struct {
+	w io.Writer
+	// The frame waiting to be shown, replaced by the next one while it
+	// would be shown for less than two hundredths, and what is on screen
+	// before it
+	pending, shown *image.Paletted
+	// 60ths of a second before the pending frame and after it
+	start, t int
+}
+
+// Time in hundredths of a second after t 60ths
+func hundredths(t int) int {
+	return (t*100 + 30) / 60
+}
+
+func (e *gifEncoder) add(img *image.Paletted, duration int) error {
+	if e.pending != nil && hundredths(e.t)-hundredths(e.start) >= 2 {
+		if err := e.emit(); err != nil {
+			return err
+		}
+		e.start = e.t
+	}
+	e.pending = img
+	e.t += duration
+	return nil
+}
+
+func (e *gifEncoder) close() error {
+	if err := e.emit(); err != nil {
+		return err
+	}
+	_, err := e.w.Write([]byte{0x3B})
+	return err
+}
+
+// Writes the pending frame, shown until the current time, after the header
+// if it is the first
+func (e *gifEncoder) emit() error {
+	delay := hundredths(e.t) - hundredths(e.start)
+	if delay < 2 {
+		delay = 2
+	}
+	img := e.pending
+	if e.shown != nil {
+		img = img.SubImage(changedBounds(e.shown, img)).(*image.Paletted)
+	}
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:    []*image.Paletted{img},
+		Delay:    []int{delay},
+		Disposal: []byte{gif.DisposalNone},
+		Config: image.Config{
+			ColorModel: e.pending.Palette,
+			Width:      e.pending.Rect.Dx(),
+			Height:     e.pending.Rect.Dy(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	// The signature, screen descriptor and global palette come before the
+	// frame, and the trailer after it
+	file := buf.Bytes()
+	header := 13
+	if flags := file[10]; flags&0x80 != 0 {
+		header += 3 << (flags&0x07 + 1)
+	}
+	if e.shown == nil {
+		if _, err := e.w.Write(file[:header]); err != nil {
+			return err
+		}
+		// Loops forever
+		loop := []byte{0x21, 0xFF, 0x0B, 'N', 'E', 'T', 'S', 'C', 'A', 'P', 'E', '2', '.', '0', 0x03, 0x01, 0x00, 0x00, 0x00}
+		if _, err := e.w.Write(loop); err != nil {
+			return err
+		}
+	}
+	e.shown = e.pending
+	_, err = e.w.Write(file[header : len(file)-1])
+	return err
+}
+
+// Writes frames as a looping APNG. Each frame is encoded by image/png on
+// its own, and its image data moved into the animation chunks.
+type apngEncoder struct {
+	w io.Writer
+	// Chunks of the first frame before its image data, which are the
+	// animation's
+	header []pngChunk
+	// Chunks of the frames, written after the frame count
+	frames bytes.Buffer
+	count  uint32
+	seq    uint32
+	prev   *image.Paletted
+}
+
+func (e *apngEncoder) add(frame *image.Paletted, duration int) error {
+	img := frame
+	if e.prev != nil {
+		img = frame.SubImage(changedBounds(e.prev, frame)).(*image.Paletted)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	chunks, err := pngChunks(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	if e.prev == nil {
+		for _, c := range chunks {
+			if c.kind != "IDAT" && c.kind != "IEND" {
+				e.header = append(e.header, c)
+			}
+		}
+	}
+
+	if duration > 0xFFFF {
+		duration = 0xFFFF
+	}
+	b := img.Bounds()
+	var fctl [26]byte
+	binary.BigEndian.PutUint32(fctl[0:], e.seq)
+	binary.BigEndian.PutUint32(fctl[4:], uint32(b.Dx()))
+	binary.BigEndian.PutUint32(fctl[8:], uint32(b.Dy()))
+	binary.BigEndian.PutUint32(fctl[12:], uint32(b.Min.X))
+	binary.BigEndian.PutUint32(fctl[16:], uint32(b.Min.Y))
+	binary.BigEndian.PutUint16(fctl[20:], uint16(duration))
+	binary.BigEndian.PutUint16(fctl[22:], FrameRate)
+	// Leaves the frame in place and replaces the pixels under it
+	fctl[24], fctl[25] = 0, 0
+	writePNGChunk(&e.frames, "fcTL", fctl[:])
+	e.seq++
+
+	for _, c := range chunks {
+		if c.kind != "IDAT" {
+			continue
+		}
+		if e.prev == nil {
+			writePNGChunk(&e.frames, "IDAT", c.data)
+			continue
+		}
+		fdat := make([]byte, 4+len(c.data))
+		binary.BigEndian.PutUint32(fdat, e.seq)
+		copy(fdat[4:], c.data)
+		writePNGChunk(&e.frames, "fdAT", fdat)
+		e.seq++
+	}
+	e.prev = frame
+	e.count++
+	return nil
+}
+
+func (e *apngEncoder) close() error {
+	var out bytes.Buffer
+	out.WriteString("\x89PNG\r\n\x1a\n")
+	for _, c := range e.header {
+		writePNGChunk(&out, c.kind, c.data)
+		if c.kind == "IHDR" {
+			var actl [8]byte
+			binary.BigEndian.PutUint32(actl[0:], e.count)
+			writePNGChunk(&out, "acTL", actl[:])
+		}
+	}
+	if _, err := out.WriteTo(e.w); err != nil {
+		return err
+	}
+	writePNGChunk(&e.frames, "IEND", nil)
+	_, err := e.frames.WriteTo(e.w)
+	return err
+}
+
+// A chunk of a PNG file
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+// Splits a PNG file into its chunks
+func pngChunks(file []byte) ([]pngChunk, error) {
+	var chunks []pngChunk
+	for p := 8; p < len(file); {
+		if p+12 > len(file) {
+			return nil, fmt.Errorf("chip8: PNG chunk cut off")
+		}
+		n := int(binary.BigEndian.Uint32(file[p:]))
+		if p+12+n > len(file) {
+			return nil, fmt.Errorf("chip8: PNG chunk cut off")
+		}
+		chunks = append(chunks, pngChunk{kind: string(file[p+4 : p+8]), data: file[p+8 : p+8+n]})
+		p += 12 + n
+	}
+	return chunks, nil
+}
+
+// Writes a PNG chunk with its length and checksum
+func writePNGChunk(w *bytes.Buffer, kind string, data []byte) {
+	var word [4]byte
+	binary.BigEndian.PutUint32(word[:], uint32(len(data)))
+	w.Write(word[:])
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+	w.WriteString(kind)
+	w.Write(data)
+	binary.BigEndian.PutUint32(word[:], crc.Sum32())
+	w.Write(word[:])
+}
@@ -0,0 +1,134 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func TestRecorderMachine(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf, RecordGIF, 1, nil)
+	// Draws a pixel once and loops
+	rom := []byte{0xA2, 0x06, 0xD0, 0x01, 0x12, 0x04, 0x80}
+	c8, err := NewFromROM(bytes.NewReader(rom), WithRecorder(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := c8.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c8.SetRecorder(nil)
+	c8.RunFrame()
+	if r.Frames() != 10 || r.duration != 10 {
+		t.Errorf("Expected 10 frames merged into one, got %d in %d instead", r.Frames(), r.duration)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 1 || g.Delay[0] != 17 || g.Config.Width != 128 || g.Config.Height != 64 {
+		t.Errorf("Expected one 128x64 frame for 17 hundredths, got %d frames, %v and %dx%d instead", len(g.Image), g.Delay, g.Config.Width, g.Config.Height)
+	}
+	// Low resolution pixels are twice as big
+	if g.Image[0].ColorIndexAt(1, 1) != 1 || g.Image[0].ColorIndexAt(2, 0) != 0 {
+		t.Errorf("Expected the pixel drawn as a 2x2 square")
+	}
+}
+
+// Returns four different screens with a pixel lit in a different column
+func recorderScreens() []*Framebuffer {
+	var screens []*Framebuffer
+	for x := 0; x < 4; x++ {
+		fb := new(Framebuffer)
+		fb.reset()
+		fb.drawSprite(uint8(x), 0, 1, []uint8{0x80}, spriteMode{})
+		screens = append(screens, fb)
+	}
+	return screens
+}
+
+func TestRecorderGIF(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf, RecordGIF, 1, nil)
+	screens := recorderScreens()
+	for n, duration := range []int{1, 1, 1, 3} {
+		for i := 0; i < duration; i++ {
+			r.Capture(screens[n])
+		}
+	}
+	// Frames are written as they are known, not kept until the end
+	if buf.Len() == 0 {
+		t.Errorf("Expected the first frames written before closing")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second screen would show for a hundredth only, so it is dropped
+	if len(g.Image) != 3 || g.Delay[0] != 2 || g.Delay[1] != 3 || g.Delay[2] != 5 {
+		t.Fatalf("Expected 3 frames for 2, 3 and 5 hundredths, got %v instead", g.Delay)
+	}
+	// Later frames only hold the pixels that changed: the first pixel going
+	// off and the third coming on
+	if b := g.Image[1].Bounds(); b != image.Rect(0, 0, 6, 2) {
+		t.Errorf("Expected the second frame to cover the change, got %v instead", b)
+	}
+}
+
+func TestRecorderAPNG(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf, RecordAPNG, 2, nil)
+	screens := recorderScreens()
+	for n, duration := range []int{1, 1, 1, 3} {
+		for i := 0; i < duration; i++ {
+			r.Capture(screens[n])
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Viewers without APNG support show the first frame
+	first, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Bounds() != image.Rect(0, 0, 256, 128) {
+		t.Errorf("Expected a 256x128 image, got %v instead", first.Bounds())
+	}
+
+	chunks, err := pngChunks(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	var delays []uint16
+	for _, c := range chunks {
+		kinds = append(kinds, c.kind)
+		switch c.kind {
+		case "acTL":
+			if frames := binary.BigEndian.Uint32(c.data); frames != 4 {
+				t.Errorf("Expected 4 frames, got %d instead", frames)
+			}
+		case "fcTL":
+			delays = append(delays, binary.BigEndian.Uint16(c.data[20:]))
+		}
+	}
+	if kinds[0] != "IHDR" || kinds[1] != "acTL" || kinds[len(kinds)-1] != "IEND" {
+		t.Errorf("Expected IHDR and acTL first and IEND last, got %v instead", kinds)
+	}
+	if len(delays) != 4 || delays[0] != 1 || delays[3] != 3 {
+		t.Errorf("Expected delays of 1, 1, 1 and 3 60ths, got %v instead", delays)
+	}
+}
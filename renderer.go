@@ -0,0 +1,15 @@
+package chip8
+
+// Renderer presents the framebuffer to the user. The machine calls Render
+// after an instruction has changed the screen; the framebuffer must not be
+// retained after Render returns since the machine keeps drawing into it.
+type Renderer interface {
+	Render(fb *Framebuffer)
+}
+
+// HeadlessRenderer is a Renderer that discards every frame, used when the
+// machine runs without a display such as in tests and batch jobs.
+type HeadlessRenderer struct{}
+
+// Does nothing
+func (HeadlessRenderer) Render(fb *Framebuffer) {}
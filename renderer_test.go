@@ -0,0 +1,38 @@
+package chip8
+
+import (
+	"testing"
+)
+
+type countingRenderer struct {
+	frames int
+}
+
+func (r *countingRenderer) Render(fb *Framebuffer) {
+	r.frames++
+}
+
+func TestRenderer(t *testing.T) {
+	r := new(countingRenderer)
+	c8 := New(WithRenderer(r))
+	// CLS, ADD V0 01, SPRITE V0 V0 1
+	c8.memory[0x200] = 0x00
+	c8.memory[0x201] = 0xE0
+	c8.memory[0x202] = 0x70
+	c8.memory[0x203] = 0x01
+	c8.memory[0x204] = 0xD0
+	c8.memory[0x205] = 0x01
+
+	c8.RunFor(1)
+	if r.frames != 1 {
+		t.Errorf("Expected 1 frame after CLS, got %d instead", r.frames)
+	}
+	c8.RunFor(1)
+	if r.frames != 1 {
+		t.Errorf("Expected no frame after ADD, got %d instead", r.frames)
+	}
+	c8.RunFor(1)
+	if r.frames != 2 {
+		t.Errorf("Expected 2 frames after SPRITE, got %d instead", r.frames)
+	}
+}
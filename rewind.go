@@ -0,0 +1,294 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrRewindUnavailable is returned when rewinding further back than the
+// rewind history goes, or without WithRewind
+var ErrRewindUnavailable = errors.New("chip8: not enough rewind history")
+
+// Bytes a recorded key change is counted as against the memory budget
+const keyRecordSize = 16
+
+// Keeps a snapshot of the machine every interval frames so that Rewind can
+// go back to any of the frames since the oldest snapshot. Snapshots are
+// kept until they take more than budget bytes, the newest one is always
+// kept. The key changes and random numbers the program sees are recorded
+// too, and replayed when rewinding to a frame between two snapshots.
+//
+// Only frames run with RunFrame, directly or by a Scheduler, can be
+// rewound to.
+func WithRewind(interval int, budget int) Option {
+	return func(c8 *Machine) {
+		if interval < 1 {
+			interval = 1
+		}
+		c8.rewind = &rewindBuffer{interval: uint64(interval), budget: budget}
+	}
+}
+
+// A change of a key, applied before the instruction at cycle
+type keyRecord struct {
+	cycle   uint64
+	key     uint8
+	pressed bool
+}
+
+// A snapshot of the machine at the start of a frame
+type rewindSnapshot struct {
+	frames uint64
+	// The state in the save state format for the newest snapshot. Older
+	// snapshots are delta encoded against the next newer one, see
+	// deltaEncode.
+	data []byte
+	// Length of the input logs when the snapshot was taken
+	keyPos  int
+	randPos int
+}
+
+// History of a machine for Rewind
+type rewindBuffer struct {
+	interval uint64
+	budget   int
+	// Oldest first
+	snapshots []rewindSnapshot
+	// Bytes used by the snapshots and logs
+	size int
+	// Key changes and CXNN random numbers since the oldest snapshot
+	keys   []keyRecord
+	random []uint8
+	// Set while frames are replayed, with the next log entries to replay
+	replaying bool
+	keyPos    int
+	randPos   int
+}
+
+// Rewinds the machine by the given number of frames. The nearest snapshot
+// at or before that frame is restored and the frames after it are run
+// again with the recorded input, without presenting them. Returns
+// ErrRewindUnavailable if the history doesn't go back that far, leaving
+// the machine unchanged. Everything after the frame rewound to is
+// forgotten.
+func (c8 *Machine) Rewind(frames int) error {
+	rb := c8.rewind
+	if rb == nil || frames < 0 || uint64(frames) > c8.frames {
+		return ErrRewindUnavailable
+	}
+	target := c8.frames - uint64(frames)
+	k := len(rb.snapshots) - 1
+	for k >= 0 && rb.snapshots[k].frames > target {
+		k--
+	}
+	if k < 0 {
+		return ErrRewindUnavailable
+	}
+	s, err := rb.decode(k)
+	if err != nil {
+		return err
+	}
+
+	c8.restore(s)
+	rb.truncate(k)
+	rb.replaying = true
+	rb.keyPos = rb.snapshots[k].keyPos
+	rb.randPos = rb.snapshots[k].randPos
+	for c8.frames < target && err == nil {
+		err = c8.RunFrame()
+	}
+	rb.replaying = false
+	// Forget the input of the frames rewound over
+	rb.keys = rb.keys[:rb.keyPos]
+	rb.random = rb.random[:rb.randPos]
+	rb.measure()
+	if c8.movie != nil && !c8.moviePlaying {
+		c8.truncateMovie()
+	}
+
+	c8.drawFlag = true
+	c8.present()
+	return err
+}
+
+// Reports whether frames are being run again by Rewind
+func (c8 *Machine) replaying() bool {
+	return c8.rewind != nil && c8.rewind.replaying
+}
+
+// Returns the most frames Rewind can currently go back
+func (c8 *Machine) RewindLimit() uint64 {
+	if c8.rewind == nil || len(c8.rewind.snapshots) == 0 {
+		return 0
+	}
+	return c8.frames - c8.rewind.snapshots[0].frames
+}
+
+// Forgets the whole history
+func (rb *rewindBuffer) clear() {
+	rb.snapshots = nil
+	rb.keys = nil
+	rb.random = nil
+	rb.size = 0
+}
+
+// Takes a snapshot of c8 if one is due at the start of the current frame
+func (rb *rewindBuffer) capture(c8 *Machine) {
+	if rb.replaying || c8.frames%rb.interval != 0 {
+		return
+	}
+	n := len(rb.snapshots)
+	if n > 0 && rb.snapshots[n-1].frames == c8.frames {
+		return
+	}
+	var buf bytes.Buffer
+	writeState(&buf, c8.Snapshot())
+	data := buf.Bytes()
+	if n > 0 {
+		newest := &rb.snapshots[n-1]
+		newest.data = deltaEncode(data, newest.data)
+	}
+	rb.snapshots = append(rb.snapshots, rewindSnapshot{
+		frames:  c8.frames,
+		data:    data,
+		keyPos:  len(rb.keys),
+		randPos: len(rb.random),
+	})
+	rb.measure()
+	rb.evict()
+}
+
+// Drops the oldest snapshots and the input before them until the history
+// fits in the budget
+func (rb *rewindBuffer) evict() {
+	drop := 0
+	for rb.size > rb.budget && drop < len(rb.snapshots)-1 {
+		rb.size -= len(rb.snapshots[drop].data)
+		drop++
+	}
+	if drop == 0 {
+		return
+	}
+	rb.snapshots = append(rb.snapshots[:0], rb.snapshots[drop:]...)
+	keyPos, randPos := rb.snapshots[0].keyPos, rb.snapshots[0].randPos
+	rb.keys = append(rb.keys[:0], rb.keys[keyPos:]...)
+	rb.random = append(rb.random[:0], rb.random[randPos:]...)
+	for i := range rb.snapshots {
+		rb.snapshots[i].keyPos -= keyPos
+		rb.snapshots[i].randPos -= randPos
+	}
+	rb.measure()
+}
+
+// Drops the snapshots newer than snapshot k, which becomes the newest
+func (rb *rewindBuffer) truncate(k int) {
+	if k == len(rb.snapshots)-1 {
+		return
+	}
+	data := rb.snapshots[len(rb.snapshots)-1].data
+	for j := len(rb.snapshots) - 2; j >= k; j-- {
+		data, _ = deltaDecode(data, rb.snapshots[j].data)
+	}
+	rb.snapshots[k].data = data
+	rb.snapshots = rb.snapshots[:k+1]
+}
+
+// Recomputes the bytes used by the history
+func (rb *rewindBuffer) measure() {
+	rb.size = len(rb.keys)*keyRecordSize + len(rb.random)
+	for _, s := range rb.snapshots {
+		rb.size += len(s.data)
+	}
+}
+
+// Returns the state of snapshot k
+func (rb *rewindBuffer) decode(k int) (*State, error) {
+	data := rb.snapshots[len(rb.snapshots)-1].data
+	for j := len(rb.snapshots) - 2; j >= k; j-- {
+		var err error
+		if data, err = deltaDecode(data, rb.snapshots[j].data); err != nil {
+			return nil, err
+		}
+	}
+	return readState(bytes.NewReader(data))
+}
+
+// Records a key change
+func (rb *rewindBuffer) recordKey(cycle uint64, key uint8, pressed bool) {
+	rb.keys = append(rb.keys, keyRecord{cycle: cycle, key: key, pressed: pressed})
+	rb.size += keyRecordSize
+}
+
+// Applies the recorded key changes due before the next instruction
+func (rb *rewindBuffer) replayKeys(c8 *Machine) {
+	for rb.keyPos < len(rb.keys) && rb.keys[rb.keyPos].cycle <= c8.cycles {
+		rec := rb.keys[rb.keyPos]
+		c8.key[rec.key] = rec.pressed
+		rb.keyPos++
+	}
+}
+
+// Records the new random byte b and returns it, or returns the next
+// recorded one instead while replaying
+func (rb *rewindBuffer) randomByte(b uint8) uint8 {
+	if rb.replaying && rb.randPos < len(rb.random) {
+		b := rb.random[rb.randPos]
+		rb.randPos++
+		return b
+	}
+	rb.random = append(rb.random, b)
+	rb.randPos = len(rb.random)
+	rb.size++
+	return b
+}
+
+// Returns older as the difference to newer, both of the same length. The
+// bytes that differ are XORed and stored as runs: the number of equal
+// bytes skipped and the number of differing bytes as uvarints, followed by
+// the differing bytes.
+func deltaEncode(newer, older []byte) []byte {
+	var delta []byte
+	var num [binary.MaxVarintLen64]byte
+	for i := 0; i < len(older); {
+		start := i
+		for i < len(older) && older[i] == newer[i] {
+			i++
+		}
+		skip := i - start
+		start = i
+		for i < len(older) && older[i] != newer[i] {
+			i++
+		}
+		delta = append(delta, num[:binary.PutUvarint(num[:], uint64(skip))]...)
+		delta = append(delta, num[:binary.PutUvarint(num[:], uint64(i-start))]...)
+		for j := start; j < i; j++ {
+			delta = append(delta, older[j]^newer[j])
+		}
+	}
+	return delta
+}
+
+// Undoes deltaEncode, returning older
+func deltaDecode(newer, delta []byte) ([]byte, error) {
+	older := append([]byte(nil), newer...)
+	r := bytes.NewReader(delta)
+	i := 0
+	for r.Len() > 0 {
+		skip, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: corrupt rewind snapshot", ErrInvalidState)
+		}
+		n, err := binary.ReadUvarint(r)
+		if err != nil || uint64(i)+skip+n > uint64(len(older)) {
+			return nil, fmt.Errorf("%w: corrupt rewind snapshot", ErrInvalidState)
+		}
+		i += int(skip)
+		for end := i + int(n); i < end; i++ {
+			b, _ := r.ReadByte()
+			older[i] ^= b
+		}
+	}
+	return older, nil
+}
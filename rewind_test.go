@@ -0,0 +1,117 @@
+package chip8
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// Draws a digit at random coordinates every cycle, and counts the cycles
+// key 0 is up in V2
+var rewindROM = []byte{
+	0xC0, 0xFF, // RAND V0, FF
+	0x81, 0x04, // ADD. V1, V0
+	0xE3, 0x9E, // SKIP.KEY V3
+	0x72, 0x01, // ADD V2, 01
+	0xD0, 0x15, // SPRITE V0, V1, 5
+	0x12, 0x00, // JUMP 0x200
+}
+
+// Runs frames of c8 pressing key 0 in some of them, and returns the state
+// after each frame, indexed by frame number
+func runRewindFrames(t *testing.T, c8 *Machine, frames int) []*State {
+	states := []*State{c8.Snapshot()}
+	for f := 0; f < frames; f++ {
+		switch f {
+		case 33:
+			c8.PressKey(0)
+		case 47:
+			c8.ReleaseKey(0)
+		}
+		if err := c8.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+		states = append(states, c8.Snapshot())
+	}
+	return states
+}
+
+func TestRewind(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader(rewindROM), WithRewind(10, 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := runRewindFrames(t, c8, 100)
+
+	if err := c8.Rewind(25); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c8.Snapshot(), states[75]) {
+		t.Errorf("Expected the state of frame 75, got frame %d instead", c8.Frames())
+	}
+	// The key was held over frame 40
+	if err := c8.Rewind(35); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c8.Snapshot(), states[40]) {
+		t.Errorf("Expected the state of frame 40, got frame %d instead", c8.Frames())
+	}
+	if !c8.KeyPressed(0) {
+		t.Errorf("Expected key 0 to be held at frame 40")
+	}
+
+	// The history after frame 40 is forgotten, and new frames are recorded
+	c8.ReleaseKey(0)
+	c8.RunFrame()
+	after := c8.Snapshot()
+	for f := 0; f < 20; f++ {
+		c8.RunFrame()
+	}
+	if err := c8.Rewind(20); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c8.Snapshot(), after) {
+		t.Errorf("Expected the state after the new frame 41")
+	}
+}
+
+func TestRewindBudget(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader(rewindROM), WithRewind(5, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runRewindFrames(t, c8, 23)
+	// Only the snapshot of frame 20 fits
+	if c8.RewindLimit() != 3 {
+		t.Errorf("Expected to rewind 3 frames at most, got %d instead", c8.RewindLimit())
+	}
+	if err := c8.Rewind(4); err != ErrRewindUnavailable {
+		t.Errorf("Expected ErrRewindUnavailable, got %v instead", err)
+	}
+	if c8.Frames() != 23 {
+		t.Errorf("Expected the machine to be left at frame 23, got %d instead", c8.Frames())
+	}
+	if err := c8.Rewind(3); err != nil || c8.Frames() != 20 {
+		t.Errorf("Expected to rewind to frame 20, got %d and %v instead", c8.Frames(), err)
+	}
+}
+
+func TestRewindDisabled(t *testing.T) {
+	c8 := New()
+	if err := c8.Rewind(0); err != ErrRewindUnavailable {
+		t.Errorf("Expected ErrRewindUnavailable, got %v instead", err)
+	}
+}
+
+func TestDeltaEncoding(t *testing.T) {
+	newer := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	older := []byte{1, 9, 9, 4, 5, 6, 7, 0}
+	delta := deltaEncode(newer, older)
+	decoded, err := deltaDecode(newer, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, older) {
+		t.Errorf("Expected %v, got %v instead", older, decoded)
+	}
+}
@@ -0,0 +1,79 @@
+package chip8
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Address programs are loaded to and start executing from on the COSMAC VIP
+const DefaultLoadAddress = 0x200
+
+// Address programs are loaded to and start executing from on the ETI-660
+const ETI660LoadAddress = 0x600
+
+var (
+	// ErrROMTooLarge is returned when a program does not fit between the load
+	// address and the end of memory
+	ErrROMTooLarge = errors.New("chip8: rom too large")
+	// ErrEmptyROM is returned when a program has no bytes
+	ErrEmptyROM = errors.New("chip8: rom is empty")
+)
+
+// Reads a program from r and places it in memory at the load address,
+// returning its size in bytes. The machine is reset so the program starts
+// from a clean state. Errors reading r are returned as is.
+func (c8 *Machine) LoadROM(r io.Reader) (int, error) {
+	// Read one byte more than fits so oversized programs are detected
+	// without reading all of them
+	limit := int64(c8.MaxROMSize()) + 1
+	rom, err := ioutil.ReadAll(io.LimitReader(r, limit))
+	if err != nil {
+		return 0, err
+	}
+	return c8.LoadROMBytes(rom)
+}
+
+// Places a program in memory at the load address and returns its size in
+// bytes. The machine keeps its own copy of rom.
+func (c8 *Machine) LoadROMBytes(rom []byte) (int, error) {
+	if len(rom) == 0 {
+		return 0, ErrEmptyROM
+	}
+	if len(rom) > c8.MaxROMSize() {
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrROMTooLarge, c8.MaxROMSize())
+	}
+	c8.rom = append([]byte(nil), rom...)
+	c8.Reset()
+	return len(rom), nil
+}
+
+// Reads the program in the named file and places it in memory at the load
+// address, returning its size in bytes
+func (c8 *Machine) LoadROMFile(name string) (int, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n, err := c8.LoadROM(f)
+	if errors.Is(err, ErrROMTooLarge) || errors.Is(err, ErrEmptyROM) {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return n, err
+}
+
+// Returns the size of the largest program that fits at the load address
+func (c8 *Machine) MaxROMSize() int {
+	if int(c8.loadAddr) >= len(c8.memory) {
+		return 0
+	}
+	return len(c8.memory) - int(c8.loadAddr)
+}
+
+// Returns the address programs are loaded to and start executing from
+func (c8 *Machine) LoadAddress() uint16 {
+	return c8.loadAddr
+}
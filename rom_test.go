@@ -0,0 +1,94 @@
+package chip8
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadROM(t *testing.T) {
+	c8 := New()
+	n, err := c8.LoadROM(bytes.NewReader([]byte{0x60, 0x0A, 0x22, 0x06}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("Expected 4 bytes loaded, got %d instead", n)
+	}
+	if c8.memory[0x200] != 0x60 || c8.memory[0x203] != 0x06 {
+		t.Errorf("Expected program at 0x200, got %X instead", c8.memory[0x200:0x204])
+	}
+	if c8.memory[0] != 0xF0 {
+		t.Errorf("Expected font at 0x000, got 0x%02X instead", c8.memory[0])
+	}
+
+	n, err = c8.LoadROM(bytes.NewReader(make([]byte, 3584)))
+	if err != nil || n != 3584 {
+		t.Errorf("Expected a 3584 byte rom to fit, got %d, %v instead", n, err)
+	}
+	_, err = c8.LoadROM(bytes.NewReader(make([]byte, 3585)))
+	if !errors.Is(err, ErrROMTooLarge) {
+		t.Errorf("Expected ErrROMTooLarge, got %v instead", err)
+	}
+	_, err = c8.LoadROMBytes(nil)
+	if !errors.Is(err, ErrEmptyROM) {
+		t.Errorf("Expected ErrEmptyROM, got %v instead", err)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("disk on fire")
+}
+
+func TestLoadROMReadError(t *testing.T) {
+	c8 := New()
+	_, err := c8.LoadROM(failingReader{})
+	if err == nil || err.Error() != "disk on fire" {
+		t.Errorf("Expected the read error, got %v instead", err)
+	}
+}
+
+func TestLoadROMFile(t *testing.T) {
+	c8 := New()
+	n, err := c8.LoadROMFile("Fishie.ch8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 160 {
+		t.Errorf("Expected 160 bytes loaded, got %d instead", n)
+	}
+
+	_, err = c8.LoadROMFile("missing.ch8")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing file error, got %v instead", err)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.ch8")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c8.LoadROMFile(empty)
+	if !errors.Is(err, ErrEmptyROM) {
+		t.Errorf("Expected ErrEmptyROM, got %v instead", err)
+	}
+}
+
+func TestLoadAddress(t *testing.T) {
+	c8 := New(WithLoadAddress(ETI660LoadAddress))
+	if _, err := c8.LoadROMBytes([]byte{0x12, 0x34}); err != nil {
+		t.Fatal(err)
+	}
+	if c8.PC() != 0x600 {
+		t.Errorf("Expected pc of 0x600, got 0x%04X instead", c8.PC())
+	}
+	if c8.memory[0x600] != 0x12 || c8.memory[0x200] != 0x00 {
+		t.Errorf("Expected program at 0x600")
+	}
+	if c8.MaxROMSize() != 2560 {
+		t.Errorf("Expected 2560 bytes to fit, got %d instead", c8.MaxROMSize())
+	}
+}
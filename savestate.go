@@ -0,0 +1,258 @@
+package chip8
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Version of the save state format written by SaveState
+const StateVersion = 3
+
+// Most random numbers a save state may have drawn. Restoring a state draws
+// them all again, and a program drawing one every instruction at 1000
+// instructions per second takes three days to draw more.
+const maxRandomDraws = 1 << 28
+
+// Identifies save state files
+var stateMagic = [4]byte{'C', '8', 'S', 'T'}
+
+var (
+	// ErrInvalidState is returned when loading data that is not a save state
+	// or is corrupt
+	ErrInvalidState = errors.New("chip8: invalid save state")
+	// ErrStateVersion is returned when loading a save state written in a
+	// format version this package doesn't read
+	ErrStateVersion = errors.New("chip8: unsupported save state version")
+	// ErrStateROMMismatch is returned when loading a save state of a
+	// different program than the one loaded
+	ErrStateROMMismatch = errors.New("chip8: save state is for a different rom")
+)
+
+// Start of a save state, describing the program and how it was run
+type stateHeader struct {
+	Magic         [4]byte
+	Version       uint16
+	ROMHash       [sha256.Size]byte
+	Variant       uint8
+	LoadAddress   uint16
+	ShiftVX       bool
+	LoadStore     uint8
+	JumpVX        bool
+	VFReset       bool
+	DisplayWait   bool
+	Clip          bool
+	CollisionRows bool
+	Seed          int64
+}
+
+// Fixed size part of a State, followed by MemorySize bytes of memory
+type stateRecord struct {
+	V             [16]uint8
+	I             uint16
+	PC            uint16
+	SP            uint16
+	Stack         [16]uint16
+	DelayTimer    uint8
+	SoundTimer    uint8
+	Keys          [16]bool
+	HiRes         bool
+	Planes        uint8
+	Pixels        [hiresWidth * hiresHeight]uint8
+	WaitingForKey bool
+	WaitRegister  uint8
+	WaitKey       int16
+	Flags         [16]uint8
+	Exited        bool
+	AudioPattern  [16]uint8
+	Pitch         uint8
+	Cycles        uint64
+	Frames        uint64
+	RandomDraws   uint64
+	MemorySize    uint32
+}
+
+// Writes the state of the machine to w, along with a hash of the loaded
+// program, the variant, load address, quirks and random seed it runs
+// with. All numbers are big endian.
+func (c8 *Machine) SaveState(w io.Writer) error {
+	header := stateHeader{
+		Magic:         stateMagic,
+		Version:       StateVersion,
+		ROMHash:       sha256.Sum256(c8.rom),
+		Variant:       uint8(c8.variant),
+		LoadAddress:   c8.loadAddr,
+		ShiftVX:       c8.quirks.ShiftVX,
+		LoadStore:     uint8(c8.quirks.LoadStore),
+		JumpVX:        c8.quirks.JumpVX,
+		VFReset:       c8.quirks.VFReset,
+		DisplayWait:   c8.quirks.DisplayWait,
+		Clip:          c8.quirks.Clip,
+		CollisionRows: c8.quirks.CollisionRows,
+		Seed:          c8.seed,
+	}
+	if err := binary.Write(w, binary.BigEndian, &header); err != nil {
+		return err
+	}
+	return writeState(w, c8.Snapshot())
+}
+
+// Reads a save state written by SaveState from r and puts the machine in
+// it, switching to the variant, load address, quirks and random seed it was
+// saved with. The program the state was saved with must be loaded,
+// otherwise ErrStateROMMismatch is returned. The machine is left unchanged
+// on errors.
+func (c8 *Machine) LoadState(r io.Reader) error {
+	var header stateHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return stateReadError(err)
+	}
+	if header.Magic != stateMagic {
+		return ErrInvalidState
+	}
+	if header.Version != StateVersion {
+		return fmt.Errorf("%w %d", ErrStateVersion, header.Version)
+	}
+	if header.ROMHash != sha256.Sum256(c8.rom) {
+		return ErrStateROMMismatch
+	}
+	variant := Variant(header.Variant)
+	if variant > VariantXOCHIP || LoadStore(header.LoadStore) > LoadStoreKeep {
+		return ErrInvalidState
+	}
+
+	state, err := readState(r)
+	if err != nil {
+		return err
+	}
+	if len(state.Memory) != variant.memorySize() {
+		return fmt.Errorf("%w: %d bytes of memory for %v", ErrInvalidState, len(state.Memory), variant)
+	}
+	if err := checkState(state); err != nil {
+		return err
+	}
+	// Each CXNN draws one number
+	if state.RandomDraws > state.Cycles || state.RandomDraws > maxRandomDraws {
+		return fmt.Errorf("%w: %d random numbers drawn in %d cycles", ErrInvalidState, state.RandomDraws, state.Cycles)
+	}
+
+	c8.variant = variant
+	c8.loadAddr = header.LoadAddress
+	c8.quirks = Quirks{
+		ShiftVX:       header.ShiftVX,
+		LoadStore:     LoadStore(header.LoadStore),
+		JumpVX:        header.JumpVX,
+		VFReset:       header.VFReset,
+		DisplayWait:   header.DisplayWait,
+		Clip:          header.Clip,
+		CollisionRows: header.CollisionRows,
+	}
+	// Start the random source over from the saved seed, Restore draws the
+	// numbers used up before the state was saved
+	c8.seed = header.Seed
+	c8.random.Seed(c8.seed)
+	c8.randomDraws = 0
+	c8.Restore(state)
+	return nil
+}
+
+// Returns ErrInvalidState if s holds values the machine can't run from,
+// such as a stack pointer past the stack
+func checkState(s *State) error {
+	switch {
+	case s.SP > uint16(len(s.Stack)):
+		return fmt.Errorf("%w: SP %d", ErrInvalidState, s.SP)
+	case s.WaitRegister > 15:
+		return fmt.Errorf("%w: waiting for a key in V%d", ErrInvalidState, s.WaitRegister)
+	case s.WaitKey < -1 || s.WaitKey > 15:
+		return fmt.Errorf("%w: waiting for key %d", ErrInvalidState, s.WaitKey)
+	case s.Framebuffer.planes > 3:
+		return fmt.Errorf("%w: planes %d", ErrInvalidState, s.Framebuffer.planes)
+	case int(s.PC) >= len(s.Memory):
+		return fmt.Errorf("%w: PC %04X past the memory", ErrInvalidState, s.PC)
+	case int(s.I) >= len(s.Memory):
+		return fmt.Errorf("%w: I %04X past the memory", ErrInvalidState, s.I)
+	}
+	return nil
+}
+
+// Writes s in the save state format, without the header
+func writeState(w io.Writer, s *State) error {
+	record := stateRecord{
+		V:             s.V,
+		I:             s.I,
+		PC:            s.PC,
+		SP:            s.SP,
+		Stack:         s.Stack,
+		DelayTimer:    s.DelayTimer,
+		SoundTimer:    s.SoundTimer,
+		Keys:          s.Keys,
+		HiRes:         s.Framebuffer.hires,
+		Planes:        s.Framebuffer.planes,
+		Pixels:        s.Framebuffer.buffer,
+		WaitingForKey: s.WaitingForKey,
+		WaitRegister:  s.WaitRegister,
+		WaitKey:       int16(s.WaitKey),
+		Flags:         s.Flags,
+		Exited:        s.Exited,
+		AudioPattern:  s.AudioPattern,
+		Pitch:         s.Pitch,
+		Cycles:        s.Cycles,
+		Frames:        s.Frames,
+		RandomDraws:   s.RandomDraws,
+		MemorySize:    uint32(len(s.Memory)),
+	}
+	if err := binary.Write(w, binary.BigEndian, &record); err != nil {
+		return err
+	}
+	_, err := w.Write(s.Memory)
+	return err
+}
+
+// Reads a State written by writeState
+func readState(r io.Reader) (*State, error) {
+	var record stateRecord
+	if err := binary.Read(r, binary.BigEndian, &record); err != nil {
+		return nil, stateReadError(err)
+	}
+	if record.MemorySize > uint32(VariantXOCHIP.memorySize()) {
+		return nil, fmt.Errorf("%w: %d bytes of memory", ErrInvalidState, record.MemorySize)
+	}
+	memory := make([]uint8, record.MemorySize)
+	if _, err := io.ReadFull(r, memory); err != nil {
+		return nil, stateReadError(err)
+	}
+	return &State{
+		Memory:        memory,
+		V:             record.V,
+		I:             record.I,
+		PC:            record.PC,
+		SP:            record.SP,
+		Stack:         record.Stack,
+		DelayTimer:    record.DelayTimer,
+		SoundTimer:    record.SoundTimer,
+		Keys:          record.Keys,
+		Framebuffer:   Framebuffer{buffer: record.Pixels, hires: record.HiRes, planes: record.Planes},
+		WaitingForKey: record.WaitingForKey,
+		WaitRegister:  record.WaitRegister,
+		WaitKey:       int(record.WaitKey),
+		Flags:         record.Flags,
+		Exited:        record.Exited,
+		AudioPattern:  record.AudioPattern,
+		Pitch:         record.Pitch,
+		Cycles:        record.Cycles,
+		Frames:        record.Frames,
+		RandomDraws:   record.RandomDraws,
+	}, nil
+}
+
+// Reports a save state that ends early as invalid, other read errors are
+// returned as is
+func stateReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: truncated", ErrInvalidState)
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSaveStateRoundTrip(t *testing.T) {
+	c8 := New(WithQuirks(QuirksCHIP48))
+	if _, err := c8.LoadROMFile("Fishie.ch8"); err != nil {
+		t.Fatal(err)
+	}
+	c8.RunFor(50)
+	c8.PressKey(0x5)
+	var buf bytes.Buffer
+	if err := c8.SaveState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	c8.RunFor(50)
+
+	restored := New()
+	if _, err := restored.LoadROMFile("Fishie.ch8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.LoadState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Quirks() != QuirksCHIP48 {
+		t.Errorf("Expected the quirks to be restored, got %+v instead", restored.Quirks())
+	}
+	if !restored.KeyPressed(0x5) {
+		t.Errorf("Expected key 5 to be held")
+	}
+	restored.RunFor(50)
+	if !reflect.DeepEqual(restored.Snapshot(), c8.Snapshot()) {
+		t.Errorf("Expected the restored machine to run like the saved one")
+	}
+}
+
+func TestLoadStateDifferentROM(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader([]byte{0x12, 0x00}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c8.SaveState(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewFromROM(bytes.NewReader([]byte{0x00, 0xE0, 0x12, 0x00}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.RunFor(3)
+	if err := other.LoadState(&buf); err != ErrStateROMMismatch {
+		t.Errorf("Expected ErrStateROMMismatch, got %v instead", err)
+	}
+	if other.Cycles() != 3 {
+		t.Errorf("Expected the machine to be left unchanged")
+	}
+}
+
+func TestLoadStateInvalid(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader([]byte{0x12, 0x00}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c8.SaveState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	truncated := data[:len(data)-1]
+	if err := c8.LoadState(bytes.NewReader(truncated)); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState for a truncated state, got %v instead", err)
+	}
+	if err := c8.LoadState(bytes.NewReader([]byte("not a save state at all, but long enough to read a header"))); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState for garbage, got %v instead", err)
+	}
+	future := append([]byte(nil), data...)
+	future[5] = StateVersion + 1
+	if err := c8.LoadState(bytes.NewReader(future)); !errors.Is(err, ErrStateVersion) {
+		t.Errorf("Expected ErrStateVersion, got %v instead", err)
+	}
+
+	// Restoring would draw more random numbers than could have been drawn
+	draws := append([]byte(nil), data...)
+	at := binary.Size(stateHeader{}) + binary.Size(stateRecord{}) - 12
+	binary.BigEndian.PutUint64(draws[at:], 1<<62)
+	if err := c8.LoadState(bytes.NewReader(draws)); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Expected ErrInvalidState for too many random numbers, got %v instead", err)
+	}
+}
+
+func TestLoadStateCorrupt(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader([]byte{0x12, 0x00}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c8.SaveState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	header := buf.Bytes()[:binary.Size(stateHeader{})]
+
+	for name, corrupt := range map[string]func(s *State){
+		"SP":            func(s *State) { s.SP = 17 },
+		"WaitRegister":  func(s *State) { s.WaitRegister = 16 },
+		"WaitKey below": func(s *State) { s.WaitKey = -2 },
+		"WaitKey above": func(s *State) { s.WaitKey = 16 },
+		"Planes":        func(s *State) { s.Framebuffer.planes = 4 },
+		"PC":            func(s *State) { s.PC = uint16(len(s.Memory)) },
+		"I":             func(s *State) { s.I = uint16(len(s.Memory)) },
+	} {
+		s := c8.Snapshot()
+		corrupt(s)
+		data := bytes.NewBuffer(append([]byte(nil), header...))
+		if err := writeState(data, s); err != nil {
+			t.Fatal(err)
+		}
+		if err := c8.LoadState(data); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("Expected ErrInvalidState for a bad %s, got %v instead", name, err)
+		}
+	}
+	if c8.PC() != 0x200 {
+		t.Errorf("Expected the machine to be left unchanged, got PC %04X instead", c8.PC())
+	}
+}
+
+func TestRestore(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader([]byte{0x60, 0x0A, 0x70, 0x01, 0x12, 0x02}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c8.RunFor(2)
+	s := c8.Snapshot()
+	c8.RunFor(4)
+	c8.Restore(s)
+	if c8.Registers()[0] != 0x0B || c8.Cycles() != 2 {
+		t.Errorf("Expected V0 of 0x0B after 2 cycles, got 0x%02X after %d instead", c8.Registers()[0], c8.Cycles())
+	}
+	c8.memory[0x200] = 0
+	if s.Memory[0x200] != 0x60 {
+		t.Errorf("Expected Restore to copy the memory")
+	}
+}
@@ -0,0 +1,134 @@
+package chip8
+
+import (
+	"time"
+)
+
+// Duration of one frame, a 60th of a second
+const FrameDuration = time.Second / FrameRate
+
+// Most frames a Scheduler runs to catch up with its clock before giving up
+// on the missed time, for instance after the process was suspended
+const maxCatchUpFrames = 10
+
+// Clock tells a Scheduler how much time has passed
+type Clock interface {
+	// Now returns the time elapsed since the clock was created
+	Now() time.Duration
+	// Sleep waits for d to pass
+	Sleep(d time.Duration)
+}
+
+// WallClock is a Clock following real time
+type WallClock struct {
+	start time.Time
+}
+
+// Returns a WallClock starting now
+func NewWallClock() *WallClock {
+	return &WallClock{start: time.Now()}
+}
+
+// Returns the real time elapsed since the clock was created
+func (clk *WallClock) Now() time.Duration {
+	return time.Since(clk.start)
+}
+
+// Sleeps for d
+func (clk *WallClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// VirtualClock is a Clock that only moves when told to, making runs
+// deterministic. Sleeping moves the clock forward without waiting.
+type VirtualClock struct {
+	now time.Duration
+}
+
+// Returns the virtual time elapsed
+func (clk *VirtualClock) Now() time.Duration {
+	return clk.now
+}
+
+// Moves the clock forward by d
+func (clk *VirtualClock) Sleep(d time.Duration) {
+	clk.Advance(d)
+}
+
+// Moves the clock forward by d
+func (clk *VirtualClock) Advance(d time.Duration) {
+	clk.now += d
+}
+
+// Scheduler runs frames of a Machine in step with a Clock, so the CPU runs
+// at its configured instruction rate and the timers at exactly 60 Hz.
+type Scheduler struct {
+	machine *Machine
+	clock   Clock
+	// Frames run, and frames skipped because the machine fell behind
+	frames  uint64
+	skipped uint64
+}
+
+// Returns a Scheduler running m against clock
+func NewScheduler(m *Machine, clock Clock) *Scheduler {
+	return &Scheduler{machine: m, clock: clock}
+}
+
+// Runs every frame that is due at the current time of the clock and
+// returns how many were run. Stops at the first fault.
+func (s *Scheduler) Update() (int, error) {
+	return s.update(0)
+}
+
+// Like Update, but stops once limit frames have been run in total. A limit
+// of 0 means no limit.
+func (s *Scheduler) update(limit uint64) (int, error) {
+	due := uint64(s.clock.Now()/FrameDuration) - s.skipped
+	if due > s.frames+maxCatchUpFrames {
+		s.skipped += due - s.frames - maxCatchUpFrames
+		due = s.frames + maxCatchUpFrames
+	}
+	if limit > 0 && due > limit {
+		due = limit
+	}
+	run := 0
+	for ; s.frames < due; s.frames++ {
+		if err := s.machine.RunFrame(); err != nil {
+			return run, err
+		}
+		run++
+	}
+	return run, nil
+}
+
+// Runs frames as they fall due until stop is closed or the machine faults
+func (s *Scheduler) Run(stop <-chan struct{}) error {
+	return s.run(0, stop)
+}
+
+// Runs frames as they fall due until n frames have been run in total, stop
+// is closed or the machine faults
+func (s *Scheduler) RunFrames(n uint64, stop <-chan struct{}) error {
+	return s.run(n, stop)
+}
+
+func (s *Scheduler) run(limit uint64, stop <-chan struct{}) error {
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		if _, err := s.update(limit); err != nil {
+			return err
+		}
+		if limit > 0 && s.frames >= limit {
+			return nil
+		}
+		next := time.Duration(s.frames+s.skipped+1) * FrameDuration
+		if wait := next - s.clock.Now(); wait > 0 {
+			s.clock.Sleep(wait)
+		}
+	}
+}
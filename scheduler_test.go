@@ -0,0 +1,117 @@
+package chip8
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunFrame(t *testing.T) {
+	// 500 instructions per second is 8.33 per frame
+	c8 := New(WithInstructionsPerSecond(500))
+	// ADD V0 01, JUMP 0x200
+	c8.memory[0x200] = 0x70
+	c8.memory[0x201] = 0x01
+	c8.memory[0x202] = 0x12
+	c8.memory[0x203] = 0x00
+	c8.timerDelay = 0x05
+	c8.soundDelay = 0x01
+
+	c8.RunFrame()
+	if c8.Cycles() != 8 {
+		t.Errorf("Expected 8 cycles, got %d instead", c8.Cycles())
+	}
+	if c8.DelayTimer() != 0x04 || c8.SoundTimer() != 0x00 {
+		t.Errorf("Expected timers of 4 and 0, got %d and %d instead", c8.DelayTimer(), c8.SoundTimer())
+	}
+	for i := 1; i < FrameRate; i++ {
+		c8.RunFrame()
+	}
+	if c8.Cycles() != 500 {
+		t.Errorf("Expected 500 cycles after a second, got %d instead", c8.Cycles())
+	}
+	if c8.Frames() != FrameRate {
+		t.Errorf("Expected %d frames, got %d instead", FrameRate, c8.Frames())
+	}
+	if c8.DelayTimer() != 0 || c8.SoundTimer() != 0 {
+		t.Errorf("Expected the timers to stop at 0, got %d and %d instead", c8.DelayTimer(), c8.SoundTimer())
+	}
+}
+
+func TestDelayTimerLoop(t *testing.T) {
+	c8 := New(WithInstructionsPerFrame(10))
+	// MVI V0 3C, MOV DELAY V0, MOV V1 DELAY, SKIP.EQ V1 00, JUMP 0x204
+	rom := []uint8{0x60, 0x3C, 0xF0, 0x15, 0xF1, 0x07, 0x31, 0x00, 0x12, 0x04, 0x12, 0x0A}
+	copy(c8.memory[0x200:], rom)
+
+	for i := 0; i < FrameRate; i++ {
+		c8.RunFrame()
+	}
+	if c8.PC() == 0x20A {
+		t.Errorf("Expected the loop to still be running after 60 frames")
+	}
+	c8.RunFrame()
+	if c8.PC() != 0x20A {
+		t.Errorf("Expected the loop to end after 61 frames, pc is 0x%04X", c8.PC())
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	c8 := New(WithInstructionsPerFrame(1))
+	// JUMP 0x200
+	c8.LoadROMBytes([]byte{0x12, 0x00})
+	clock := new(VirtualClock)
+	s := NewScheduler(c8, clock)
+
+	if n, err := s.Update(); err != nil || n != 0 {
+		t.Errorf("Expected no frames at time 0, got %d instead", n)
+	}
+	clock.Advance(FrameDuration - time.Nanosecond)
+	if n, err := s.Update(); err != nil || n != 0 {
+		t.Errorf("Expected no frames before the first is due, got %d instead", n)
+	}
+	clock.Advance(time.Nanosecond)
+	if n, err := s.Update(); err != nil || n != 1 {
+		t.Errorf("Expected 1 frame, got %d instead", n)
+	}
+	clock.Advance(5 * FrameDuration)
+	if n, err := s.Update(); err != nil || n != 5 {
+		t.Errorf("Expected 5 frames, got %d instead", n)
+	}
+
+	// Falling far behind only runs a bounded number of frames
+	clock.Advance(time.Minute)
+	if n, err := s.Update(); err != nil || n != maxCatchUpFrames {
+		t.Errorf("Expected %d frames, got %d instead", maxCatchUpFrames, n)
+	}
+	clock.Advance(FrameDuration)
+	if n, err := s.Update(); err != nil || n != 1 {
+		t.Errorf("Expected 1 frame after catching up, got %d instead", n)
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	c8 := New()
+	// JUMP 0x200
+	c8.LoadROMBytes([]byte{0x12, 0x00})
+	clock := new(VirtualClock)
+	s := NewScheduler(c8, clock)
+	stop := make(chan struct{})
+
+	if err := s.RunFrames(120, stop); err != nil {
+		t.Fatal(err)
+	}
+	if c8.Frames() != 120 {
+		t.Errorf("Expected 120 frames, got %d instead", c8.Frames())
+	}
+	if clock.Now() != 120*FrameDuration {
+		t.Errorf("Expected the clock to be at %v, got %v instead", 120*FrameDuration, clock.Now())
+	}
+
+	close(stop)
+	if err := s.Run(stop); err != nil {
+		t.Fatal(err)
+	}
+	if c8.Frames() != 120 {
+		t.Errorf("Expected no frames once stopped, got %d instead", c8.Frames())
+	}
+}
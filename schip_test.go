@@ -0,0 +1,160 @@
+package chip8
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestSCHIPInvalidOnCHIP8(t *testing.T) {
+	for _, inst := range []uint16{0x00C1, 0x00FB, 0x00FC, 0x00FD, 0x00FE, 0x00FF, 0xF030, 0xF075, 0xF085} {
+		c8 := New()
+		if err := c8.executeInstruction(inst); err != ErrInvalidOpcode {
+			t.Errorf("%04X: expected ErrInvalidOpcode, got %v instead", inst, err)
+		}
+	}
+}
+
+func TestHiRes(t *testing.T) {
+	c8 := New(WithVariant(VariantSCHIP))
+	fb := c8.Framebuffer()
+	c8.executeInstruction(0x00FF)
+	if !fb.HiRes() || fb.Width() != 128 || fb.Height() != 64 {
+		t.Errorf("Expected a 128x64 screen, got %dx%d instead", fb.Width(), fb.Height())
+	}
+	c8.reg[0] = 120
+	c8.reg[1] = 60
+	c8.i = 0
+	c8.executeInstruction(0xD011)
+	if !fb.Pixel(120, 60) || !fb.Pixel(123, 60) {
+		t.Errorf("Expected a sprite in the bottom right corner")
+	}
+	c8.executeInstruction(0x00FE)
+	if fb.HiRes() || fb.Width() != 64 || fb.Height() != 32 {
+		t.Errorf("Expected a 64x32 screen, got %dx%d instead", fb.Width(), fb.Height())
+	}
+	if fb.Pixel(56, 28) {
+		t.Errorf("Expected switching modes to clear the screen")
+	}
+}
+
+func TestScroll(t *testing.T) {
+	c8 := New(WithVariant(VariantSCHIP))
+	fb := c8.Framebuffer()
+	// A single pixel at (10, 10)
+	fb.drawSprite(10, 10, 1, []uint8{0x80}, spriteMode{})
+
+	c8.executeInstruction(0x00C3)
+	if fb.Pixel(10, 10) || !fb.Pixel(10, 13) {
+		t.Errorf("Expected the pixel to scroll down 3 rows")
+	}
+	c8.executeInstruction(0x00FB)
+	if fb.Pixel(10, 13) || !fb.Pixel(14, 13) {
+		t.Errorf("Expected the pixel to scroll right 4 columns")
+	}
+	c8.executeInstruction(0x00FC)
+	c8.executeInstruction(0x00FC)
+	if fb.Pixel(14, 13) || !fb.Pixel(6, 13) {
+		t.Errorf("Expected the pixel to scroll left 8 columns")
+	}
+	c8.executeInstruction(0x00CF)
+	c8.executeInstruction(0x00CF)
+	for y := 0; y < fb.Height(); y++ {
+		if fb.Pixel(6, y) {
+			t.Errorf("Expected the pixel to scroll off the screen, found it at row %d", y)
+		}
+	}
+}
+
+func TestExit(t *testing.T) {
+	c8 := New(WithVariant(VariantSCHIP))
+	c8.LoadROMBytes([]uint8{0x00, 0xFD})
+	if err := c8.Step(); err != ErrExit {
+		t.Errorf("Expected ErrExit, got %v instead", err)
+	}
+	if !c8.Exited() || c8.Step() != ErrExit {
+		t.Errorf("Expected the machine to stay exited")
+	}
+	c8.Reset()
+	if c8.Exited() {
+		t.Errorf("Expected Reset to restart the program")
+	}
+}
+
+func TestWideSprite(t *testing.T) {
+	c8 := New(WithVariant(VariantSCHIP))
+	for i := 0; i < 32; i++ {
+		c8.memory[0x300+i] = 0xFF
+	}
+	c8.i = 0x300
+	c8.executeInstruction(0xD000)
+	fb := c8.Framebuffer()
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if !fb.Pixel(x, y) {
+				t.Fatalf("Expected pixel (%d, %d) to be lit", x, y)
+			}
+		}
+	}
+	if fb.Pixel(16, 0) || fb.Pixel(0, 16) {
+		t.Errorf("Expected a 16x16 sprite")
+	}
+	c8.executeInstruction(0xD000)
+	if c8.reg[0xF] != 1 || fb.Pixel(0, 0) {
+		t.Errorf("Expected redrawing to erase the sprite and set VF")
+	}
+}
+
+func TestBigFont(t *testing.T) {
+	c8 := New(WithVariant(VariantSCHIP))
+	c8.reg[2] = 0x7
+	c8.executeInstruction(0xF230)
+	if c8.i != bigFontAddress+70 {
+		t.Errorf("Expected I of 0x%04X, got 0x%04X instead", bigFontAddress+70, c8.i)
+	}
+	if c8.memory[c8.i] != 0xFF || c8.memory[c8.i+9] != 0x18 {
+		t.Errorf("Expected the big 7 at I")
+	}
+}
+
+func TestUserFlags(t *testing.T) {
+	store := FileFlagStore(filepath.Join(t.TempDir(), "flags"))
+	c8 := New(WithVariant(VariantSCHIP), WithFlagStore(store))
+	c8.reg[0] = 0x11
+	c8.reg[1] = 0x22
+	c8.reg[2] = 0x33
+	if err := c8.executeInstruction(0xF175); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new machine sees the flags stored by the first
+	c8 = New(WithVariant(VariantSCHIP), WithFlagStore(store))
+	if err := c8.executeInstruction(0xF285); err != nil {
+		t.Fatal(err)
+	}
+	if c8.reg[0] != 0x11 || c8.reg[1] != 0x22 || c8.reg[2] != 0x00 {
+		t.Errorf("Expected V0-V2 of 11 22 00, got %X instead", c8.reg[:3])
+	}
+}
+
+type brokenFlagStore struct{}
+
+func (brokenFlagStore) LoadFlags() ([]uint8, error) {
+	return nil, errors.New("no flags for you")
+}
+
+func (brokenFlagStore) SaveFlags(flags []uint8) error {
+	return errors.New("no flags for you")
+}
+
+func TestUserFlagsError(t *testing.T) {
+	c8 := New(WithVariant(VariantSCHIP), WithFlagStore(brokenFlagStore{}))
+	c8.LoadROMBytes([]uint8{0xF1, 0x85})
+	err := c8.Step()
+	if err == nil || err.Error() != "no flags for you" {
+		t.Errorf("Expected the store error, got %v instead", err)
+	}
+	if c8.PC() != 0x200 || c8.Fault() != nil {
+		t.Errorf("Expected the instruction to be retried")
+	}
+}
@@ -0,0 +1,121 @@
+package chip8
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Colors of the screen when no palette is given, by Color: off, lit in the
+// first plane, lit in the second and lit in both, as in the default
+// terminal colors
+var DefaultPalette = color.Palette{
+	color.RGBA{0x00, 0x00, 0x00, 0xFF},
+	color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+	color.RGBA{0xFF, 0x00, 0x00, 0xFF},
+	color.RGBA{0xFF, 0xFF, 0x00, 0xFF},
+}
+
+// Returns the screen as an image, each pixel a square of scale by scale
+// pixels in the color of palette at its Color. An empty palette is
+// DefaultPalette, and a scale below 1 is 1. Palettes with fewer than four
+// colors repeat their last one.
+func (disp *Framebuffer) Image(scale int, palette color.Palette) *image.Paletted {
+	if len(palette) == 0 {
+		palette = DefaultPalette
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	width, height := disp.Width(), disp.Height()
+	img := image.NewPaletted(image.Rect(0, 0, width*scale, height*scale), palette)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := disp.Color(x, y)
+			if int(c) >= len(palette) {
+				c = uint8(len(palette) - 1)
+			}
+			if c == 0 {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				row := img.Pix[(y*scale+dy)*img.Stride:]
+				for dx := 0; dx < scale; dx++ {
+					row[x*scale+dx] = c
+				}
+			}
+		}
+	}
+	return img
+}
+
+// Writes img to the file name as a PNG, PBM or PGM image, chosen by the
+// extension of name
+func WriteScreenshot(name string, img image.Image) error {
+	var encode func(io.Writer, image.Image) error
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".png":
+		encode = png.Encode
+	case ".pbm":
+		encode = EncodePBM
+	case ".pgm":
+		encode = EncodePGM
+	default:
+		return fmt.Errorf("chip8: unknown image format %q, expected .png, .pbm or .pgm", ext)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = encode(f, img)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Writes img to w as a binary PBM image. Pixels darker than half are black,
+// the rest white.
+func EncodePBM(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "P4\n%d %d\n", b.Dx(), b.Dy())
+	row := make([]byte, (b.Dx()+7)/8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for i := range row {
+			row[i] = 0
+		}
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if gray(img.At(x, y)) < 0x80 {
+				n := x - b.Min.X
+				row[n/8] |= 0x80 >> uint(n%8)
+			}
+		}
+		bw.Write(row)
+	}
+	return bw.Flush()
+}
+
+// Writes img to w as a binary PGM image with 256 shades of gray
+func EncodePGM(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "P5\n%d %d\n255\n", b.Dx(), b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			bw.WriteByte(gray(img.At(x, y)))
+		}
+	}
+	return bw.Flush()
+}
+
+// Returns the brightness of c
+func gray(c color.Color) uint8 {
+	return color.GrayModel.Convert(c).(color.Gray).Y
+}
@@ -0,0 +1,83 @@
+package chip8
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFramebufferImage(t *testing.T) {
+	fb := new(Framebuffer)
+	fb.reset()
+	fb.drawSprite(1, 0, 1, []uint8{0x80}, spriteMode{})
+
+	img := fb.Image(2, nil)
+	if img.Bounds() != image.Rect(0, 0, 128, 64) {
+		t.Fatalf("Expected a 128x64 image, got %v instead", img.Bounds())
+	}
+	for _, p := range []image.Point{{2, 0}, {3, 0}, {2, 1}, {3, 1}} {
+		if img.ColorIndexAt(p.X, p.Y) != 1 {
+			t.Errorf("Expected %v to be lit", p)
+		}
+	}
+	if img.ColorIndexAt(1, 0) != 0 || img.ColorIndexAt(4, 1) != 0 || img.ColorIndexAt(2, 2) != 0 {
+		t.Errorf("Expected only the scaled pixel to be lit")
+	}
+
+	green := color.Palette{color.Black, color.RGBA{0, 0xFF, 0, 0xFF}}
+	if c := fb.Image(1, green).At(1, 0); c != green[1] {
+		t.Errorf("Expected the lit pixel in green, got %v instead", c)
+	}
+}
+
+func TestEncodeNetpbm(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 10, 2), DefaultPalette)
+	img.SetColorIndex(0, 0, 1)
+	img.SetColorIndex(9, 1, 1)
+
+	var buf bytes.Buffer
+	if err := EncodePBM(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	expected := append([]byte("P4\n10 2\n"), 0x7F, 0xC0, 0xFF, 0x80)
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Expected % X, got % X instead", expected, buf.Bytes())
+	}
+
+	buf.Reset()
+	if err := EncodePGM(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	expected = append([]byte("P5\n10 2\n255\n"), make([]byte, 20)...)
+	expected[len("P5\n10 2\n255\n")] = 0xFF
+	expected[len(expected)-1] = 0xFF
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Expected % X, got % X instead", expected, buf.Bytes())
+	}
+}
+
+func TestWriteScreenshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chip8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	img := new(Framebuffer).Image(1, nil)
+	for name, magic := range map[string]string{"shot.png": "\x89PNG", "shot.pbm": "P4\n", "shot.pgm": "P5\n"} {
+		if err := WriteScreenshot(filepath.Join(dir, name), img); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		if !bytes.HasPrefix(data, []byte(magic)) {
+			t.Errorf("%s: expected to start with %q, got %q instead", name, magic, data[:4])
+		}
+	}
+	if err := WriteScreenshot(filepath.Join(dir, "shot.bmp"), img); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
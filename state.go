@@ -0,0 +1,97 @@
+package chip8
+
+// State is a copy of everything that makes up a running machine
+type State struct {
+	Memory      []uint8
+	V           [16]uint8
+	I           uint16
+	PC          uint16
+	SP          uint16
+	Stack       [16]uint16
+	DelayTimer  uint8
+	SoundTimer  uint8
+	Keys        [16]bool
+	Framebuffer Framebuffer
+	// FX0A progress, see Machine.WaitingForKey
+	WaitingForKey bool
+	WaitRegister  uint8
+	WaitKey       int
+	// SUPER-CHIP RPL user flags and whether the program has exited
+	Flags  [16]uint8
+	Exited bool
+	// XO-CHIP audio pattern buffer and pitch
+	AudioPattern [16]uint8
+	Pitch        uint8
+	Cycles       uint64
+	Frames       uint64
+	// Bytes drawn from the random source since the last reset
+	RandomDraws uint64
+}
+
+// Returns a copy of the current state of the machine
+func (c8 *Machine) Snapshot() *State {
+	return &State{
+		Memory:        append([]uint8(nil), c8.memory...),
+		V:             c8.reg,
+		I:             c8.i,
+		PC:            c8.pc,
+		SP:            c8.sp,
+		Stack:         c8.stack,
+		DelayTimer:    c8.timerDelay,
+		SoundTimer:    c8.soundDelay,
+		Keys:          c8.key,
+		Framebuffer:   c8.graphics,
+		WaitingForKey: c8.waitingKey,
+		WaitRegister:  c8.waitReg,
+		WaitKey:       c8.waitKey,
+		Flags:         c8.rpl,
+		Exited:        c8.exited,
+		AudioPattern:  c8.pattern,
+		Pitch:         c8.pitch,
+		Cycles:        c8.cycles,
+		Frames:        c8.frames,
+		RandomDraws:   c8.randomDraws,
+	}
+}
+
+// Puts the machine in state s, as returned by Snapshot. A machine halted by
+// a fault runs again. The loaded program, variant and quirks are kept, see
+// LoadState to restore those too. The rewind history is discarded.
+func (c8 *Machine) Restore(s *State) {
+	c8.restore(s)
+	if c8.rewind != nil {
+		c8.rewind.clear()
+	}
+}
+
+// Like Restore, keeping the rewind history
+func (c8 *Machine) restore(s *State) {
+	c8.memory = append([]uint8(nil), s.Memory...)
+	c8.reg = s.V
+	c8.i = s.I
+	c8.pc = s.PC
+	c8.sp = s.SP
+	c8.stack = s.Stack
+	c8.timerDelay = s.DelayTimer
+	c8.soundDelay = s.SoundTimer
+	c8.key = s.Keys
+	c8.graphics = s.Framebuffer
+	c8.waitingKey = s.WaitingForKey
+	c8.waitReg = s.WaitRegister
+	c8.waitKey = s.WaitKey
+	c8.rpl = s.Flags
+	// The restored flags replace the ones in the flag store
+	c8.flagsLoaded = true
+	c8.exited = s.Exited
+	c8.pattern = s.AudioPattern
+	c8.pitch = s.Pitch
+	c8.cycles = s.Cycles
+	c8.frames = s.Frames
+	// Carry on with the random numbers the machine would have drawn next
+	c8.seekRandom(s.RandomDraws)
+	c8.halted = nil
+	c8.vblankWait = false
+	c8.frameStep = 0
+	// Present the restored screen
+	c8.drawFlag = true
+}
@@ -0,0 +1,389 @@
+package chip8
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrInvalidTrace is returned when reading a trace that is in neither
+// trace format
+var ErrInvalidTrace = errors.New("chip8: invalid trace")
+
+// TraceEntry is an instruction executed by the machine and what it changed
+type TraceEntry struct {
+	// Instructions run before this one since the last reset
+	Cycle  uint64 `json:"cycle"`
+	PC     uint16 `json:"pc"`
+	Opcode uint16 `json:"opcode"`
+	// Mnemonic of the opcode, without the opcode in front
+	Mnemonic string `json:"mnemonic"`
+	// New values of the registers the instruction changed, by name V0 to VF
+	Registers map[string]uint8 `json:"regs,omitempty"`
+	// New values of I and SP, if the instruction changed them
+	I  *uint16 `json:"i,omitempty"`
+	SP *uint16 `json:"sp,omitempty"`
+	// Bytes stored in memory, in order of address
+	Writes []MemoryWrite `json:"writes,omitempty"`
+	// Seed of the random source, on the first entry after a reset or after
+	// the tracer is set
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+// MemoryWrite is a byte stored in memory by an instruction
+type MemoryWrite struct {
+	Addr  uint16 `json:"addr"`
+	Value uint8  `json:"value"`
+}
+
+// Tracer is given every instruction the machine executes, see WithTracer
+type Tracer interface {
+	Trace(e *TraceEntry)
+}
+
+// Hands every instruction executed to t. Instructions that fault are not
+// traced, and neither are the frames run again while rewinding. Without a
+// tracer, tracing costs a nil check per instruction.
+func WithTracer(t Tracer) Option {
+	return func(c8 *Machine) {
+		c8.tracer = t
+	}
+}
+
+// Sets the tracer, nil to stop tracing
+func (c8 *Machine) SetTracer(t Tracer) {
+	c8.tracer = t
+	c8.traceSeed = true
+}
+
+// The state an instruction may change, taken before it runs
+type traceBefore struct {
+	reg [16]uint8
+	i   uint16
+	sp  uint16
+	// Memory the instruction stores to
+	writeAddr int
+	writeLen  int
+}
+
+// Takes the state inst may change before it runs
+func (c8 *Machine) traceBefore(inst uint16) traceBefore {
+	b := traceBefore{reg: c8.reg, i: c8.i, sp: c8.sp, writeAddr: int(c8.i)}
+	x, y := int(inst>>8&0xF), int(inst>>4&0xF)
+	switch {
+	case inst&0xF0FF == 0xF033:
+		b.writeLen = 3
+	case inst&0xF0FF == 0xF055:
+		b.writeLen = x + 1
+	case inst&0xF00F == 0x5002:
+		b.writeLen = x - y + 1
+		if y > x {
+			b.writeLen = y - x + 1
+		}
+	}
+	return b
+}
+
+// Hands the instruction inst at pc, which has just run, to the tracer
+func (c8 *Machine) trace(pc, inst uint16, b *traceBefore) {
+	e := &TraceEntry{
+		Cycle:    c8.cycles - 1,
+		PC:       pc,
+		Opcode:   inst,
+		Mnemonic: traceMnemonic(inst),
+	}
+	for r, v := range c8.reg {
+		if v != b.reg[r] {
+			if e.Registers == nil {
+				e.Registers = make(map[string]uint8)
+			}
+			e.Registers[fmt.Sprintf("V%X", r)] = v
+		}
+	}
+	if c8.i != b.i {
+		i := c8.i
+		e.I = &i
+	}
+	if c8.sp != b.sp {
+		sp := c8.sp
+		e.SP = &sp
+	}
+	for addr := b.writeAddr; addr < b.writeAddr+b.writeLen && addr < len(c8.memory); addr++ {
+		e.Writes = append(e.Writes, MemoryWrite{Addr: uint16(addr), Value: c8.memory[addr]})
+	}
+	if c8.traceSeed {
+		seed := c8.seed
+		e.Seed = &seed
+		c8.traceSeed = false
+	}
+	c8.tracer.Trace(e)
+}
+
+// Returns the mnemonic of inst without the opcode in front
+func traceMnemonic(inst uint16) string {
+	m := Mnemonic(inst)
+	if strings.HasPrefix(m, "Instruction not") {
+		return m
+	}
+	return m[5:]
+}
+
+// TraceFormat is how a TraceWriter writes entries
+type TraceFormat int
+
+const (
+	// One JSON object per line, see TraceEntry for the fields
+	TraceJSON TraceFormat = iota
+	// Binary entries after a header, see TraceWriter
+	TraceBinary
+)
+
+// Returns the trace format called name: jsonl or binary
+func ParseTraceFormat(name string) (TraceFormat, error) {
+	switch name {
+	case "jsonl", "json":
+		return TraceJSON, nil
+	case "binary":
+		return TraceBinary, nil
+	}
+	return 0, fmt.Errorf("chip8: unknown trace format %q", name)
+}
+
+// Magic number and version starting a binary trace
+const (
+	traceMagic   = "C8TR"
+	traceVersion = 2
+)
+
+// Flags of a binary trace entry
+const (
+	traceFlagI = 1 << iota
+	traceFlagSP
+	traceFlagSeed
+)
+
+// TraceWriter is a Tracer writing the entries to a writer. Output is
+// buffered, call Flush once tracing is done.
+//
+// The binary format starts with "C8TR" and a version byte. Each entry is
+// the cycle as a uvarint difference from the previous entry, PC and
+// opcode as big endian words, a big endian mask of the registers changed
+// followed by their values, a flags byte telling whether I (a word), SP
+// (a byte) and the seed (a big endian 64 bit word) follow, and the count
+// of memory writes as a uvarint followed by the address word and value of
+// each.
+type TraceWriter struct {
+	w         *bufio.Writer
+	format    TraceFormat
+	lastCycle uint64
+	started   bool
+	err       error
+}
+
+// Returns a TraceWriter writing to w in the given format
+func NewTraceWriter(w io.Writer, format TraceFormat) *TraceWriter {
+	return &TraceWriter{w: bufio.NewWriter(w), format: format}
+}
+
+// Writes an entry. Errors are kept and returned by Flush.
+func (tw *TraceWriter) Trace(e *TraceEntry) {
+	if tw.err != nil {
+		return
+	}
+	if tw.format == TraceJSON {
+		data, err := json.Marshal(e)
+		if err == nil {
+			data = append(data, '\n')
+			_, err = tw.w.Write(data)
+		}
+		tw.err = err
+		return
+	}
+
+	if !tw.started {
+		tw.started = true
+		tw.w.WriteString(traceMagic)
+		tw.w.WriteByte(traceVersion)
+	}
+	var buf []byte
+	uvarint := func(v uint64) {
+		var num [binary.MaxVarintLen64]byte
+		buf = append(buf, num[:binary.PutUvarint(num[:], v)]...)
+	}
+	word := func(v uint16) {
+		buf = append(buf, byte(v>>8), byte(v))
+	}
+	uvarint(e.Cycle - tw.lastCycle)
+	tw.lastCycle = e.Cycle
+	word(e.PC)
+	word(e.Opcode)
+	var mask uint16
+	var values []byte
+	for r := 0; r < 16; r++ {
+		if v, ok := e.Registers[fmt.Sprintf("V%X", r)]; ok {
+			mask |= 1 << r
+			values = append(values, v)
+		}
+	}
+	word(mask)
+	buf = append(buf, values...)
+	var flags byte
+	if e.I != nil {
+		flags |= traceFlagI
+	}
+	if e.SP != nil {
+		flags |= traceFlagSP
+	}
+	if e.Seed != nil {
+		flags |= traceFlagSeed
+	}
+	buf = append(buf, flags)
+	if e.I != nil {
+		word(*e.I)
+	}
+	if e.SP != nil {
+		buf = append(buf, byte(*e.SP))
+	}
+	if e.Seed != nil {
+		var seed [8]byte
+		binary.BigEndian.PutUint64(seed[:], uint64(*e.Seed))
+		buf = append(buf, seed[:]...)
+	}
+	uvarint(uint64(len(e.Writes)))
+	for _, w := range e.Writes {
+		word(w.Addr)
+		buf = append(buf, w.Value)
+	}
+	_, tw.err = tw.w.Write(buf)
+}
+
+// Writes out buffered entries and returns the first error met
+func (tw *TraceWriter) Flush() error {
+	if tw.err != nil {
+		return tw.err
+	}
+	return tw.w.Flush()
+}
+
+// TraceReader reads the entries of a trace in either format
+type TraceReader struct {
+	r         *bufio.Reader
+	binary    bool
+	lastCycle uint64
+	// Line or entry number, for errors
+	n int
+}
+
+// Returns a TraceReader reading from r, telling the format from the start
+// of the trace
+func NewTraceReader(r io.Reader) (*TraceReader, error) {
+	tr := &TraceReader{r: bufio.NewReader(r)}
+	head, err := tr.r.Peek(len(traceMagic) + 1)
+	if err == nil && string(head[:len(traceMagic)]) == traceMagic {
+		if v := head[len(traceMagic)]; v != traceVersion {
+			return nil, fmt.Errorf("%w: binary version %d", ErrInvalidTrace, v)
+		}
+		tr.binary = true
+		tr.r.Discard(len(head))
+	}
+	return tr, nil
+}
+
+// Returns the next entry, or io.EOF at the end of the trace
+func (tr *TraceReader) Next() (*TraceEntry, error) {
+	tr.n++
+	if !tr.binary {
+		for {
+			line, err := tr.r.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) == 0 {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			e := new(TraceEntry)
+			if jerr := json.Unmarshal(line, e); jerr != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidTrace, tr.n, jerr)
+			}
+			return e, nil
+		}
+	}
+
+	delta, err := binary.ReadUvarint(tr.r)
+	if err != nil {
+		// A trace ends between entries only
+		return nil, err
+	}
+	e := &TraceEntry{Cycle: tr.lastCycle + delta}
+	tr.lastCycle = e.Cycle
+	var fixed [6]byte
+	if _, err := io.ReadFull(tr.r, fixed[:]); err != nil {
+		return nil, tr.truncated()
+	}
+	e.PC = binary.BigEndian.Uint16(fixed[0:])
+	e.Opcode = binary.BigEndian.Uint16(fixed[2:])
+	e.Mnemonic = traceMnemonic(e.Opcode)
+	mask := binary.BigEndian.Uint16(fixed[4:])
+	for r := 0; r < 16; r++ {
+		if mask&(1<<r) == 0 {
+			continue
+		}
+		v, err := tr.r.ReadByte()
+		if err != nil {
+			return nil, tr.truncated()
+		}
+		if e.Registers == nil {
+			e.Registers = make(map[string]uint8)
+		}
+		e.Registers[fmt.Sprintf("V%X", r)] = v
+	}
+	flags, err := tr.r.ReadByte()
+	if err != nil {
+		return nil, tr.truncated()
+	}
+	if flags&traceFlagI != 0 {
+		var word [2]byte
+		if _, err := io.ReadFull(tr.r, word[:]); err != nil {
+			return nil, tr.truncated()
+		}
+		i := binary.BigEndian.Uint16(word[:])
+		e.I = &i
+	}
+	if flags&traceFlagSP != 0 {
+		b, err := tr.r.ReadByte()
+		if err != nil {
+			return nil, tr.truncated()
+		}
+		sp := uint16(b)
+		e.SP = &sp
+	}
+	if flags&traceFlagSeed != 0 {
+		var word [8]byte
+		if _, err := io.ReadFull(tr.r, word[:]); err != nil {
+			return nil, tr.truncated()
+		}
+		seed := int64(binary.BigEndian.Uint64(word[:]))
+		e.Seed = &seed
+	}
+	count, err := binary.ReadUvarint(tr.r)
+	if err != nil || count > 0x10000 {
+		return nil, tr.truncated()
+	}
+	for j := uint64(0); j < count; j++ {
+		var w [3]byte
+		if _, err := io.ReadFull(tr.r, w[:]); err != nil {
+			return nil, tr.truncated()
+		}
+		e.Writes = append(e.Writes, MemoryWrite{Addr: binary.BigEndian.Uint16(w[:]), Value: w[2]})
+	}
+	return e, nil
+}
+
+func (tr *TraceReader) truncated() error {
+	return fmt.Errorf("%w: entry %d is cut off", ErrInvalidTrace, tr.n)
+}
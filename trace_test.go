@@ -0,0 +1,149 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+var traceROM = []byte{
+	0x60, 0x7B, // MVI V0, 7B
+	0xA3, 0x00, // MVI I 0x0300
+	0xF0, 0x33, // MOVBCD V0
+	0x22, 0x0A, // CALL 0x020A
+	0x12, 0x08, // JUMP 0x0208
+	0xF1, 0x55, // STORE (I), V0-V1
+	0x00, 0xEE, // RTS
+}
+
+// Keeps the entries traced
+type traceLog []*TraceEntry
+
+func (l *traceLog) Trace(e *TraceEntry) {
+	*l = append(*l, e)
+}
+
+func runTrace(t *testing.T, tracer Tracer, cycles int) {
+	c8, err := NewFromROM(bytes.NewReader(traceROM), WithTracer(tracer), WithSeed(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c8.RunFor(cycles); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var log traceLog
+	runTrace(t, &log, 7)
+
+	i, iAfterStore, sp1, sp0 := uint16(0x300), uint16(0x302), uint16(1), uint16(0)
+	seed := int64(5)
+	expected := []*TraceEntry{
+		{Cycle: 0, PC: 0x200, Opcode: 0x607B, Mnemonic: "MVI V0, 7B", Registers: map[string]uint8{"V0": 0x7B}, Seed: &seed},
+		{Cycle: 1, PC: 0x202, Opcode: 0xA300, Mnemonic: "MVI I 0x0300", I: &i},
+		{Cycle: 2, PC: 0x204, Opcode: 0xF033, Mnemonic: "MOVBCD V0", Writes: []MemoryWrite{{0x300, 1}, {0x301, 2}, {0x302, 3}}},
+		{Cycle: 3, PC: 0x206, Opcode: 0x220A, Mnemonic: "CALL 0x020A", SP: &sp1},
+		{Cycle: 4, PC: 0x20A, Opcode: 0xF155, Mnemonic: "STORE (I), V0-V1", I: &iAfterStore, Writes: []MemoryWrite{{0x300, 0x7B}, {0x301, 0}}},
+		{Cycle: 5, PC: 0x20C, Opcode: 0x00EE, Mnemonic: "RTS", SP: &sp0},
+		{Cycle: 6, PC: 0x208, Opcode: 0x1208, Mnemonic: "JUMP 0x0208"},
+	}
+	if !reflect.DeepEqual([]*TraceEntry(log), expected) {
+		got, _ := json.Marshal(log)
+		t.Errorf("Expected the instructions and their changes, got %s instead", got)
+	}
+}
+
+func TestTraceFormats(t *testing.T) {
+	var log traceLog
+	runTrace(t, &log, 7)
+
+	for _, format := range []TraceFormat{TraceJSON, TraceBinary} {
+		var buf bytes.Buffer
+		tw := NewTraceWriter(&buf, format)
+		runTrace(t, tw, 7)
+		if err := tw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if format == TraceJSON && !bytes.HasPrefix(buf.Bytes(), []byte(`{"cycle":0,"pc":512,"opcode":24699,"mnemonic":"MVI V0, 7B","regs":{"V0":123},"seed":5}`+"\n")) {
+			t.Errorf("Expected a JSON line per instruction, got %q instead", buf.String())
+		}
+
+		tr, err := NewTraceReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var read []*TraceEntry
+		for {
+			e, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			read = append(read, e)
+		}
+		if !reflect.DeepEqual(read, []*TraceEntry(log)) {
+			t.Errorf("Format %d: expected to read back the entries written", format)
+		}
+	}
+}
+
+func TestTraceReaderInvalid(t *testing.T) {
+	tr, _ := NewTraceReader(bytes.NewReader([]byte("C8TR\x02\x00\x02")))
+	if _, err := tr.Next(); !errors.Is(err, ErrInvalidTrace) {
+		t.Errorf("Expected ErrInvalidTrace for a cut off entry, got %v instead", err)
+	}
+	if _, err := NewTraceReader(bytes.NewReader([]byte("C8TR\x01\x00\x02"))); !errors.Is(err, ErrInvalidTrace) {
+		t.Errorf("Expected ErrInvalidTrace for another version, got %v instead", err)
+	}
+	tr, _ = NewTraceReader(bytes.NewReader([]byte("not json\n")))
+	if _, err := tr.Next(); !errors.Is(err, ErrInvalidTrace) {
+		t.Errorf("Expected ErrInvalidTrace for a bad line, got %v instead", err)
+	}
+}
+
+// Hands out the entries of a log
+type traceSlice []*TraceEntry
+
+func (s *traceSlice) Next() (*TraceEntry, error) {
+	if len(*s) == 0 {
+		return nil, io.EOF
+	}
+	e := (*s)[0]
+	*s = (*s)[1:]
+	return e, nil
+}
+
+func TestCompareTraces(t *testing.T) {
+	var a, b traceLog
+	runTrace(t, &a, 7)
+	c8, _ := NewFromROM(bytes.NewReader(traceROM), WithTracer(&b), WithQuirks(QuirksSCHIP11))
+	c8.RunFor(7)
+
+	s1, s2 := traceSlice(a), traceSlice(a)
+	div, n, err := CompareTraces(&s1, &s2, nil, 0)
+	if err != nil || div != nil || n != 7 {
+		t.Errorf("Expected a trace to agree with itself for 7 entries, got %v after %d instead", div, n)
+	}
+
+	sa, sb := traceSlice(a), traceSlice(b)
+	div, n, err = CompareTraces(&sa, &sb, traceROM, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// FX55 leaves I alone under the SUPER-CHIP 1.1 quirks
+	if div == nil || n != 4 || div.Index != 4 || !reflect.DeepEqual(div.Fields, []string{"i"}) {
+		t.Fatalf("Expected I to differ after STORE, got %+v after %d instead", div, n)
+	}
+	if div.StateA.I != 0x300 || div.StateA.SP != 1 || div.StateA.Memory[0x302] != 3 || div.StateA.V[0] != 0x7B {
+		t.Errorf("Expected the state before STORE, got %+v instead", div.StateA)
+	}
+	if len(div.History) != 2 || div.History[0].PC != 0x204 || div.History[1].PC != 0x206 {
+		t.Errorf("Expected the two entries before STORE, got %v instead", div.History)
+	}
+}
@@ -0,0 +1,180 @@
+package chip8
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TraceSource hands out the entries of a trace in order, io.EOF once done.
+// TraceReader is one.
+type TraceSource interface {
+	Next() (*TraceEntry, error)
+}
+
+// TraceState is the state of a machine rebuilt from the entries of a trace
+type TraceState struct {
+	V  [16]uint8
+	I  uint16
+	SP uint16
+	// All 64K of memory, starting out as the image the trace was taken from
+	Memory []uint8
+}
+
+// Returns the state of a machine that has yet to run, with memory holding
+// a copy of memory. Registers start out at zero.
+func NewTraceState(memory []uint8) *TraceState {
+	s := &TraceState{Memory: make([]uint8, 0x10000)}
+	copy(s.Memory, memory)
+	return s
+}
+
+// Applies the changes of e to the state
+func (s *TraceState) Apply(e *TraceEntry) {
+	s.V = s.registersAfter(e)
+	s.I, s.SP = s.iAfter(e), s.spAfter(e)
+	for _, w := range e.Writes {
+		s.Memory[w.Addr] = w.Value
+	}
+}
+
+// Returns the registers once e has run, leaving the state as it is
+func (s *TraceState) registersAfter(e *TraceEntry) [16]uint8 {
+	v := s.V
+	for name, value := range e.Registers {
+		if r, ok := traceRegister(name); ok {
+			v[r] = value
+		}
+	}
+	return v
+}
+
+func (s *TraceState) iAfter(e *TraceEntry) uint16 {
+	if e.I != nil {
+		return *e.I
+	}
+	return s.I
+}
+
+func (s *TraceState) spAfter(e *TraceEntry) uint16 {
+	if e.SP != nil {
+		return *e.SP
+	}
+	return s.SP
+}
+
+// Returns the byte at addr once e has run
+func (s *TraceState) memoryAfter(e *TraceEntry, addr uint16) uint8 {
+	v := s.Memory[addr]
+	for _, w := range e.Writes {
+		if w.Addr == addr {
+			v = w.Value
+		}
+	}
+	return v
+}
+
+// Returns the register named V0 to VF, in either case
+func traceRegister(name string) (int, bool) {
+	if len(name) != 2 || !strings.EqualFold(name[:1], "V") {
+		return 0, false
+	}
+	r, err := strconv.ParseUint(name[1:], 16, 4)
+	return int(r), err == nil
+}
+
+// TraceDivergence is the first instruction two traces disagree on
+type TraceDivergence struct {
+	// Instructions both traces agreed on before this one
+	Index int
+	A, B  *TraceEntry
+	// What differs once the instructions have run: pc, opcode, V0 to VF, i,
+	// sp, or memory followed by the address
+	Fields []string
+	// States of both traces before the instructions ran
+	StateA, StateB *TraceState
+	// Entries of a leading up to the divergence, oldest first
+	History []*TraceEntry
+}
+
+// Compares traces a and b entry by entry, stopping at the first one they
+// differ at, and returns it along with the number of entries both agreed
+// on. The divergence is nil if the traces agree until either ends. Entries
+// are compared by the state they leave behind, so cycle numbers, mnemonics
+// and registers noted without changing don't count. Both traces start from
+// memory, see NewTraceState, and up to context entries before the
+// divergence are kept in its History.
+func CompareTraces(a, b TraceSource, memory []uint8, context int) (*TraceDivergence, int, error) {
+	sa, sb := NewTraceState(memory), NewTraceState(memory)
+	var history []*TraceEntry
+	for n := 0; ; n++ {
+		ea, err := a.Next()
+		if err == io.EOF {
+			return nil, n, nil
+		}
+		if err != nil {
+			return nil, n, err
+		}
+		eb, err := b.Next()
+		if err == io.EOF {
+			return nil, n, nil
+		}
+		if err != nil {
+			return nil, n, err
+		}
+		if fields := compareTraceEntries(sa, ea, sb, eb); len(fields) > 0 {
+			return &TraceDivergence{
+				Index:   n,
+				A:       ea,
+				B:       eb,
+				Fields:  fields,
+				StateA:  sa,
+				StateB:  sb,
+				History: history,
+			}, n, nil
+		}
+		sa.Apply(ea)
+		sb.Apply(eb)
+		if context > 0 {
+			if len(history) == context {
+				history = history[1:]
+			}
+			history = append(history, ea)
+		}
+	}
+}
+
+// Returns what differs between running a from sa and b from sb
+func compareTraceEntries(sa *TraceState, a *TraceEntry, sb *TraceState, b *TraceEntry) []string {
+	var fields []string
+	if a.PC != b.PC {
+		fields = append(fields, "pc")
+	}
+	if a.Opcode != b.Opcode {
+		fields = append(fields, "opcode")
+	}
+	va, vb := sa.registersAfter(a), sb.registersAfter(b)
+	for r := range va {
+		if va[r] != vb[r] {
+			fields = append(fields, fmt.Sprintf("V%X", r))
+		}
+	}
+	if sa.iAfter(a) != sb.iAfter(b) {
+		fields = append(fields, "i")
+	}
+	if sa.spAfter(a) != sb.spAfter(b) {
+		fields = append(fields, "sp")
+	}
+	seen := make(map[uint16]bool)
+	for _, w := range append(append([]MemoryWrite(nil), a.Writes...), b.Writes...) {
+		if seen[w.Addr] {
+			continue
+		}
+		seen[w.Addr] = true
+		if sa.memoryAfter(a, w.Addr) != sb.memoryAfter(b, w.Addr) {
+			fields = append(fields, fmt.Sprintf("memory 0x%04X", w.Addr))
+		}
+	}
+	return fields
+}
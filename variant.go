@@ -0,0 +1,77 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variant is the instruction set a machine runs. Each variant extends the
+// one before it.
+type Variant int
+
+const (
+	// The original Chip-8 instruction set
+	VariantCHIP8 Variant = iota
+	// SUPER-CHIP 1.1: high resolution mode, scrolling, 16x16 sprites, a big
+	// font and persistent user flags
+	VariantSCHIP
+	// XO-CHIP: 64 KiB of memory, two bitplanes and programmable audio
+	VariantXOCHIP
+)
+
+// Named variants
+var Variants = map[string]Variant{
+	"chip8":  VariantCHIP8,
+	"schip":  VariantSCHIP,
+	"xochip": VariantXOCHIP,
+}
+
+// Returns the variant with the given name from Variants
+func ParseVariant(name string) (Variant, error) {
+	v, ok := Variants[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("chip8: unknown variant %q", name)
+	}
+	return v, nil
+}
+
+func (v Variant) String() string {
+	for name, variant := range Variants {
+		if variant == v {
+			return name
+		}
+	}
+	return fmt.Sprintf("Variant(%d)", int(v))
+}
+
+// Returns the quirks programs for the variant usually expect
+func (v Variant) Quirks() Quirks {
+	switch v {
+	case VariantSCHIP:
+		return QuirksSCHIP11
+	case VariantXOCHIP:
+		return QuirksXOCHIP
+	}
+	return DefaultQuirks
+}
+
+// Returns the number of bytes of memory of the variant
+func (v Variant) memorySize() int {
+	if v >= VariantXOCHIP {
+		return 65536
+	}
+	return 4096
+}
+
+// Runs the instruction set of v, VariantCHIP8 if not set. Instructions of
+// later variants are invalid opcodes.
+func WithVariant(v Variant) Option {
+	return func(c8 *Machine) {
+		c8.variant = v
+	}
+}
+
+// Returns the instruction set the machine runs
+func (c8 *Machine) Variant() Variant {
+	return c8.variant
+}
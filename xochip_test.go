@@ -0,0 +1,129 @@
+package chip8
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestXOCHIPInvalidOnSCHIP(t *testing.T) {
+	for _, inst := range []uint16{0x00D1, 0xF000, 0x5012, 0x5013, 0xF101, 0xF002, 0xF03A} {
+		c8 := New(WithVariant(VariantSCHIP))
+		if err := c8.executeInstruction(inst); err != ErrInvalidOpcode {
+			t.Errorf("%04X: expected ErrInvalidOpcode, got %v instead", inst, err)
+		}
+	}
+}
+
+func TestXOCHIPScrollUp(t *testing.T) {
+	c8 := New(WithVariant(VariantXOCHIP))
+	fb := c8.Framebuffer()
+	// A single pixel at (10, 10)
+	fb.drawSprite(10, 10, 1, []uint8{0x80}, spriteMode{})
+	if err := c8.executeInstruction(0x00D4); err != nil {
+		t.Fatal(err)
+	}
+	if fb.Pixel(10, 10) || !fb.Pixel(10, 6) {
+		t.Errorf("Expected the pixel to scroll up 4 rows")
+	}
+	c8.executeInstruction(0x00DF)
+	for y := 0; y < fb.Height(); y++ {
+		if fb.Pixel(10, y) {
+			t.Errorf("Expected the pixel to scroll off the screen, found it at row %d", y)
+		}
+	}
+	if m := Mnemonic(0x00D4); m != "00D4 SCROLL.UP 4" {
+		t.Errorf("Expected 00D4 SCROLL.UP 4, got %q instead", m)
+	}
+}
+
+func TestXOCHIPMemory(t *testing.T) {
+	c8, err := NewFromROM(bytes.NewReader([]byte{0xF0, 0x00, 0xFF, 0x00, 0x60, 0x2A, 0xF0, 0x55}), WithVariant(VariantXOCHIP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c8.RunFor(3)
+	if c8.I() != 0xFF01 {
+		t.Errorf("Expected I of 0xFF01, got 0x%04X instead", c8.I())
+	}
+	if c8.PC() != 0x208 {
+		t.Errorf("Expected pc of 0x208, got 0x%04X instead", c8.PC())
+	}
+	if c8.memory[0xFF00] != 0x2A {
+		t.Errorf("Expected 0x2A at 0xFF00, got 0x%02X instead", c8.memory[0xFF00])
+	}
+}
+
+func TestXOCHIPSkipLongInstruction(t *testing.T) {
+	// SKIP.EQ V0, 00 jumps over all four bytes of F000 NNNN
+	c8, err := NewFromROM(bytes.NewReader([]byte{0x30, 0x00, 0xF0, 0x00, 0x12, 0x34, 0x61, 0x01}), WithVariant(VariantXOCHIP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c8.RunFor(2)
+	if c8.PC() != 0x208 || c8.Registers()[1] != 1 {
+		t.Errorf("Expected to skip over F000 NNNN, got pc 0x%04X instead", c8.PC())
+	}
+}
+
+func TestXOCHIPSaveLoadRange(t *testing.T) {
+	c8 := New(WithVariant(VariantXOCHIP))
+	c8.reg = [16]uint8{0, 0, 2, 3, 4}
+	c8.i = 0x300
+	c8.executeInstruction(0x5242)
+	if c8.memory[0x300] != 2 || c8.memory[0x302] != 4 || c8.i != 0x300 {
+		t.Errorf("Expected V2-V4 at 0x300, got %v instead", c8.memory[0x300:0x303])
+	}
+	// Reversed range loads in descending order
+	c8.executeInstruction(0x5753)
+	if c8.reg[7] != 2 || c8.reg[6] != 3 || c8.reg[5] != 4 {
+		t.Errorf("Expected V7-V5 of 2, 3, 4, got %v instead", c8.reg[5:8])
+	}
+}
+
+func TestXOCHIPPlanes(t *testing.T) {
+	c8 := New(WithVariant(VariantXOCHIP))
+	fb := c8.Framebuffer()
+	copy(c8.memory[0x300:], []uint8{0x80, 0xC0})
+	c8.i = 0x300
+
+	// Both planes take one row each from memory
+	c8.executeInstruction(0xF301)
+	c8.executeInstruction(0xD011)
+	if fb.Color(0, 0) != 3 || fb.Color(1, 0) != 2 {
+		t.Errorf("Expected colors 3 and 2, got %d and %d instead", fb.Color(0, 0), fb.Color(1, 0))
+	}
+
+	// Clearing only the first plane leaves the second
+	c8.executeInstruction(0xF101)
+	c8.executeInstruction(0x00E0)
+	if fb.Color(0, 0) != 2 || fb.Color(1, 0) != 2 {
+		t.Errorf("Expected only the second plane to be left")
+	}
+	if !fb.Pixel(0, 0) {
+		t.Errorf("Expected a pixel lit in the second plane to be lit")
+	}
+
+	// Drawing to the second plane collides with it
+	c8.executeInstruction(0xF201)
+	c8.executeInstruction(0xD011)
+	if c8.reg[15] != 1 || fb.Color(0, 0) != 0 {
+		t.Errorf("Expected a collision erasing (0, 0)")
+	}
+}
+
+func TestXOCHIPAudio(t *testing.T) {
+	c8 := New(WithVariant(VariantXOCHIP))
+	if c8.Pitch() != 64 {
+		t.Errorf("Expected a pitch of 64, got %d instead", c8.Pitch())
+	}
+	for j := 0; j < 16; j++ {
+		c8.memory[0x300+j] = uint8(j)
+	}
+	c8.i = 0x300
+	c8.reg[4] = 100
+	c8.executeInstruction(0xF002)
+	c8.executeInstruction(0xF43A)
+	if c8.AudioPattern()[15] != 15 || c8.Pitch() != 100 {
+		t.Errorf("Expected the pattern and pitch to be set")
+	}
+}